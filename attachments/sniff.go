@@ -0,0 +1,38 @@
+package attachments
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// allowedTypes are the content types that can be uploaded: photos, scanned or exported documents and DICOM images
+// from X-ray machines.
+var allowedTypes = map[string]bool{
+	"image/jpeg":        true,
+	"image/png":         true,
+	"image/gif":         true,
+	"image/webp":        true,
+	"application/pdf":   true,
+	"application/dicom": true,
+}
+
+// Sniff finds the content type of an upload from its first bytes, ignoring what the client claimed, and reports
+// whether it can be uploaded.
+func Sniff(data []byte) (string, bool) {
+	//DICOM files start with a 128 byte preamble followed by "DICM"
+	if len(data) >= 132 && bytes.Equal(data[128:132], []byte("DICM")) {
+		return "application/dicom", true
+	}
+
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType, allowedTypes[contentType]
+}
+
+// IsImage reports whether a content type is a picture a thumbnail can be made of.
+func IsImage(contentType string) bool {
+	return contentType == "image/jpeg" || contentType == "image/png" || contentType == "image/gif"
+}
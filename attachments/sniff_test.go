@@ -0,0 +1,78 @@
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	picture := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		picture.Set(x, 0, color.NRGBA{R: 255, A: 255})
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, picture); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	dicom := append(make([]byte, 128), []byte("DICM")...)
+	cases := []struct {
+		name        string
+		data        []byte
+		contentType string
+		allowed     bool
+	}{
+		{"png", encodePNG(t, 2, 2), "image/png", true},
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf", true},
+		{"dicom", dicom, "application/dicom", true},
+		{"html claiming nothing", []byte("<html><script>alert(1)</script></html>"), "text/html", false},
+		{"executable", []byte("MZ\x90\x00\x03"), "application/octet-stream", false},
+	}
+
+	for _, c := range cases {
+		contentType, allowed := Sniff(c.data)
+		if contentType != c.contentType || allowed != c.allowed {
+			t.Errorf("%s: got %s %v, want %s %v", c.name, contentType, allowed, c.contentType, c.allowed)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	thumbnail, err := Thumbnail(encodePNG(t, 1024, 512))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != ThumbnailSize || config.Height != ThumbnailSize/2 {
+		t.Errorf("got %dx%d, want %dx%d", config.Width, config.Height, ThumbnailSize, ThumbnailSize/2)
+	}
+}
+
+func TestThumbnailKeepsSmallPictures(t *testing.T) {
+	thumbnail, err := Thumbnail(encodePNG(t, 40, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, err := jpeg.DecodeConfig(bytes.NewReader(thumbnail)); err != nil || config.Width != 40 || config.Height != 30 {
+		t.Errorf("got %+v, %v, want 40x30", config, err)
+	}
+}
+
+func TestThumbnailRejectsGarbage(t *testing.T) {
+	if _, err := Thumbnail([]byte("not a picture")); err == nil {
+		t.Error("a thumbnail was made of something that isn't a picture")
+	}
+}
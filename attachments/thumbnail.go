@@ -0,0 +1,95 @@
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+
+	//decoders of the images thumbnails are made of
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ThumbnailSize is the largest side of a thumbnail, in pixels.
+const ThumbnailSize = 256
+
+// maxPixels keeps a tiny file claiming a huge picture from using up the memory when decoded.
+const maxPixels = 50_000_000
+
+// ErrTooLarge is returned by Thumbnail for pictures with too many pixels to decode.
+var ErrTooLarge = errors.New("attachments: the picture is too large for a thumbnail")
+
+// Thumbnail scales a picture down to fit a ThumbnailSize square and encodes it as JPEG, over a white background
+// where it was transparent.
+func Thumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > ThumbnailSize || height > ThumbnailSize {
+		if width >= height {
+			width, height = ThumbnailSize, max(1, height*ThumbnailSize/bounds.Dx())
+		} else {
+			width, height = max(1, width*ThumbnailSize/bounds.Dy()), ThumbnailSize
+		}
+	}
+
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, source, bounds.Min, draw.Over)
+
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, scale(flat, width, height), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return thumbnail.Bytes(), nil
+}
+
+// scale resizes a picture averaging the pixels each pixel of the result covers.
+func scale(source *image.RGBA, width int, height int) *image.RGBA {
+	bounds := source.Bounds()
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					offset := source.PixOffset(sx, sy)
+					r += uint64(source.Pix[offset])
+					g += uint64(source.Pix[offset+1])
+					b += uint64(source.Pix[offset+2])
+					count++
+				}
+			}
+			result.SetRGBA(x, y, color.RGBA{R: uint8(r / count), G: uint8(g / count), B: uint8(b / count), A: 255})
+		}
+	}
+	return result
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,42 @@
+package attachments
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Variants of an attachment that can be downloaded.
+const (
+	VariantOriginal  = "original"
+	VariantThumbnail = "thumbnail"
+)
+
+// Sign computes the signature of a download URL of an attachment variant, valid until the expiry Unix time. Whoever
+// has the URL can download the file until then, so URLs are only handed to callers allowed to see the attachment.
+func Sign(secret string, attachmentId string, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.%s.%d", attachmentId, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a download URL and that it didn't expire.
+func Verify(secret string, signature string, attachmentId string, variant string, expires int64, now time.Time) bool {
+	if now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, attachmentId, variant, expires)))
+}
+
+// NewSecret generates a random secret to sign download URLs with when none is configured. URLs signed with it stop
+// working when the API restarts.
+func NewSecret() string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(secret)
+}
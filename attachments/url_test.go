@@ -0,0 +1,40 @@
+package attachments
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(15 * time.Minute).Unix()
+	signature := Sign("secret", "a1", VariantOriginal, expires)
+
+	if !Verify("secret", signature, "a1", VariantOriginal, expires, now) {
+		t.Fatal("a valid signature was turned down")
+	}
+	if !Verify("secret", signature, "a1", VariantOriginal, expires, time.Unix(expires, 0)) {
+		t.Error("a signature was turned down in its last second")
+	}
+
+	cases := map[string]bool{
+		"expired":         Verify("secret", signature, "a1", VariantOriginal, expires, time.Unix(expires+1, 0)),
+		"other secret":    Verify("other", signature, "a1", VariantOriginal, expires, now),
+		"other file":      Verify("secret", signature, "a2", VariantOriginal, expires, now),
+		"other variant":   Verify("secret", signature, "a1", VariantThumbnail, expires, now),
+		"extended expiry": Verify("secret", signature, "a1", VariantOriginal, expires+3600, now),
+		"no signature":    Verify("secret", "", "a1", VariantOriginal, expires, now),
+	}
+	for name, accepted := range cases {
+		if accepted {
+			t.Errorf("%s: the signature was accepted", name)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	first, second := NewSecret(), NewSecret()
+	if len(first) != 64 || first == second {
+		t.Errorf("got %q and %q, want two different 32 byte secrets", first, second)
+	}
+}
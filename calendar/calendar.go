@@ -0,0 +1,218 @@
+package calendar
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProductId identifies the API as the producer of the calendars.
+const ProductId = "-//pet-appointments-api//Calendar//EN"
+
+// MIMEType is the media type of an iCalendar document.
+const MIMEType = "text/calendar; charset=utf-8"
+
+type Status string
+
+const (
+	StatusConfirmed Status = "CONFIRMED"
+	StatusTentative Status = "TENTATIVE"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Event is a VEVENT. Calendar apps recognise an updated event by its UID and keep the version with the highest
+// Sequence, so the sequence has to grow every time the event changes.
+type Event struct {
+	UID          string
+	Sequence     int
+	Status       Status
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	TimeZone     *time.Location
+	Created      time.Time
+	LastModified time.Time
+
+	// Imported events may also be all-day, free instead of busy, or recurring.
+	AllDay      bool
+	Transparent bool
+	Rule        *Rule
+	Exceptions  []time.Time
+	// RecurrenceId is the original start of the occurrence an event overrides, or that an expanded occurrence had.
+	RecurrenceId time.Time
+
+	duration time.Duration
+}
+
+// Calendar is an RFC 5545 VCALENDAR.
+type Calendar struct {
+	Name string
+	// RefreshInterval tells subscribed calendar apps how often to fetch the feed again, zero leaves it to them.
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Write encodes the calendar as an iCalendar document, stamping it with the given time.
+func Write(w io.Writer, cal Calendar, now time.Time) error {
+	out := &writer{w: bufio.NewWriter(w)}
+
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:" + ProductId)
+	out.line("CALSCALE:GREGORIAN")
+	out.line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		out.line("X-WR-CALNAME:" + escape(cal.Name))
+	}
+	if cal.RefreshInterval > 0 {
+		interval := fmt.Sprintf("PT%dM", int(cal.RefreshInterval.Minutes()))
+		out.line("REFRESH-INTERVAL;VALUE=DURATION:" + interval)
+		out.line("X-PUBLISHED-TTL:" + interval)
+	}
+
+	for _, zone := range zones(cal.Events) {
+		writeTimeZone(out, zone.location, zone.from, zone.to)
+	}
+
+	for _, event := range cal.Events {
+		writeEvent(out, event, now)
+	}
+
+	out.line("END:VCALENDAR")
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+func writeEvent(out *writer, event Event, now time.Time) {
+	out.line("BEGIN:VEVENT")
+	out.line("UID:" + escape(event.UID))
+	out.line("DTSTAMP:" + utc(now))
+	out.line("SEQUENCE:" + fmt.Sprint(event.Sequence))
+	out.line("DTSTART" + dateTime(event.Start, event.TimeZone))
+	out.line("DTEND" + dateTime(event.End, event.TimeZone))
+	if event.Status != "" {
+		out.line("STATUS:" + string(event.Status))
+	}
+	if event.Status == StatusCancelled {
+		out.line("TRANSP:TRANSPARENT")
+	} else {
+		out.line("TRANSP:OPAQUE")
+	}
+	out.line("SUMMARY:" + escape(event.Summary))
+	if event.Description != "" {
+		out.line("DESCRIPTION:" + escape(event.Description))
+	}
+	if event.Location != "" {
+		out.line("LOCATION:" + escape(event.Location))
+	}
+	if !event.Created.IsZero() {
+		out.line("CREATED:" + utc(event.Created))
+	}
+	if !event.LastModified.IsZero() {
+		out.line("LAST-MODIFIED:" + utc(event.LastModified))
+	}
+	out.line("END:VEVENT")
+}
+
+// NewToken generates a random token for a calendar feed URL.
+func NewToken() string {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(token)
+}
+
+// dateTime formats a DTSTART/DTEND value including its separator, in UTC or as a local time of the given zone.
+func dateTime(t time.Time, location *time.Location) string {
+	if isUTC(location) {
+		return ":" + utc(t)
+	}
+	return ";TZID=" + location.String() + ":" + t.In(location).Format("20060102T150405")
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func isUTC(location *time.Location) bool {
+	return location == nil || location == time.UTC || location.String() == "UTC"
+}
+
+// escape escapes a TEXT value.
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(text)
+}
+
+// zoneRange is a time zone used by some events together with the period they span.
+type zoneRange struct {
+	location *time.Location
+	from, to time.Time
+}
+
+// zones returns the time zones used by the events, sorted by name.
+func zones(events []Event) []zoneRange {
+	byName := map[string]*zoneRange{}
+	for _, event := range events {
+		if isUTC(event.TimeZone) {
+			continue
+		}
+
+		zone, ok := byName[event.TimeZone.String()]
+		if !ok {
+			zone = &zoneRange{location: event.TimeZone, from: event.Start, to: event.End}
+			byName[event.TimeZone.String()] = zone
+		}
+		if event.Start.Before(zone.from) {
+			zone.from = event.Start
+		}
+		if event.End.After(zone.to) {
+			zone.to = event.End
+		}
+	}
+
+	var sorted []zoneRange
+	for _, zone := range byName {
+		sorted = append(sorted, *zone)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].location.String() < sorted[j].location.String() })
+	return sorted
+}
+
+// writer writes content lines, folding them at 75 octets as RFC 5545 requires.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) line(content string) {
+	if w.err != nil {
+		return
+	}
+
+	//continuation lines start with a space, which counts towards their length
+	limit := 75
+	for len(content) > limit {
+		//never split a UTF-8 sequence
+		cut := limit
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, w.err = w.w.WriteString(content[:cut] + "\r\n "); w.err != nil {
+			return
+		}
+		content = content[cut:]
+		limit = 74
+	}
+	_, w.err = w.w.WriteString(content + "\r\n")
+}
@@ -0,0 +1,143 @@
+package calendar
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	events := []Event{
+		{
+			UID:         "appointment-1@pet-appointments-api",
+			Sequence:    3,
+			Status:      StatusConfirmed,
+			Summary:     "Grooming; Rex, the dog",
+			Description: "Bring the vaccination card.\nAsk about the diet, please \\ thanks",
+			Location:    "Calle Mayor 1, Madrid",
+			Start:       time.Date(2024, 10, 26, 10, 0, 0, 0, madrid),
+			End:         time.Date(2024, 10, 27, 10, 30, 0, 0, madrid),
+			TimeZone:    madrid,
+		},
+		{
+			UID:      "appointment-2@pet-appointments-api",
+			Status:   StatusCancelled,
+			Summary:  strings.Repeat("Vaccination ", 12),
+			Start:    time.Date(2024, 11, 2, 16, 0, 0, 0, time.UTC),
+			End:      time.Date(2024, 11, 2, 16, 45, 0, 0, time.UTC),
+			TimeZone: time.UTC,
+		},
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, Calendar{Name: "Ana Vet", RefreshInterval: time.Hour, Events: events}, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(out.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	if !strings.Contains(out.String(), "BEGIN:VTIMEZONE\r\nTZID:Europe/Madrid") {
+		t.Error("the time zone of the events is not described")
+	}
+
+	parsed, err := Parse(&out, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(events) {
+		t.Fatalf("got %d events, want %d", len(parsed), len(events))
+	}
+	for i, want := range events {
+		got := parsed[i]
+		if got.UID != want.UID || got.Sequence != want.Sequence || got.Status != want.Status || got.Summary != want.Summary ||
+			got.Description != want.Description || got.Location != want.Location {
+			t.Errorf("event %d: got %+v, want %+v", i, got, want)
+		}
+		if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.TimeZone.String() != want.TimeZone.String() {
+			t.Errorf("event %d: got %s - %s %s, want %s - %s %s", i, got.Start, got.End, got.TimeZone, want.Start, want.End, want.TimeZone)
+		}
+	}
+	if !parsed[1].Transparent {
+		t.Error("a cancelled event is written as busy")
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	if _, err := Parse(strings.NewReader("BEGIN:VCARD\r\nFN:Ana\r\nEND:VCARD\r\n"), time.UTC); !errors.Is(err, ErrNotCalendar) {
+		t.Errorf("got %v, want %v", err, ErrNotCalendar)
+	}
+	if _, err := Parse(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nEND:VCALENDAR\r\n"), time.UTC); err == nil {
+		t.Error("a document with unbalanced components was accepted")
+	}
+}
+
+const recurringCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"DTSTART:20240701T090000Z\r\n" +
+	"DURATION:PT30M\r\n" +
+	"RRULE:FREQ=DAILY;COUNT=5\r\n" +
+	"EXDATE:20240703T090000Z\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT10M\r\n" +
+	"SUMMARY:not the event summary\r\n" +
+	"END:VALARM\r\n" +
+	"SUMMARY:Stand-up\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"RECURRENCE-ID:20240704T090000Z\r\n" +
+	"DTSTART:20240704T140000Z\r\n" +
+	"DTEND:20240704T143000Z\r\n" +
+	"SUMMARY:Stand-up (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"RECURRENCE-ID:20240705T090000Z\r\n" +
+	"DTSTART:20240705T090000Z\r\n" +
+	"DTEND:20240705T093000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestExpand(t *testing.T) {
+	events, err := Parse(strings.NewReader(recurringCalendar), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events[0].Summary != "Stand-up" {
+		t.Errorf("got summary %q, the alarm leaked into the event", events[0].Summary)
+	}
+
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	occurrences := Expand(events, from, from.AddDate(0, 0, 7))
+
+	//the 3rd is excluded, the 4th moved to the afternoon and the 5th cancelled
+	want := []struct {
+		start, recurrenceId time.Time
+		summary             string
+	}{
+		{time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC), "Stand-up"},
+		{time.Date(2024, 7, 2, 9, 0, 0, 0, time.UTC), time.Date(2024, 7, 2, 9, 0, 0, 0, time.UTC), "Stand-up"},
+		{time.Date(2024, 7, 4, 14, 0, 0, 0, time.UTC), time.Date(2024, 7, 4, 9, 0, 0, 0, time.UTC), "Stand-up (moved)"},
+	}
+	if len(occurrences) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %+v", len(occurrences), len(want), occurrences)
+	}
+	for i, w := range want {
+		got := occurrences[i]
+		if !got.Start.Equal(w.start) || !got.RecurrenceId.Equal(w.recurrenceId) || got.Summary != w.summary || got.End.Sub(got.Start) != 30*time.Minute {
+			t.Errorf("occurrence %d: got %s (%s) %q until %s", i, got.Start, got.RecurrenceId, got.Summary, got.End)
+		}
+	}
+}
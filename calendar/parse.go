@@ -0,0 +1,387 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSize is the largest iCalendar document accepted for import.
+const MaxSize = 10 << 20
+
+// ErrNotCalendar is returned when a document has no VCALENDAR.
+var ErrNotCalendar = errors.New("calendar: the document is not an iCalendar file")
+
+// property is a content line, e.g. DTSTART;TZID=Europe/Madrid:20261020T100000.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of an iCalendar document. Floating times, and times in a zone the server does not know,
+// are read in the given location.
+func Parse(r io.Reader, location *time.Location) ([]Event, error) {
+	lines, err := unfold(io.LimitReader(r, MaxSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var components []string
+	var event *Event
+	seenCalendar := false
+
+	for number, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("calendar: line %d: %w", number+1, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			components = append(components, component)
+			if component == "VCALENDAR" {
+				seenCalendar = true
+			}
+			if component == "VEVENT" && len(components) == 2 {
+				event = &Event{}
+			}
+			continue
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("calendar: line %d: unexpected END:%s", number+1, prop.value)
+			}
+			if event != nil && len(components) == 2 {
+				if err := finishEvent(event); err != nil {
+					return nil, fmt.Errorf("calendar: line %d: %w", number+1, err)
+				}
+				events = append(events, *event)
+				event = nil
+			}
+			components = components[:len(components)-1]
+			continue
+		}
+
+		//properties of nested components, like VALARM, don't belong to the event
+		if event == nil || len(components) != 2 {
+			continue
+		}
+
+		if err := setProperty(event, prop, location); err != nil {
+			return nil, fmt.Errorf("calendar: line %d: %s: %w", number+1, prop.name, err)
+		}
+	}
+
+	if !seenCalendar {
+		return nil, ErrNotCalendar
+	}
+	return events, nil
+}
+
+func setProperty(event *Event, prop property, location *time.Location) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		event.UID = prop.value
+	case "SUMMARY":
+		event.Summary = unescape(prop.value)
+	case "DESCRIPTION":
+		event.Description = unescape(prop.value)
+	case "LOCATION":
+		event.Location = unescape(prop.value)
+	case "SEQUENCE":
+		event.Sequence, err = strconv.Atoi(prop.value)
+	case "STATUS":
+		event.Status = Status(strings.ToUpper(prop.value))
+	case "TRANSP":
+		event.Transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+	case "DTSTART":
+		event.Start, event.AllDay, err = parsePropertyTime(prop, location)
+		event.TimeZone = event.Start.Location()
+	case "DTEND":
+		event.End, _, err = parsePropertyTime(prop, location)
+	case "DURATION":
+		//the end is only known once DTSTART was read
+		event.duration, err = parseDuration(prop.value)
+	case "RRULE":
+		var rule Rule
+		if rule, err = ParseRule(prop.value, location); err == nil {
+			event.Rule = &rule
+		}
+	case "EXDATE":
+		for _, value := range strings.Split(prop.value, ",") {
+			exception, _, err := parseDateTime(value, propertyLocation(prop, location))
+			if err != nil {
+				return err
+			}
+			event.Exceptions = append(event.Exceptions, exception)
+		}
+	case "RECURRENCE-ID":
+		event.RecurrenceId, _, err = parsePropertyTime(prop, location)
+	case "CREATED":
+		event.Created, _, err = parsePropertyTime(prop, location)
+	case "LAST-MODIFIED":
+		event.LastModified, _, err = parsePropertyTime(prop, location)
+	}
+	return err
+}
+
+// finishEvent checks an event once all its properties were read, working out its end when it was not given.
+func finishEvent(event *Event) error {
+	if event.UID == "" {
+		return errors.New("the event has no UID")
+	}
+	if event.Start.IsZero() {
+		return errors.New("the event " + event.UID + " has no DTSTART")
+	}
+
+	switch {
+	case event.End.IsZero() && event.duration != 0:
+		event.End = event.Start.Add(event.duration)
+	case event.End.IsZero() && event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	case event.End.IsZero():
+		event.End = event.Start
+	}
+
+	if event.End.Before(event.Start) {
+		return errors.New("the event " + event.UID + " ends before it starts")
+	}
+	return nil
+}
+
+// Expand turns recurring events into their occurrences overlapping [from, to), applying exceptions and the events
+// overriding single occurrences. Every returned event has RecurrenceId set to its original start, which together with
+// the UID identifies it across imports. Cancelled events and occurrences are left out.
+func Expand(events []Event, from, to time.Time) []Event {
+	overrides := map[string]map[int64]Event{}
+	for _, event := range events {
+		if !event.RecurrenceId.IsZero() {
+			if overrides[event.UID] == nil {
+				overrides[event.UID] = map[int64]Event{}
+			}
+			overrides[event.UID][event.RecurrenceId.Unix()] = event
+		}
+	}
+
+	var occurrences []Event
+	add := func(occurrence Event) {
+		if occurrence.Status != StatusCancelled && occurrence.Start.Before(to) && occurrence.End.After(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	for _, event := range events {
+		if !event.RecurrenceId.IsZero() {
+			continue
+		}
+
+		if event.Rule == nil {
+			event.RecurrenceId = event.Start
+			add(event)
+			continue
+		}
+
+		duration := event.End.Sub(event.Start)
+		for _, start := range event.Rule.Occurrences(event.Start, from.Add(-duration), to) {
+			if isException(event.Exceptions, start) {
+				continue
+			}
+
+			if override, ok := overrides[event.UID][start.Unix()]; ok {
+				add(override)
+				delete(overrides[event.UID], start.Unix())
+				continue
+			}
+
+			occurrence := event
+			occurrence.Rule = nil
+			occurrence.Exceptions = nil
+			occurrence.Start = start
+			occurrence.End = start.Add(duration)
+			occurrence.RecurrenceId = start
+			add(occurrence)
+		}
+	}
+
+	//overrides moving an occurrence into the period from outside of it
+	for _, byStart := range overrides {
+		for _, override := range byStart {
+			add(override)
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+	return occurrences
+}
+
+// isException reports whether an occurrence was excluded by EXDATE. A date-only exception excludes the whole day.
+func isException(exceptions []time.Time, start time.Time) bool {
+	for _, exception := range exceptions {
+		if exception.Equal(start) {
+			return true
+		}
+		if exception.Hour() == 0 && exception.Minute() == 0 && exception.Second() == 0 {
+			year, month, day := start.In(exception.Location()).Date()
+			exceptionYear, exceptionMonth, exceptionDay := exception.Date()
+			if year == exceptionYear && month == exceptionMonth && day == exceptionDay {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unfold splits a document into its content lines, joining the folded ones.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxSize)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, error) {
+	prop := property{params: map[string]string{}}
+
+	//the value starts at the first colon outside of a quoted parameter value
+	quoted := false
+	colon := -1
+	for i, char := range line {
+		if char == '"' {
+			quoted = !quoted
+		}
+		if char == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return prop, errors.New("missing ':'")
+	}
+	prop.value = line[colon+1:]
+
+	parts := splitUnquoted(line[:colon], ';')
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+func splitUnquoted(s string, separator rune) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, char := range s {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case char == separator && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(text string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(text)
+}
+
+// propertyLocation returns the zone named by the TZID parameter, or the given location.
+func propertyLocation(prop property, location *time.Location) *time.Location {
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			return zone
+		}
+	}
+	return location
+}
+
+func parsePropertyTime(prop property, location *time.Location) (time.Time, bool, error) {
+	return parseDateTime(prop.value, propertyLocation(prop, location))
+}
+
+// parseDateTime parses a DATE or DATE-TIME value, reporting whether it was a date.
+func parseDateTime(value string, location *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case len(value) == 8:
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	default:
+		t, err := time.ParseInLocation("20060102T150405", value, location)
+		return t, false, err
+	}
+}
+
+// parseDuration parses a DURATION value such as "PT1H30M", "P1D" or "-PT15M".
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign = -1
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+
+	var duration time.Duration
+	number := ""
+	inTime := false
+	for i := 1; i < len(value); i++ {
+		char := value[i]
+		switch {
+		case char == 'T':
+			inTime = true
+		case char >= '0' && char <= '9':
+			number += string(char)
+		default:
+			unit, ok := units[char]
+			if !ok || number == "" || (inTime && (char == 'W' || char == 'D')) || (!inTime && char != 'W' && char != 'D') {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			n, _ := strconv.Atoi(number)
+			duration += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * duration, nil
+}
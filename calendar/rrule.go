@@ -0,0 +1,430 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// ErrUnsupportedRule is returned for recurrence rules using parts the expander does not know, like BYHOUR.
+var ErrUnsupportedRule = errors.New("calendar: unsupported recurrence rule")
+
+// maxPeriods bounds the expansion of a series, besides the window it is expanded in: enough for a daily series of
+// over fifty years.
+const maxPeriods = 20000
+
+// WeekdayNum is a BYDAY entry, e.g. "MO" or "-1FR" for the last Friday. N is zero when every such weekday matches.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is an RFC 5545 RRULE. Occurrences keep the wall clock time of the first one, so they don't move with DST.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+
+// ParseRule parses the value of an RRULE property. A floating or date-only UNTIL is read in the given location.
+func ParseRule(value string, location *time.Location) (Rule, error) {
+	rule := Rule{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(strings.TrimSpace(value), ";") {
+		if part == "" {
+			continue
+		}
+		name, partValue, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("calendar: invalid recurrence rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(partValue))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly && rule.Freq != Yearly {
+				return Rule{}, fmt.Errorf("%w: FREQ=%s", ErrUnsupportedRule, partValue)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(partValue)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("the interval must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(partValue)
+		case "UNTIL":
+			var allDay bool
+			rule.Until, allDay, err = parseDateTime(partValue, location)
+			if allDay {
+				//UNTIL is inclusive, a date includes the whole day
+				rule.Until = rule.Until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(partValue, ",") {
+				var weekday WeekdayNum
+				if weekday, err = parseWeekdayNum(day); err != nil {
+					break
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseInts(partValue, -31, 31)
+		case "BYMONTH":
+			rule.ByMonth, err = parseInts(partValue, 1, 12)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseInts(partValue, -366, 366)
+		case "WKST":
+			weekday, ok := weekdays[strings.ToUpper(partValue)]
+			if !ok {
+				err = errors.New("unknown weekday")
+			}
+			rule.WeekStart = weekday
+		default:
+			return Rule{}, fmt.Errorf("%w: %s", ErrUnsupportedRule, name)
+		}
+
+		if err != nil {
+			return Rule{}, fmt.Errorf("calendar: invalid recurrence rule part %q: %w", part, err)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, errors.New("calendar: the recurrence rule has no FREQ")
+	}
+	return rule, nil
+}
+
+// String formats the rule as the value of an RRULE property.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+utc(r.Until))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			name := strings.ToUpper(day.Day.String()[:2])
+			if day.N != 0 {
+				name = strconv.Itoa(day.N) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(r.WeekStart.String()[:2]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the starts of the occurrences of a series beginning at start, which always is the first one,
+// that fall in [from, to).
+func (r Rule) Occurrences(start, from, to time.Time) []time.Time {
+	found, _ := r.expand(start, from, to)
+	return found
+}
+
+// expand returns the occurrences of the series in [from, to), and how many of its periods it walked to find them.
+func (r Rule) expand(start, from, to time.Time) ([]time.Time, int) {
+	var found []time.Time
+	emit := func(occurrence time.Time) bool {
+		if !occurrence.Before(to) || (!r.Until.IsZero() && occurrence.After(r.Until)) {
+			return false
+		}
+		if !occurrence.Before(from) {
+			found = append(found, occurrence)
+		}
+		return true
+	}
+
+	count := 1
+	if !emit(start) {
+		return found, 0
+	}
+
+	period := 0
+	for ; period < maxPeriods; period++ {
+		//periods past the window or the end of the series can't have occurrences, so rules that rarely or never
+		//produce one, like the 30th of February, stop there
+		if first := r.periodStart(start, period); !first.Before(to) || (!r.Until.IsZero() && first.After(r.Until)) {
+			return found, period
+		}
+
+		for _, candidate := range r.candidates(start, period) {
+			if !candidate.After(start) {
+				continue
+			}
+			if r.Count > 0 && count >= r.Count {
+				return found, period + 1
+			}
+			if !emit(candidate) {
+				return found, period + 1
+			}
+			count++
+		}
+	}
+	return found, period
+}
+
+// candidates returns the sorted occurrences the rule produces in the given period of the series.
+func (r Rule) candidates(start time.Time, period int) []time.Time {
+	location := start.Location()
+	year, month, day := start.Date()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, location)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		candidate := at(year, month, day+period*r.Interval)
+		if r.matchesMonth(candidate) && r.matchesMonthDay(candidate) && r.matchesWeekday(candidate) {
+			days = append(days, candidate)
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := at(year, month, day-offset+7*period*r.Interval)
+		for i := 0; i < 7; i++ {
+			candidate := weekStart.AddDate(0, 0, i)
+			candidate = at(candidate.Year(), candidate.Month(), candidate.Day())
+			if len(r.ByDay) == 0 && candidate.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchesWeekday(candidate) && r.matchesMonth(candidate) {
+				days = append(days, candidate)
+			}
+		}
+	case Monthly:
+		first := at(year, month+time.Month(period*r.Interval), 1)
+		if r.matchesMonth(first) {
+			days = r.monthDays(first.Year(), first.Month(), day, at)
+		}
+	case Yearly:
+		y := year + period*r.Interval
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, m := range r.ByMonth {
+				days = append(days, r.monthDays(y, time.Month(m), day, at)...)
+			}
+		case len(r.ByDay) > 0 && len(r.ByMonthDay) == 0:
+			days = r.yearDays(y, at)
+		default:
+			days = r.monthDays(y, month, day, at)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return r.applySetPos(days)
+}
+
+// periodStart returns the start of the first day of a period of the series, before any of its occurrences.
+func (r Rule) periodStart(start time.Time, period int) time.Time {
+	year, month, day := start.Date()
+	switch r.Freq {
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		return time.Date(year, month, day-offset+7*period*r.Interval, 0, 0, 0, 0, start.Location())
+	case Monthly:
+		return time.Date(year, month+time.Month(period*r.Interval), 1, 0, 0, 0, 0, start.Location())
+	case Yearly:
+		return time.Date(year+period*r.Interval, time.January, 1, 0, 0, 0, 0, start.Location())
+	default:
+		return time.Date(year, month, day+period*r.Interval, 0, 0, 0, 0, start.Location())
+	}
+}
+
+// monthDays returns the days of a month matching BYMONTHDAY and BYDAY, or the day of the month of the first
+// occurrence when neither is set.
+func (r Rule) monthDays(year int, month time.Month, startDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	length := daysIn(year, month)
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if startDay > length {
+			return nil
+		}
+		return []time.Time{at(year, month, startDay)}
+	}
+
+	var days []time.Time
+	for d := 1; d <= length; d++ {
+		candidate := at(year, month, d)
+		if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(candidate) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !matchesNthWeekday(r.ByDay, candidate, (d-1)/7+1, (length-d)/7+1) {
+			continue
+		}
+		days = append(days, candidate)
+	}
+	return days
+}
+
+// yearDays returns the days of a year matching BYDAY, where the ordinals count within the year.
+func (r Rule) yearDays(year int, at func(int, time.Month, int) time.Time) []time.Time {
+	length := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+
+	var days []time.Time
+	for d := 1; d <= length; d++ {
+		candidate := at(year, time.January, d)
+		if matchesNthWeekday(r.ByDay, candidate, (d-1)/7+1, (length-d)/7+1) {
+			days = append(days, candidate)
+		}
+	}
+	return days
+}
+
+func (r Rule) applySetPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+
+	var selected []time.Time
+	for _, position := range r.BySetPos {
+		index := position - 1
+		if position < 0 {
+			index = len(days) + position
+		}
+		if index >= 0 && index < len(days) {
+			selected = append(selected, days[index])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+func (r Rule) matchesMonth(t time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if time.Month(month) == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := daysIn(t.Year(), t.Month())
+	for _, day := range r.ByMonthDay {
+		if day == t.Day() || (day < 0 && length+day+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday checks BYDAY ignoring the ordinals, which only make sense in monthly and yearly rules.
+func (r Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNthWeekday checks BYDAY given the position of the day among the same weekdays of its month or year,
+// counting from the start and from the end.
+func matchesNthWeekday(byDay []WeekdayNum, t time.Time, fromStart, fromEnd int) bool {
+	for _, day := range byDay {
+		if day.Day != t.Weekday() {
+			continue
+		}
+		if day.N == 0 || day.N == fromStart || -day.N == fromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return WeekdayNum{}, errors.New("unknown weekday")
+	}
+
+	day, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return WeekdayNum{}, errors.New("unknown weekday")
+	}
+
+	weekday := WeekdayNum{Day: day}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, errors.New("invalid weekday ordinal")
+		}
+		weekday.N = n
+	}
+	return weekday, nil
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var numbers []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("%d is out of range", n)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+func joinInts(numbers []int) string {
+	var parts []string
+	for _, n := range numbers {
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,161 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func mustRule(t *testing.T, value string) Rule {
+	t.Helper()
+	rule, err := ParseRule(value, time.UTC)
+	if err != nil {
+		t.Fatalf("ParseRule(%q): %v", value, err)
+	}
+	return rule
+}
+
+func assertTimes(t *testing.T, got []time.Time, want ...time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseRuleString(t *testing.T) {
+	for _, value := range []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;COUNT=10;BYDAY=MO,WE,FR",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=MONTHLY;UNTIL=20241231T235959Z;BYMONTHDAY=1,15",
+		"FREQ=YEARLY;BYDAY=MO,TU,WE,TH,FR;BYMONTH=3;BYSETPOS=-1;WKST=SU",
+	} {
+		if got := mustRule(t, value).String(); got != value {
+			t.Errorf("ParseRule(%q).String() = %q", value, got)
+		}
+	}
+}
+
+func TestParseRuleRejects(t *testing.T) {
+	for _, value := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;BYHOUR=9", "FREQ=DAILY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=XX", "FREQ"} {
+		if _, err := ParseRule(value, time.UTC); err == nil {
+			t.Errorf("ParseRule(%q) was accepted", value)
+		}
+	}
+}
+
+func TestOccurrencesWeeklyByDay(t *testing.T) {
+	//Monday 1 July 2024
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	rule := mustRule(t, "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=5")
+
+	got := rule.Occurrences(start, start, start.AddDate(1, 0, 0))
+	assertTimes(t, got,
+		start,
+		time.Date(2024, 7, 4, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 8, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 11, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 15, 9, 0, 0, 0, time.UTC),
+	)
+}
+
+func TestOccurrencesCountIncludesThoseBeforeFrom(t *testing.T) {
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	rule := mustRule(t, "FREQ=DAILY;INTERVAL=2;COUNT=4")
+
+	got := rule.Occurrences(start, start.AddDate(0, 0, 3), start.AddDate(0, 1, 0))
+	assertTimes(t, got, time.Date(2024, 7, 5, 9, 0, 0, 0, time.UTC), time.Date(2024, 7, 7, 9, 0, 0, 0, time.UTC))
+}
+
+func TestOccurrencesLastFridayUntil(t *testing.T) {
+	start := time.Date(2024, 1, 26, 18, 0, 0, 0, time.UTC)
+	rule := mustRule(t, "FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20240430T000000Z")
+
+	got := rule.Occurrences(start, start, start.AddDate(1, 0, 0))
+	assertTimes(t, got,
+		start,
+		time.Date(2024, 2, 23, 18, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 29, 18, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 26, 18, 0, 0, 0, time.UTC),
+	)
+}
+
+func TestOccurrencesSkipMissingMonthDays(t *testing.T) {
+	start := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	rule := mustRule(t, "FREQ=MONTHLY;COUNT=4")
+
+	got := rule.Occurrences(start, start, start.AddDate(2, 0, 0))
+	assertTimes(t, got,
+		start,
+		time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 31, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 31, 10, 0, 0, 0, time.UTC),
+	)
+}
+
+func TestOccurrencesKeepWallClockAcrossDST(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	//Spain moves to summer time on 31 March 2024
+	start := time.Date(2024, 3, 29, 10, 0, 0, 0, madrid)
+	got := mustRule(t, "FREQ=DAILY;COUNT=4").Occurrences(start, start, start.AddDate(0, 1, 0))
+	if len(got) != 4 {
+		t.Fatalf("got %v", got)
+	}
+	for _, occurrence := range got {
+		if local := occurrence.In(madrid); local.Hour() != 10 || local.Minute() != 0 {
+			t.Errorf("%s is not at 10:00 in Madrid", occurrence)
+		}
+	}
+}
+
+func TestOccurrencesRulesThatNeverMatch(t *testing.T) {
+	start := time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC)
+	year := start.AddDate(1, 0, 0)
+
+	cases := []struct {
+		value   string
+		periods int
+	}{
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", 2},
+		{"FREQ=YEARLY;BYMONTH=2;BYDAY=5MO;BYMONTHDAY=31", 2},
+		{"FREQ=MONTHLY;BYMONTH=4;BYMONTHDAY=31", 13},
+		{"FREQ=WEEKLY;BYDAY=MO;BYMONTH=2;BYSETPOS=3", 53},
+		{"FREQ=DAILY;BYMONTH=2;BYMONTHDAY=30", 367},
+	}
+
+	for _, c := range cases {
+		//only the periods of the window are walked, not the thousands a rule that never matches could
+		got, periods := mustRule(t, c.value).expand(start, start, year)
+		assertTimes(t, got, start)
+		if periods != c.periods {
+			t.Errorf("%s: walked %d periods, want %d", c.value, periods, c.periods)
+		}
+	}
+}
+
+func TestOccurrencesStopAtUntil(t *testing.T) {
+	start := time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC)
+	rule := mustRule(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30;UNTIL=20260101T000000Z")
+
+	got, periods := rule.expand(start, start, start.AddDate(1000, 0, 0))
+	assertTimes(t, got, start)
+	if periods != 3 {
+		t.Errorf("walked %d periods, want 3", periods)
+	}
+}
+
+func TestOccurrencesFarFromTheStart(t *testing.T) {
+	//a weekly series started long ago is still expanded in a window of today
+	start := time.Date(1990, 1, 1, 9, 0, 0, 0, time.UTC)
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	got := mustRule(t, "FREQ=WEEKLY").Occurrences(start, from, from.AddDate(0, 0, 7))
+	assertTimes(t, got, time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC))
+}
@@ -0,0 +1,122 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"pet-appointments-api/models"
+	"pet-appointments-api/safehttp"
+	"strings"
+	"time"
+)
+
+// SyncInterval is how often the calendar sources are imported again.
+const SyncInterval = time.Hour
+
+// FetchTimeout is how long fetching a calendar may take.
+const FetchTimeout = 30 * time.Second
+
+// Client is the HTTP client calendars are fetched with. The URLs are given by partners, so it refuses to connect to
+// the host or its private network.
+var Client = safehttp.NewClient(FetchTimeout)
+
+// FeedURL returns the URL a calendar is fetched from, accepting webcal:// URLs as calendar apps hand them out.
+func FeedURL(url string) string {
+	if strings.HasPrefix(url, "webcal://") {
+		return "https://" + strings.TrimPrefix(url, "webcal://")
+	}
+	return url
+}
+
+// Fetch downloads an iCalendar document of at most MaxSize bytes.
+func Fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	url = FeedURL(url)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "text/calendar")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar: fetching %s answered %s", url, response.Status)
+	}
+
+	document, err := io.ReadAll(io.LimitReader(response.Body, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(document) > MaxSize {
+		return nil, fmt.Errorf("calendar: %s is larger than %d bytes", url, MaxSize)
+	}
+	return document, nil
+}
+
+// SourceStore gives the Syncer access to the calendar sources and imports what it fetches.
+type SourceStore interface {
+	// DueSources returns the sources due at the given time, leasing them so no other syncer picks them up until the
+	// lease expires.
+	DueSources(ctx context.Context, now time.Time, lease time.Duration) ([]models.CalendarSource, error)
+	ImportSource(ctx context.Context, source models.CalendarSource, document io.Reader, now time.Time) error
+	SourceFailed(ctx context.Context, source models.CalendarSource, err error, now time.Time) error
+}
+
+// Syncer periodically imports the calendar sources of the partners.
+type Syncer struct {
+	Store    SourceStore
+	Client   *http.Client
+	Interval time.Duration
+	Lease    time.Duration
+}
+
+func NewSyncer(store SourceStore) *Syncer {
+	return &Syncer{Store: store, Client: Client, Interval: time.Minute, Lease: 10 * time.Minute}
+}
+
+// Start runs the syncer until the context is cancelled.
+func (s *Syncer) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Run(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run imports the sources due at the given time.
+func (s *Syncer) Run(ctx context.Context, now time.Time) {
+	sources, err := s.Store.DueSources(ctx, now, s.Lease)
+	if err != nil {
+		log.Println("calendar: looking for due calendar sources failed:", err)
+		return
+	}
+
+	for _, source := range sources {
+		document, err := Fetch(ctx, s.Client, source.URL)
+		if err == nil {
+			err = s.Store.ImportSource(ctx, source, bytes.NewReader(document), time.Now())
+		}
+		if err == nil {
+			continue
+		}
+
+		log.Println("calendar: importing calendar source", source.Id.Hex(), "failed:", err)
+		if err := s.Store.SourceFailed(ctx, source, err, time.Now()); err != nil {
+			log.Println("calendar: saving calendar source", source.Id.Hex(), "failed:", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+)
+
+// writeTimeZone writes the VTIMEZONE of a location, with one observance for the offset in effect when the period
+// starts and one for every transition until it ends, so calendar apps don't need to know the zone themselves.
+func writeTimeZone(out *writer, location *time.Location, from, to time.Time) {
+	out.line("BEGIN:VTIMEZONE")
+	out.line("TZID:" + location.String())
+
+	start := from.In(location)
+	_, offset := start.Zone()
+	writeObservance(out, start, offset, start)
+
+	for _, transition := range transitions(location, from, to) {
+		writeObservance(out, transition.at, transition.offsetFrom, transition.at.In(location))
+	}
+
+	out.line("END:VTIMEZONE")
+}
+
+// writeObservance writes a STANDARD or DAYLIGHT observance starting at the given instant, whose DTSTART is expressed
+// in the offset that was in effect before it.
+func writeObservance(out *writer, at time.Time, offsetFrom int, after time.Time) {
+	kind := "STANDARD"
+	if after.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, offsetTo := after.Zone()
+
+	out.line("BEGIN:" + kind)
+	out.line("DTSTART:" + at.UTC().Add(time.Duration(offsetFrom)*time.Second).Format("20060102T150405"))
+	out.line("TZOFFSETFROM:" + formatOffset(offsetFrom))
+	out.line("TZOFFSETTO:" + formatOffset(offsetTo))
+	out.line("TZNAME:" + escape(name))
+	out.line("END:" + kind)
+}
+
+type transition struct {
+	at         time.Time
+	offsetFrom int
+}
+
+// transitions returns the instants between from and to at which the UTC offset of the location changes.
+func transitions(location *time.Location, from, to time.Time) []transition {
+	var found []transition
+
+	//offsets change at most a few times a year, so looking at every day is enough to see them all
+	previous := from
+	_, previousOffset := previous.In(location).Zone()
+	for day := from.Add(24 * time.Hour); ; day = day.Add(24 * time.Hour) {
+		if day.After(to) {
+			day = to
+		}
+
+		if _, offset := day.In(location).Zone(); offset != previousOffset {
+			found = append(found, transition{at: firstWithOffset(location, previous, day, offset), offsetFrom: previousOffset})
+			previousOffset = offset
+		}
+
+		if !day.Before(to) {
+			return found
+		}
+		previous = day
+	}
+}
+
+// firstWithOffset narrows down the first second in (before, after] where the location has the given offset.
+func firstWithOffset(location *time.Location, before, after time.Time, offset int) time.Time {
+	low, high := before.Unix(), after.Unix()
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		if _, current := time.Unix(middle, 0).In(location).Zone(); current == offset {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return time.Unix(high, 0)
+}
+
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	formatted := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+	if seconds := offset % 60; seconds != 0 {
+		formatted += fmt.Sprintf("%02d", seconds)
+	}
+	return formatted
+}
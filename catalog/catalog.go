@@ -0,0 +1,245 @@
+package catalog
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"pet-appointments-api/models"
+	"pet-appointments-api/textmatch"
+	"sort"
+	"strings"
+)
+
+// Suggestion is a catalog entry offered for what someone started typing.
+type Suggestion struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	SpeciesCode string `json:"speciesCode,omitempty"`
+}
+
+// Catalog is the species and breeds catalog in memory, to match what people write against it.
+type Catalog struct {
+	species      []models.Species
+	speciesTerms [][]string
+	breeds       map[string][]models.Breed
+	breedTerms   map[string][][]string
+}
+
+// New builds a Catalog from its species and breeds.
+func New(species []models.Species, breeds []models.Breed) *Catalog {
+	c := &Catalog{species: species, breeds: map[string][]models.Breed{}, breedTerms: map[string][][]string{}}
+	for _, s := range species {
+		c.speciesTerms = append(c.speciesTerms, terms(s.Code, s.Names, s.Aliases))
+	}
+	for _, b := range breeds {
+		c.breeds[b.SpeciesCode] = append(c.breeds[b.SpeciesCode], b)
+		c.breedTerms[b.SpeciesCode] = append(c.breedTerms[b.SpeciesCode], terms(b.Code, b.Names, b.Aliases))
+	}
+	return c
+}
+
+// Load reads the whole catalog from its collections.
+func Load(ctx context.Context, speciesCollection *mongo.Collection, breedCollection *mongo.Collection) (*Catalog, error) {
+	var species []models.Species
+	results, err := speciesCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &species); err != nil {
+		return nil, err
+	}
+
+	var breeds []models.Breed
+	results, err = breedCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &breeds); err != nil {
+		return nil, err
+	}
+
+	return New(species, breeds), nil
+}
+
+// AllSpecies returns the species of the catalog.
+func (c *Catalog) AllSpecies() []models.Species {
+	return c.species
+}
+
+// Breeds returns the breeds of a species.
+func (c *Catalog) Breeds(speciesCode string) []models.Breed {
+	return c.breeds[speciesCode]
+}
+
+// Species finds the species a value names, by its code, one of its names or one of its aliases, ignoring case,
+// accents and spacing.
+func (c *Catalog) Species(value string) (models.Species, bool) {
+	if i := exact(c.speciesTerms, value); i >= 0 {
+		return c.species[i], true
+	}
+	return models.Species{}, false
+}
+
+// Breed finds the breed of a species a value names, the same way Species does.
+func (c *Catalog) Breed(speciesCode string, value string) (models.Breed, bool) {
+	if i := exact(c.breedTerms[speciesCode], value); i >= 0 {
+		return c.breeds[speciesCode][i], true
+	}
+	return models.Breed{}, false
+}
+
+// ClosestSpecies finds the species a value names like Species does, and otherwise the only species with a term one
+// or two typos away from it.
+func (c *Catalog) ClosestSpecies(value string) (models.Species, bool) {
+	if i := closest(c.speciesTerms, value); i >= 0 {
+		return c.species[i], true
+	}
+	return models.Species{}, false
+}
+
+// ClosestBreed finds the breed of a species a value names the same way ClosestSpecies does.
+func (c *Catalog) ClosestBreed(speciesCode string, value string) (models.Breed, bool) {
+	if i := closest(c.breedTerms[speciesCode], value); i >= 0 {
+		return c.breeds[speciesCode][i], true
+	}
+	return models.Breed{}, false
+}
+
+// SuggestSpecies returns the species someone may mean by a query, named in a language: the ones with a term
+// starting with the query first, then the ones with a term containing it and last the ones a typo away.
+func (c *Catalog) SuggestSpecies(query string, language string, limit int) []Suggestion {
+	suggestions := []Suggestion{}
+	for _, i := range suggest(c.speciesTerms, query, limit) {
+		suggestions = append(suggestions, Suggestion{Code: c.species[i].Code, Name: c.species[i].Name(language)})
+	}
+	return suggestions
+}
+
+// SuggestBreeds returns the breeds of a species someone may mean by a query, the same way SuggestSpecies does.
+func (c *Catalog) SuggestBreeds(speciesCode string, query string, language string, limit int) []Suggestion {
+	suggestions := []Suggestion{}
+	breeds := c.breeds[speciesCode]
+	for _, i := range suggest(c.breedTerms[speciesCode], query, limit) {
+		suggestions = append(suggestions, Suggestion{Code: breeds[i].Code, Name: breeds[i].Name(language), SpeciesCode: speciesCode})
+	}
+	return suggestions
+}
+
+// Code turns a name into a catalog code, like "guinea_pig" for "Guinea Pig".
+func Code(name string) string {
+	return strings.ReplaceAll(Normalize(name), " ", "_")
+}
+
+// Normalize is how values are compared with the catalog.
+func Normalize(value string) string {
+	return textmatch.Normalize(value)
+}
+
+func terms(code string, names map[string]string, aliases []string) []string {
+	all := []string{Normalize(code)}
+	for _, name := range names {
+		all = append(all, Normalize(name))
+	}
+	for _, alias := range aliases {
+		all = append(all, Normalize(alias))
+	}
+	return all
+}
+
+func exact(entries [][]string, value string) int {
+	value = Normalize(value)
+	if value == "" {
+		return -1
+	}
+	for i, entryTerms := range entries {
+		for _, term := range entryTerms {
+			if term == value {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// closest allows one typo in short values and two in longer ones, and gives up when two entries are as close.
+func closest(entries [][]string, value string) int {
+	if i := exact(entries, value); i >= 0 {
+		return i
+	}
+
+	value = Normalize(value)
+	allowed := 1
+	if len([]rune(value)) >= 8 {
+		allowed = 2
+	}
+	if len([]rune(value)) < 4 {
+		return -1
+	}
+
+	best, bestDistance, tied := -1, allowed+1, false
+	for i, entryTerms := range entries {
+		distance := allowed + 1
+		for _, term := range entryTerms {
+			if d := textmatch.Distance(term, value); d < distance {
+				distance = d
+			}
+		}
+		if distance < bestDistance {
+			best, bestDistance, tied = i, distance, false
+		} else if distance == bestDistance && distance <= allowed {
+			tied = true
+		}
+	}
+	if tied {
+		return -1
+	}
+	return best
+}
+
+func suggest(entries [][]string, query string, limit int) []int {
+	query = Normalize(query)
+	type ranked struct {
+		index int
+		rank  int
+	}
+
+	var matches []ranked
+	for i, entryTerms := range entries {
+		rank := -1
+		for _, term := range entryTerms {
+			switch {
+			case query == "" || strings.HasPrefix(term, query):
+				rank = 0
+			case strings.Contains(term, query) && (rank < 0 || rank > 1):
+				rank = 1
+			case len([]rune(query)) >= 3 && textmatch.Distance(prefix(term, len([]rune(query))), query) <= 1 && rank < 0:
+				rank = 2
+			}
+			if rank == 0 {
+				break
+			}
+		}
+		if rank >= 0 {
+			matches = append(matches, ranked{index: i, rank: rank})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].rank < matches[j].rank })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	indexes := []int{}
+	for _, match := range matches {
+		indexes = append(indexes, match.index)
+	}
+	return indexes
+}
+
+func prefix(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) > length {
+		runes = runes[:length]
+	}
+	return string(runes)
+}
@@ -0,0 +1,116 @@
+package catalog
+
+import (
+	"pet-appointments-api/models"
+	"testing"
+)
+
+func TestClosestSpecies(t *testing.T) {
+	catalog := New(Defaults())
+
+	cases := []struct {
+		value string
+		want  string
+	}{
+		//codes, names in every language and aliases, ignoring case, accents and spacing
+		{"dog", "dog"},
+		{"Dog", "dog"},
+		{"Perro", "dog"},
+		{"perrito", "dog"},
+		{"  GATO ", "cat"},
+		{"kitty", "cat"},
+		{"guinea_pig", "guinea_pig"},
+		{"Guinea-Pig", "guinea_pig"},
+		{"conejillo de indias", "guinea_pig"},
+		{"Hámster", "hamster"},
+		{"hamster", "hamster"},
+		{"Pájaro", "bird"},
+		{"pajaro", "bird"},
+		{"HURON", "ferret"},
+		//short values only match exactly
+		{"pez", "fish"},
+		{"cuy", "guinea_pig"},
+		{"dgo", ""},
+		{"cta", ""},
+		//one typo in short values, two in long ones
+		{"feret", "ferret"},
+		{"conejjo", "rabbit"},
+		{"hamstre", ""},
+		{"cabbalo", ""},
+		{"caballlo", "horse"},
+		{"conejitto", "rabbit"},
+		{"reptillle", "reptile"},
+		//as close to the cat as to the cavy, a guinea pig
+		{"caty", ""},
+		{"dinosaur", ""},
+		{"", ""},
+		{"---", ""},
+	}
+
+	for _, c := range cases {
+		species, ok := catalog.ClosestSpecies(c.value)
+		if ok != (c.want != "") || species.Code != c.want {
+			t.Errorf("ClosestSpecies(%q) = %q, %t, want %q", c.value, species.Code, ok, c.want)
+		}
+	}
+}
+
+func TestClosestSpeciesAddedLanguage(t *testing.T) {
+	species, breeds := Defaults()
+	species = append(species, models.Species{Code: "hedgehog", Names: map[string]string{"en": "Hedgehog", "de": "Igel", "fr": "Hérisson"}, Aliases: []string{"erizo"}})
+	catalog := New(species, breeds)
+
+	for value, want := range map[string]string{"igel": "hedgehog", "herisson": "hedgehog", "hérison": "hedgehog", "Erizo": "hedgehog", "Hund": ""} {
+		got, ok := catalog.ClosestSpecies(value)
+		if ok != (want != "") || got.Code != want {
+			t.Errorf("ClosestSpecies(%q) = %q, %t, want %q", value, got.Code, ok, want)
+		}
+	}
+
+	//the exact match comes first, even when another species is a typo away
+	if got, _ := catalog.Species("Hedgehog"); got.Code != "hedgehog" {
+		t.Errorf("Species(%q) = %q, want hedgehog", "Hedgehog", got.Code)
+	}
+	if got, ok := catalog.Species("hedgehgo"); ok {
+		t.Errorf("Species(%q) = %q, want no exact match", "hedgehgo", got.Code)
+	}
+}
+
+func TestClosestBreed(t *testing.T) {
+	catalog := New(Defaults())
+
+	cases := []struct {
+		species string
+		value   string
+		want    string
+	}{
+		{"dog", "Pastor Alemán", "german_shepherd"},
+		{"dog", "pastor aleman", "german_shepherd"},
+		{"dog", "Labrador", "labrador_retriever"},
+		{"dog", "perro salchicha", "dachshund"},
+		{"dog", "yorkie", "yorkshire_terrier"},
+		{"dog", "rotweiler", "rottweiler"},
+		{"dog", "mestizo", MixedBreed},
+		{"cat", "común", MixedBreed},
+		{"cat", "Siamés", "siamese"},
+		//breeds are only matched within their species
+		{"cat", "beagle", ""},
+		{"fish", "persian", ""},
+		{"unicorn", "mixed", ""},
+	}
+
+	for _, c := range cases {
+		breed, ok := catalog.ClosestBreed(c.species, c.value)
+		if ok != (c.want != "") || breed.Code != c.want {
+			t.Errorf("ClosestBreed(%q, %q) = %q, %t, want %q", c.species, c.value, breed.Code, ok, c.want)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	for name, want := range map[string]string{"Guinea Pig": "guinea_pig", "Pastor  Alemán": "pastor_aleman", "dog": "dog"} {
+		if got := Code(name); got != want {
+			t.Errorf("Code(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
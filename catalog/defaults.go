@@ -0,0 +1,71 @@
+package catalog
+
+import "pet-appointments-api/models"
+
+// MixedBreed is the breed every species has for pets of mixed or unknown breed.
+const MixedBreed = "mixed"
+
+// defaultSpecies are the species the catalog starts with. Administrators add and edit the rest.
+var defaultSpecies = []models.Species{
+	{Code: "dog", Names: map[string]string{"en": "Dog", "es": "Perro"}, Aliases: []string{"canine", "puppy", "pup", "perrito", "cachorro", "can"}},
+	{Code: "cat", Names: map[string]string{"en": "Cat", "es": "Gato"}, Aliases: []string{"feline", "kitten", "kitty", "gatito", "felino"}},
+	{Code: "rabbit", Names: map[string]string{"en": "Rabbit", "es": "Conejo"}, Aliases: []string{"bunny", "conejito"}},
+	{Code: "guinea_pig", Names: map[string]string{"en": "Guinea pig", "es": "Cobaya"}, Aliases: []string{"cavy", "cobayo", "cuy", "conejillo de indias"}},
+	{Code: "hamster", Names: map[string]string{"en": "Hamster", "es": "Hámster"}},
+	{Code: "ferret", Names: map[string]string{"en": "Ferret", "es": "Hurón"}},
+	{Code: "bird", Names: map[string]string{"en": "Bird", "es": "Ave"}, Aliases: []string{"pájaro", "pajarito"}},
+	{Code: "reptile", Names: map[string]string{"en": "Reptile", "es": "Reptil"}},
+	{Code: "fish", Names: map[string]string{"en": "Fish", "es": "Pez"}},
+	{Code: "horse", Names: map[string]string{"en": "Horse", "es": "Caballo"}, Aliases: []string{"pony", "equine", "yegua"}},
+}
+
+// defaultBreeds are the breeds the catalog starts with, besides the mixed breed of every species.
+var defaultBreeds = map[string][]models.Breed{
+	"dog": {
+		{Code: "labrador_retriever", Names: map[string]string{"en": "Labrador Retriever", "es": "Labrador"}, Aliases: []string{"lab"}},
+		{Code: "golden_retriever", Names: map[string]string{"en": "Golden Retriever", "es": "Golden Retriever"}, Aliases: []string{"golden"}},
+		{Code: "german_shepherd", Names: map[string]string{"en": "German Shepherd", "es": "Pastor alemán"}, Aliases: []string{"alsatian"}},
+		{Code: "french_bulldog", Names: map[string]string{"en": "French Bulldog", "es": "Bulldog francés"}, Aliases: []string{"frenchie"}},
+		{Code: "bulldog", Names: map[string]string{"en": "Bulldog", "es": "Bulldog inglés"}, Aliases: []string{"english bulldog"}},
+		{Code: "poodle", Names: map[string]string{"en": "Poodle", "es": "Caniche"}},
+		{Code: "beagle", Names: map[string]string{"en": "Beagle", "es": "Beagle"}},
+		{Code: "chihuahua", Names: map[string]string{"en": "Chihuahua", "es": "Chihuahua"}},
+		{Code: "dachshund", Names: map[string]string{"en": "Dachshund", "es": "Teckel"}, Aliases: []string{"sausage dog", "salchicha", "perro salchicha", "dackel"}},
+		{Code: "yorkshire_terrier", Names: map[string]string{"en": "Yorkshire Terrier", "es": "Yorkshire terrier"}, Aliases: []string{"yorkie"}},
+		{Code: "boxer", Names: map[string]string{"en": "Boxer", "es": "Bóxer"}},
+		{Code: "border_collie", Names: map[string]string{"en": "Border Collie", "es": "Border collie"}},
+		{Code: "siberian_husky", Names: map[string]string{"en": "Siberian Husky", "es": "Husky siberiano"}, Aliases: []string{"husky"}},
+		{Code: "shih_tzu", Names: map[string]string{"en": "Shih Tzu", "es": "Shih tzu"}},
+		{Code: "pug", Names: map[string]string{"en": "Pug", "es": "Carlino"}},
+		{Code: "rottweiler", Names: map[string]string{"en": "Rottweiler", "es": "Rottweiler"}},
+		{Code: "cocker_spaniel", Names: map[string]string{"en": "Cocker Spaniel", "es": "Cocker spaniel"}, Aliases: []string{"cocker"}},
+	},
+	"cat": {
+		{Code: "siamese", Names: map[string]string{"en": "Siamese", "es": "Siamés"}},
+		{Code: "persian", Names: map[string]string{"en": "Persian", "es": "Persa"}},
+		{Code: "maine_coon", Names: map[string]string{"en": "Maine Coon", "es": "Maine coon"}},
+		{Code: "bengal", Names: map[string]string{"en": "Bengal", "es": "Bengalí"}},
+		{Code: "british_shorthair", Names: map[string]string{"en": "British Shorthair", "es": "Británico de pelo corto"}},
+		{Code: "ragdoll", Names: map[string]string{"en": "Ragdoll", "es": "Ragdoll"}},
+		{Code: "sphynx", Names: map[string]string{"en": "Sphynx", "es": "Esfinge"}},
+		{Code: "abyssinian", Names: map[string]string{"en": "Abyssinian", "es": "Abisinio"}},
+	},
+}
+
+// Defaults returns the species and breeds the catalog starts with.
+func Defaults() ([]models.Species, []models.Breed) {
+	var breeds []models.Breed
+	for _, species := range defaultSpecies {
+		breeds = append(breeds, models.Breed{
+			SpeciesCode: species.Code,
+			Code:        MixedBreed,
+			Names:       map[string]string{"en": "Mixed breed", "es": "Mestizo"},
+			Aliases:     []string{"mix", "mixed", "mutt", "crossbreed", "cross", "unknown", "other", "mestiza", "cruce", "común"},
+		})
+		for _, breed := range defaultBreeds[species.Code] {
+			breed.SpeciesCode = species.Code
+			breeds = append(breeds, breed)
+		}
+	}
+	return append([]models.Species(nil), defaultSpecies...), breeds
+}
@@ -4,6 +4,10 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"pet-appointments-api/contact"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // This function checks if the environment variable is correctly loaded, and if it exist, returns the variable.
@@ -15,3 +19,192 @@ func EnvMongoURI() string {
 
 	return os.Getenv("MONGOURI")
 }
+
+// Returns the card gateway used for card payments. Only "fake" is available for now, any other value disables card payments.
+func EnvCardGateway() string {
+	return getEnv("CARD_GATEWAY", "")
+}
+
+// Returns the platform commission in basis points (1% = 100) applied to partners without their own commission rule.
+func EnvCommissionBasisPoints() int64 {
+	basisPoints, err := strconv.ParseInt(getEnv("COMMISSION_BASIS_POINTS", "1000"), 10, 64)
+	if err != nil {
+		log.Fatal("Error: COMMISSION_BASIS_POINTS must be an integer!!")
+	}
+	return basisPoints
+}
+
+// Returns how long before an appointment its reminders are sent, from a comma separated list like "24h,2h".
+func EnvReminderOffsets() []time.Duration {
+	var offsets []time.Duration
+	for _, value := range strings.Split(getEnv("REMINDER_OFFSETS", "24h,2h"), ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			log.Fatal("Error: REMINDER_OFFSETS must be a comma separated list of durations!!")
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// Returns how long before a vaccination is due its reminders are sent, from a comma separated list like "720h,168h".
+func EnvVaccinationReminderOffsets() []time.Duration {
+	var offsets []time.Duration
+	for _, value := range strings.Split(getEnv("VACCINATION_REMINDER_OFFSETS", "720h,168h"), ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			log.Fatal("Error: VACCINATION_REMINDER_OFFSETS must be a comma separated list of durations!!")
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// Returns how long a freed slot is held for a waitlisted owner before it is offered to the next one.
+func EnvWaitlistHold() time.Duration {
+	hold, err := time.ParseDuration(getEnv("WAITLIST_HOLD", "2h"))
+	if err != nil || hold <= 0 {
+		log.Fatal("Error: WAITLIST_HOLD must be a positive duration!!")
+	}
+	return hold
+}
+
+// Returns how long a slot hold reserves a slot while the booking is filled in.
+func EnvSlotHold() time.Duration {
+	hold, err := time.ParseDuration(getEnv("SLOT_HOLD", "5m"))
+	if err != nil || hold <= 0 {
+		log.Fatal("Error: SLOT_HOLD must be a positive duration!!")
+	}
+	return hold
+}
+
+// Returns how many slots an owner can hold at the same time.
+func EnvSlotHoldsPerOwner() int {
+	holds, err := strconv.Atoi(getEnv("SLOT_HOLDS_PER_OWNER", "3"))
+	if err != nil || holds <= 0 {
+		log.Fatal("Error: SLOT_HOLDS_PER_OWNER must be a positive number!!")
+	}
+	return holds
+}
+
+// Returns where attachments are stored, "local" for a directory of the server or "s3" for an S3-compatible bucket.
+func EnvAttachmentStore() string {
+	return getEnv("ATTACHMENT_STORE", "local")
+}
+
+// Returns the directory the "local" attachment store keeps its files in.
+func EnvAttachmentDir() string {
+	return getEnv("ATTACHMENT_DIR", "uploads")
+}
+
+// Returns the bucket settings of the "s3" attachment store. The endpoint is only set for S3-compatible services
+// other than AWS, like a local MinIO.
+func EnvS3() (endpoint string, region string, bucket string, accessKey string, secretKey string) {
+	return getEnv("S3_ENDPOINT", ""), getEnv("S3_REGION", "us-east-1"), getEnv("S3_BUCKET", ""), getEnv("S3_ACCESS_KEY", ""), getEnv("S3_SECRET_KEY", "")
+}
+
+// Returns the largest attachment that can be uploaded, in bytes.
+func EnvAttachmentMaxSize() int {
+	size, err := strconv.Atoi(getEnv("ATTACHMENT_MAX_SIZE", "20971520"))
+	if err != nil || size <= 0 {
+		log.Fatal("Error: ATTACHMENT_MAX_SIZE must be a positive number of bytes!!")
+	}
+	return size
+}
+
+// Returns the secret attachment download URLs are signed with. Without one, a random secret is used and the URLs
+// stop working when the API restarts.
+func EnvAttachmentURLSecret() string {
+	return getEnv("ATTACHMENT_URL_SECRET", "")
+}
+
+// Returns how long attachment download URLs work for.
+func EnvAttachmentURLTTL() time.Duration {
+	ttl, err := time.ParseDuration(getEnv("ATTACHMENT_URL_TTL", "15m"))
+	if err != nil || ttl <= 0 {
+		log.Fatal("Error: ATTACHMENT_URL_TTL must be a positive duration!!")
+	}
+	return ttl
+}
+
+// Returns how addresses are located. Only "fixture" is available for now, which uses known locations without
+// calling any service.
+func EnvGeocoder() string {
+	return getEnv("GEOCODER", "fixture")
+}
+
+// Returns the JSON file of known locations the "fixture" geocoder uses. Without one, it knows the centres of some
+// large cities.
+func EnvGeocoderFixtures() string {
+	return getEnv("GEOCODER_FIXTURES", "")
+}
+
+// Returns the country phone numbers written without their calling code are read in, as an ISO 3166 code like "US".
+func EnvPhoneRegion() string {
+	region := strings.ToUpper(getEnv("PHONE_DEFAULT_REGION", "US"))
+	if !contact.KnownRegion(region) {
+		log.Fatal("Error: PHONE_DEFAULT_REGION must be a supported ISO 3166 country code!!")
+	}
+	return region
+}
+
+// Returns the ISO 4217 currency of the amounts stored before prices carried their currency, like "USD".
+func EnvDefaultCurrency() string {
+	currency := strings.ToUpper(getEnv("DEFAULT_CURRENCY", "USD"))
+	if len(currency) != 3 {
+		log.Fatal("Error: DEFAULT_CURRENCY must be an ISO 4217 currency code!!")
+	}
+	return currency
+}
+
+// Returns the channels notifications are sent through, from a comma separated list of "smtp", "sms", "log" and "file".
+func EnvNotifiers() []string {
+	var notifiers []string
+	for _, value := range strings.Split(getEnv("NOTIFIERS", "log"), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			notifiers = append(notifiers, value)
+		}
+	}
+	return notifiers
+}
+
+// Returns the brokers domain events are published to besides the in-process bus, from a comma separated list of "log".
+func EnvEventBrokers() []string {
+	var brokers []string
+	for _, value := range strings.Split(getEnv("EVENT_BROKERS", ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			brokers = append(brokers, value)
+		}
+	}
+	return brokers
+}
+
+// Returns the SMTP server settings used to send emails.
+func EnvSMTP() (host string, port int, username string, password string, from string) {
+	port, err := strconv.Atoi(getEnv("SMTP_PORT", "25"))
+	if err != nil {
+		log.Fatal("Error: SMTP_PORT must be an integer!!")
+	}
+	return getEnv("SMTP_HOST", "localhost"), port, getEnv("SMTP_USERNAME", ""), getEnv("SMTP_PASSWORD", ""), getEnv("SMTP_FROM", "no-reply@localhost")
+}
+
+// Returns the SMS gateway settings used to send text messages.
+func EnvSMSGateway() (url string, apiKey string, from string) {
+	return getEnv("SMS_GATEWAY_URL", ""), getEnv("SMS_GATEWAY_API_KEY", ""), getEnv("SMS_FROM", "")
+}
+
+// Returns the file the "file" notifier appends messages to.
+func EnvNotificationLogFile() string {
+	return getEnv("NOTIFICATION_LOG_FILE", "notifications.log")
+}
+
+// getEnv returns an optional environment variable, or the fallback value when it is not set.
+func getEnv(key string, fallback string) string {
+	//the .env file is optional for these settings, so a missing file is not an error here
+	_ = godotenv.Load()
+
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,27 @@
+package contact
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// ErrInvalidEmail is returned for values that aren't a single email address.
+var ErrInvalidEmail = errors.New("contact: invalid email address")
+
+// ParseEmail checks an email address is one that can be written to, like "ana@example.com" but not
+// "Ana <ana@example.com>" or "ana@localhost", and returns it normalised.
+func ParseEmail(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value || address.Name != "" {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(value, "@")
+	domain := value[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(value), nil
+}
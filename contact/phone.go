@@ -0,0 +1,149 @@
+package contact
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidPhone is returned for values that aren't a phone number.
+	ErrInvalidPhone = errors.New("contact: invalid phone number")
+	// ErrUnknownRegion is returned when a national number has to be read in a region this package doesn't know.
+	ErrUnknownRegion = errors.New("contact: unknown phone region")
+)
+
+// region is how phone numbers are written in a country: its calling code, the prefix dialled before national
+// numbers inside the country and how many digits numbers have without either.
+type region struct {
+	code      string
+	trunk     string
+	minDigits int
+	maxDigits int
+}
+
+// regions are the countries national numbers can be read in, by ISO 3166 code. Numbers written with their
+// calling code are accepted from anywhere.
+var regions = map[string]region{
+	"AR": {code: "54", trunk: "0", minDigits: 10, maxDigits: 11},
+	"AT": {code: "43", trunk: "0", minDigits: 4, maxDigits: 13},
+	"AU": {code: "61", trunk: "0", minDigits: 9, maxDigits: 9},
+	"BE": {code: "32", trunk: "0", minDigits: 8, maxDigits: 9},
+	"BR": {code: "55", trunk: "0", minDigits: 10, maxDigits: 11},
+	"CA": {code: "1", trunk: "1", minDigits: 10, maxDigits: 10},
+	"CH": {code: "41", trunk: "0", minDigits: 9, maxDigits: 9},
+	"CL": {code: "56", minDigits: 9, maxDigits: 9},
+	"CO": {code: "57", minDigits: 8, maxDigits: 10},
+	"DE": {code: "49", trunk: "0", minDigits: 6, maxDigits: 13},
+	"DK": {code: "45", minDigits: 8, maxDigits: 8},
+	"EC": {code: "593", trunk: "0", minDigits: 8, maxDigits: 9},
+	"ES": {code: "34", minDigits: 9, maxDigits: 9},
+	"FI": {code: "358", trunk: "0", minDigits: 5, maxDigits: 12},
+	"FR": {code: "33", trunk: "0", minDigits: 9, maxDigits: 9},
+	"GB": {code: "44", trunk: "0", minDigits: 9, maxDigits: 10},
+	"IE": {code: "353", trunk: "0", minDigits: 7, maxDigits: 9},
+	"IN": {code: "91", trunk: "0", minDigits: 10, maxDigits: 10},
+	"IT": {code: "39", minDigits: 6, maxDigits: 11},
+	"JP": {code: "81", trunk: "0", minDigits: 9, maxDigits: 10},
+	"MX": {code: "52", minDigits: 10, maxDigits: 10},
+	"NL": {code: "31", trunk: "0", minDigits: 9, maxDigits: 9},
+	"NO": {code: "47", minDigits: 8, maxDigits: 8},
+	"NZ": {code: "64", trunk: "0", minDigits: 8, maxDigits: 10},
+	"PE": {code: "51", trunk: "0", minDigits: 8, maxDigits: 9},
+	"PL": {code: "48", minDigits: 9, maxDigits: 9},
+	"PT": {code: "351", minDigits: 9, maxDigits: 9},
+	"SE": {code: "46", trunk: "0", minDigits: 6, maxDigits: 9},
+	"US": {code: "1", trunk: "1", minDigits: 10, maxDigits: 10},
+	"UY": {code: "598", trunk: "0", minDigits: 8, maxDigits: 8},
+	"VE": {code: "58", trunk: "0", minDigits: 10, maxDigits: 10},
+	"ZA": {code: "27", trunk: "0", minDigits: 9, maxDigits: 9},
+}
+
+// byCode are the regions by calling code. Countries sharing a code, like the US and Canada, number alike.
+var byCode = map[string]region{}
+
+func init() {
+	for _, r := range regions {
+		byCode[r.code] = r
+	}
+}
+
+// KnownRegion tells whether national numbers can be read in a region.
+func KnownRegion(code string) bool {
+	_, ok := regions[strings.ToUpper(code)]
+	return ok
+}
+
+// ParsePhone reads a phone number as people write it, like "+44 20 7946 0958", "0044 20 7946 0958" or, in the
+// default region, "020 7946 0958", and returns it in E.164, like "+442079460958".
+func ParsePhone(value string, defaultRegion string) (string, error) {
+	digits, international := clean(value)
+	if digits == "" {
+		return "", ErrInvalidPhone
+	}
+
+	if international {
+		//calling codes are prefix-free, so the first one matching is the only one
+		for length := 1; length <= 3 && length < len(digits); length++ {
+			if r, ok := byCode[digits[:length]]; ok {
+				national, ok := r.national(digits[length:])
+				if !ok {
+					return "", ErrInvalidPhone
+				}
+				return "+" + r.code + national, nil
+			}
+		}
+		//countries missing from the table only get the length E.164 allows checked
+		if len(digits) < 7 || len(digits) > 15 || digits[0] == '0' {
+			return "", ErrInvalidPhone
+		}
+		return "+" + digits, nil
+	}
+
+	r, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return "", ErrUnknownRegion
+	}
+	national, ok := r.national(digits)
+	if !ok {
+		return "", ErrInvalidPhone
+	}
+	return "+" + r.code + national, nil
+}
+
+// national checks the digits after the calling code, dropping the trunk prefix when it was written, even after
+// the calling code like in "+44 (0)20 7946 0958". National numbers never start with the trunk prefix of their
+// country, as it couldn't be told apart when dialled, so a number starting with it always has it written.
+func (r region) national(digits string) (string, bool) {
+	if r.trunk != "" && strings.HasPrefix(digits, r.trunk) {
+		digits = digits[len(r.trunk):]
+	}
+	if len(digits) < r.minDigits || len(digits) > r.maxDigits {
+		return "", false
+	}
+	return digits, true
+}
+
+// clean keeps the digits of a phone number, telling whether it was written with its calling code. Spaces, dashes,
+// dots, slashes and parentheses are how people group digits, anything else makes it invalid.
+func clean(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	international := false
+	switch {
+	case strings.HasPrefix(value, "+"):
+		international, value = true, value[1:]
+	case strings.HasPrefix(value, "00"):
+		international, value = true, value[2:]
+	}
+
+	var digits strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '/' || r == '(' || r == ')' || r == ' ':
+		default:
+			return "", false
+		}
+	}
+	return digits.String(), international
+}
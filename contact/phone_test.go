@@ -0,0 +1,57 @@
+package contact
+
+import "testing"
+
+func TestParsePhone(t *testing.T) {
+	cases := []struct {
+		name   string
+		value  string
+		region string
+		want   string
+		err    error
+	}{
+		{"international", "+44 20 7946 0958", "", "+442079460958", nil},
+		{"international with 00", "0044 20 7946 0958", "US", "+442079460958", nil},
+		{"trunk prefix after the calling code", "+44 (0)20 7946 0958", "", "+442079460958", nil},
+		{"trunk prefix after a calling code with long numbers", "+49 (0)30 123456", "", "+4930123456", nil},
+		{"national with the trunk prefix", "020 7946 0958", "GB", "+442079460958", nil},
+		{"national without the trunk prefix", "20 7946 0958", "gb", "+442079460958", nil},
+		{"national in a region without trunk prefix", "612 34 56 78", "ES", "+34612345678", nil},
+		{"national starting with 0 in a region without trunk prefix", "06 1234 5678", "IT", "+390612345678", nil},
+		{"dots, dashes and slashes", "030/123.456-7", "DE", "+49301234567", nil},
+		{"US national", "(415) 555-0100", "US", "+14155550100", nil},
+		{"US national with the trunk prefix", "1 415 555 0100", "US", "+14155550100", nil},
+		{"US national a digit short with the trunk prefix", "1 415 555 010", "US", "", ErrInvalidPhone},
+		{"CA national", "604-555-0100", "CA", "+16045550100", nil},
+		{"CA international", "+1 604 555 0100", "GB", "+16045550100", nil},
+		{"US international read in CA", "+1 415 555 0100", "CA", "+14155550100", nil},
+		{"too short for its region", "+44 20 7946", "", "", ErrInvalidPhone},
+		{"too long for its region", "+33 6 12 34 56 78 9", "", "", ErrInvalidPhone},
+		{"only the trunk prefix and too few digits", "+44 0 2079 4609", "", "", ErrInvalidPhone},
+		{"unknown calling code", "+999 123 4567", "", "+9991234567", nil},
+		{"unknown calling code, too short", "+999 123", "", "", ErrInvalidPhone},
+		{"unknown calling code, too long", "+999 1234 5678 90123", "", "", ErrInvalidPhone},
+		{"calling code starting with 0", "+0 123 4567", "", "", ErrInvalidPhone},
+		{"national in an unknown region", "020 7946 0958", "XX", "", ErrUnknownRegion},
+		{"national without a region", "020 7946 0958", "", "", ErrUnknownRegion},
+		{"letters", "+44 20 CALL NOW", "", "", ErrInvalidPhone},
+		{"extension", "+44 20 7946 0958 ext 12", "", "", ErrInvalidPhone},
+		{"empty", "  ", "GB", "", ErrInvalidPhone},
+		{"only a plus", "+", "GB", "", ErrInvalidPhone},
+	}
+
+	for _, c := range cases {
+		got, err := ParsePhone(c.value, c.region)
+		if got != c.want || err != c.err {
+			t.Errorf("%s: ParsePhone(%q, %q) = %q, %v, want %q, %v", c.name, c.value, c.region, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestKnownRegion(t *testing.T) {
+	for region, want := range map[string]bool{"GB": true, "us": true, "XX": false, "": false} {
+		if got := KnownRegion(region); got != want {
+			t.Errorf("KnownRegion(%q) = %t, want %t", region, got, want)
+		}
+	}
+}
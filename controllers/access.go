@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pet-appointments-api/models"
+)
+
+// The API has no authentication of its own. The gateway in front of it authenticates the caller and forwards who
+// they are in one of these headers, which the endpoints serving restricted data rely on.
+const (
+	headerOwnerId   = "X-Owner-Id"
+	headerPartnerId = "X-Partner-Id"
+)
+
+// appointmentParty reports whether the caller is the owner or the partner of an appointment.
+func appointmentParty(c *fiber.Ctx, appointment models.Appointment) bool {
+	ownerId, partnerId := c.Get(headerOwnerId), c.Get(headerPartnerId)
+	return (ownerId != "" && ownerId == appointment.OwnerId) || (partnerId != "" && partnerId == appointment.PartnerId)
+}
+
+// treatedPet reports whether a partner completed an appointment of a pet.
+func treatedPet(ctx context.Context, partnerId string, petId string) (bool, error) {
+	filter := bson.M{"petid": petId, "partnerid": partnerId, "status": models.AppointmentCompleted}
+	count, err := appointmentCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// canReadMedicalRecord reports whether the caller is the owner of a pet or a partner that treated it.
+func canReadMedicalRecord(ctx context.Context, c *fiber.Ctx, pet models.Pet) (bool, error) {
+	if ownerId := c.Get(headerOwnerId); ownerId != "" && ownerId == pet.OwnerId {
+		return true, nil
+	}
+	if partnerId := c.Get(headerPartnerId); partnerId != "" {
+		return treatedPet(ctx, partnerId, pet.Id.Hex())
+	}
+	return false, nil
+}
+
+// medicalRecordAuthor returns the partner making the call when they treated the pet, as only they can write in its
+// medical record.
+func medicalRecordAuthor(ctx context.Context, c *fiber.Ctx, pet models.Pet) (string, bool, error) {
+	partnerId := c.Get(headerPartnerId)
+	if partnerId == "" {
+		return "", false, nil
+	}
+
+	treated, err := treatedPet(ctx, partnerId, pet.Id.Hex())
+	return partnerId, treated, err
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"pet-appointments-api/geocoding"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+)
+
+// locateAddress sets the location of an address that was sent without one. A location that was sent is kept, it
+// is more precise than what the geocoder finds.
+func locateAddress(ctx context.Context, address *models.Address) error {
+	if address == nil {
+		return nil
+	}
+	if address.Location != nil {
+		if !address.Location.Valid() {
+			return fmt.Errorf("%w: it must be a point with a longitude and a latitude", errInvalidLocation)
+		}
+		return nil
+	}
+
+	location, err := geocoding.Default().Geocode(ctx, *address)
+	if errors.Is(err, geocoding.ErrNotFound) {
+		return fmt.Errorf("%w: %s, send its location instead", errAddressNotFound, address)
+	}
+	if err != nil {
+		return err
+	}
+	address.Location = &location
+	return nil
+}
+
+// addressError answers a request whose address could not be located.
+func addressError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errInvalidLocation):
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": err.Error()}})
+	case errors.Is(err, errAddressNotFound):
+		return c.Status(http.StatusUnprocessableEntity).JSON(responses.Response{Status: http.StatusUnprocessableEntity, Message: "Error: the address could not be located.", Data: &fiber.Map{"data": err.Error()}})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+}
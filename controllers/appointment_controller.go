@@ -2,11 +2,15 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"net/http"
 	"pet-appointments-api/configs"
 	"pet-appointments-api/models"
+	"pet-appointments-api/payments"
 	"pet-appointments-api/responses"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -18,7 +22,7 @@ import (
 var appointmentCollection *mongo.Collection = configs.GetCollection(configs.DB, "appointments")
 var validateAppointment = validator.New()
 
-// Create a new Appointment
+// Create a new Appointment, or a series of them when a recurrence rule is given
 func CreateAppointment(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	var appointment models.Appointment
@@ -34,23 +38,148 @@ func CreateAppointment(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
-	newAppointment := models.Appointment{
-		Id:          primitive.NewObjectID(),
-		OwnerId:     appointment.OwnerId,
-		PetId:       appointment.PetId,
-		PartnerId:   appointment.PartnerId,
-		Service:     appointment.Service,
-		Amount:      appointment.Amount,
-		PaymentType: appointment.PaymentType,
-		Date:        time.Now(),
+	//the price is always derived from the service catalog, never taken from the client
+	service, err := findService(ctx, appointment.ServiceId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid service ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partner, err := findPartner(ctx, appointment.PartnerId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
-	result, err := appointmentCollection.InsertOne(ctx, newAppointment)
+	//a recurrence rule books a series of linked occurrences
+	starts := []time.Time{appointment.StartTime}
+	seriesId, recurrence := "", ""
+	if appointment.Recurrence != "" {
+		rule, occurrences, err := seriesStarts(appointment.Recurrence, appointment.StartTime, partner.Location())
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid recurrence.", Data: &fiber.Map{"data": err.Error()}})
+		}
+		starts, seriesId, recurrence = occurrences, primitive.NewObjectID().Hex(), rule.String()
+	}
+
+	//a held slot can only be booked with the token of its hold, which covers the first occurrence of a series
+	var hold models.SlotHold
+	if appointment.HoldToken != "" {
+		if hold, err = findSlotHold(ctx, appointment.HoldToken, time.Now()); err != nil {
+			return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the hold expired or does not exist.", Data: &fiber.Map{"data": err.Error()}})
+		}
+		if hold.PartnerId != appointment.PartnerId || !hold.StartTime.Equal(appointment.StartTime) || appointment.StartTime.Add(service.Duration()).After(hold.EndTime) {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the hold is for another slot.", Data: &fiber.Map{"data": hold}})
+		}
+	}
+
+	//only the first occurrence of a series takes the coupon, so a series never runs out of it halfway
+	var newAppointments []models.Appointment
+	var coupon *models.Coupon
+	for index, start := range starts {
+		occurrence := appointment
+		occurrence.StartTime = start
+		if index > 0 {
+			occurrence.CouponCode = ""
+		}
+
+		breakdown, occurrenceCoupon, err := priceAppointment(ctx, occurrence, service, "")
+		if err != nil {
+			return pricingError(c, err)
+		}
+		if index == 0 {
+			coupon = occurrenceCoupon
+		}
+
+		newAppointment := scheduledAppointment(occurrence, service, breakdown)
+		newAppointment.Recurrence, newAppointment.SeriesId, newAppointment.OccurrenceIndex = recurrence, seriesId, index
+		newAppointments = append(newAppointments, newAppointment)
+	}
+
+	//the Appointments and their events are saved together, in the transaction that checks every occurrence fits in
+	//the partner agenda, under its lock
+	var results []*mongo.InsertOneResult
+	var conflicts []occurrenceConflict
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		results, conflicts = nil, nil
+		if err := lockPartnerAgenda(sc, appointment.PartnerId); err != nil {
+			return err
+		}
+		for _, start := range starts {
+			busy, err := findConflicts(sc, appointment.PartnerId, start, start.Add(service.Duration()))
+			if err != nil {
+				return err
+			}
+			if busy = withoutHold(busy, hold); len(busy) > 0 {
+				conflicts = append(conflicts, occurrenceConflict{Start: start, Conflicts: busy})
+			}
+		}
+		if len(conflicts) > 0 {
+			return errSlotUnavailable
+		}
+
+		if appointment.HoldToken != "" {
+			if err := consumeSlotHold(sc, hold, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		//the coupon is redeemed with the booking, so it isn't used up by a booking that is never saved
+		if err := redeemCoupon(sc, coupon); err != nil {
+			return err
+		}
+
+		for _, newAppointment := range newAppointments {
+			result, err := appointmentCollection.InsertOne(sc, newAppointment)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+
+			if err := recordEvent(sc, models.EventAppointmentCreated, newAppointment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errSlotUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the partner is not available at that time.", Data: &fiber.Map{"data": conflicts}})
+	}
+	if errors.Is(err, errHoldUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the hold expired or does not exist.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if errors.Is(err, errInvalidCoupon) {
+		return pricingError(c, err)
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment creation process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
-	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Appointment was created successfully.", Data: &fiber.Map{"data": result}})
+	notifyAppointment(ctx, models.EventBookingConfirmed, newAppointments[0], nil)
+
+	if seriesId == "" {
+		return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Appointment was created successfully.", Data: &fiber.Map{"data": results[0]}})
+	}
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new series of " + strconv.Itoa(len(newAppointments)) + " Appointments was created successfully.", Data: &fiber.Map{"data": results, "seriesId": seriesId}})
+}
+
+// scheduledAppointment builds a new scheduled Appointment for the service with the given price.
+func scheduledAppointment(appointment models.Appointment, service models.Service, breakdown models.PriceBreakdown) models.Appointment {
+	return models.Appointment{
+		Id:            primitive.NewObjectID(),
+		OwnerId:       appointment.OwnerId,
+		PetId:         appointment.PetId,
+		PartnerId:     appointment.PartnerId,
+		ServiceId:     appointment.ServiceId,
+		Service:       service.Name,
+		CouponCode:    strings.ToUpper(appointment.CouponCode),
+		Pricing:       breakdown,
+		PaymentType:   appointment.PaymentType,
+		PaymentStatus: models.AppointmentUnpaid,
+		Status:        models.AppointmentScheduled,
+		AmountPaid:    models.Zero(breakdown.Total.Currency),
+		StartTime:     appointment.StartTime,
+		EndTime:       appointment.StartTime.Add(service.Duration()),
+		Date:          time.Now(),
+	}
 }
 
 // Get an Appointment
@@ -71,14 +200,17 @@ func GetAppointment(c *fiber.Ctx) error {
 	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": appointment}})
 }
 
-// Edit an Appointment
+// Edit an Appointment, or with ?scope=following or ?scope=all also the other occurrences of its series
 func EditAppointment(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	appointmentId := c.Params("appointmentId")
 	var appointment models.Appointment
 	defer cancel()
 
-	objId, _ := primitive.ObjectIDFromHex(appointmentId)
+	scope, err := seriesScope(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid scope.", Data: &fiber.Map{"data": err.Error()}})
+	}
 
 	//validate the request body
 	if err := c.BodyParser(&appointment); err != nil {
@@ -90,25 +222,395 @@ func EditAppointment(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
-	update := bson.M{"petId": appointment.PetId, "partnerId": appointment.PartnerId, "service": appointment.Service, "amount": appointment.Amount, "paymentType": appointment.PaymentType}
+	//re-price the appointment against the catalog in case the service or the partner changed
+	service, err := findService(ctx, appointment.ServiceId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid service ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the owner is kept from the stored appointment, it is needed to price multi-pet discounts
+	previous, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
 
-	result, err := appointmentCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+	if !previous.IsScheduled() {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only scheduled appointments can be edited.", Data: &fiber.Map{"data": previous}})
+	}
 
+	targets, err := findSeriesOccurrences(ctx, previous, scope)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the other occurrences move by as much as the edited one, keeping their own coupon
+	shift := appointment.StartTime.Sub(previous.StartTime)
+
+	var edits []appointmentEdit
+	var coupon *models.Coupon
+	for _, target := range targets {
+		edited := appointment
+		edited.Id = target.Id
+		edited.OwnerId = target.OwnerId
+		edited.StartTime = target.StartTime.Add(shift)
+		if target.Id != previous.Id {
+			edited.CouponCode = target.CouponCode
+		}
+
+		breakdown, targetCoupon, err := priceAppointment(ctx, edited, service, target.CouponCode)
+		if err != nil {
+			return pricingError(c, err)
+		}
+		if target.Id == previous.Id {
+			coupon = targetCoupon
+		}
+
+		edits = append(edits, appointmentEdit{previous: target, edited: edited, breakdown: breakdown})
+	}
+	//the new times are checked against the partner agenda in the transaction that moves the occurrences, under its lock
+	var updatedAppointments []models.Appointment
+	var conflicts []occurrenceConflict
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		updatedAppointments, conflicts = nil, nil
+		if err := lockPartnerAgenda(sc, appointment.PartnerId); err != nil {
+			return err
+		}
+		for _, edit := range edits {
+			start := edit.edited.StartTime
+			busy, err := findConflicts(sc, edit.edited.PartnerId, start, start.Add(service.Duration()), targets...)
+			if err != nil {
+				return err
+			}
+			if len(busy) > 0 {
+				conflicts = append(conflicts, occurrenceConflict{Start: start, Conflicts: busy})
+			}
+		}
+		if len(conflicts) > 0 {
+			return errSlotUnavailable
+		}
+
+		//a coupon is only redeemed again when it changed, together with the edit
+		if !strings.EqualFold(previous.CouponCode, appointment.CouponCode) {
+			if err := redeemCoupon(sc, coupon); err != nil {
+				return err
+			}
+		}
+
+		for _, edit := range edits {
+			updated, err := saveAppointmentEdit(sc, edit, service)
+			if err != nil {
+				return err
+			}
+			updatedAppointments = append(updatedAppointments, updated)
+		}
+		return nil
+	})
+
+	if errors.Is(err, errSlotUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the partner is not available at that time.", Data: &fiber.Map{"data": conflicts}})
+	}
+	if errors.Is(err, errInvalidCoupon) {
+		return pricingError(c, err)
+	}
+	if errors.Is(err, errNotScheduled) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only scheduled appointments can be edited.", Data: &fiber.Map{"data": err.Error()}})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment edit process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
 	//get updated appointment details
 	var updatedAppointment models.Appointment
-	if result.MatchedCount == 1 {
-		err := appointmentCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedAppointment)
+	for _, updated := range updatedAppointments {
+		if updated.Id == previous.Id {
+			updatedAppointment = updated
+		}
+	}
+
+	if shift != 0 {
+		notifyAppointment(ctx, models.EventRescheduled, updatedAppointment, nil)
+	}
+
+	if scope == scopeThis {
+		return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Appointment with the ID " + appointmentId + " was edited correctly.", Data: &fiber.Map{"data": updatedAppointment}})
+	}
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Appointment with the ID " + appointmentId + " and " + strconv.Itoa(len(updatedAppointments)-1) + " other occurrences were edited correctly.", Data: &fiber.Map{"data": updatedAppointment, "series": updatedAppointments}})
+}
+
+// Complete an Appointment once the service was given
+func CompleteAppointment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if appointment.Status != "" && appointment.Status != models.AppointmentScheduled {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only scheduled appointments can be completed.", Data: &fiber.Map{"data": appointment}})
+	}
+
+	appointment.Status = models.AppointmentCompleted
+	appointment.UpdateDate = time.Now()
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := appointmentCollection.UpdateOne(sc, bson.M{"id": appointment.Id}, bson.M{"$set": bson.M{"status": models.AppointmentCompleted, "updatedate": appointment.UpdateDate}}); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventAppointmentCompleted, appointment)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment completion process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Appointment with the ID " + appointmentId + " was completed correctly.", Data: &fiber.Map{"data": appointment}})
+}
+
+// cancelRequest is the body accepted to cancel an Appointment. NoShow is used by partners when the owner did not show up.
+type cancelRequest struct {
+	Reason string `json:"reason,omitempty"`
+	NoShow bool   `json:"noShow,omitempty"`
+}
+
+// Cancel an Appointment applying the cancellation policy of its Partner, or with ?scope=following or ?scope=all
+// also the upcoming occurrences of its series
+func CancelAppointment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	var request cancelRequest
+	defer cancel()
+
+	scope, err := seriesScope(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid scope.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the body is optional
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if appointment.Status != "" && appointment.Status != models.AppointmentScheduled {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only scheduled appointments can be cancelled.", Data: &fiber.Map{"data": appointment}})
+	}
+
+	now := time.Now()
+	if request.NoShow && now.Before(appointment.StartTime) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: an appointment can't be marked as a no-show before it starts.", Data: &fiber.Map{"data": appointment.StartTime}})
+	}
+	if request.NoShow && scope != scopeThis {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: only a single occurrence can be marked as a no-show.", Data: &fiber.Map{"data": scope}})
+	}
+
+	targets, err := findSeriesOccurrences(ctx, appointment, scope)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment cancellation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	var cancelledAppointments []models.Appointment
+	var cancelledAppointment models.Appointment
+	for _, target := range targets {
+		//the other occurrences that already started are left alone, they are completed or marked as no-shows instead
+		if target.Id != appointment.Id && target.StartTime.Before(now) {
+			continue
+		}
+
+		cancelled, err := cancelAppointment(ctx, target, request, now)
+		if errors.Is(err, errNotScheduled) && target.Id != appointment.Id {
+			//another request completed or cancelled this occurrence in the meantime
+			continue
+		}
+		if errors.Is(err, errNotScheduled) {
+			return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only scheduled appointments can be cancelled.", Data: &fiber.Map{"data": err.Error()}})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment cancellation process failed.", Data: &fiber.Map{"data": err.Error(), "cancelled": cancelledAppointments}})
+		}
+		cancelledAppointments = append(cancelledAppointments, cancelled)
+
+		if target.Id == appointment.Id {
+			cancelledAppointment = cancelled
+		}
+	}
 
+	notifyAppointment(ctx, models.EventCancelled, cancelledAppointment, nil)
+
+	//the freed slots go to the waitlist of the partner, a no-show frees nothing
+	if !request.NoShow {
+		for _, cancelled := range cancelledAppointments {
+			offerFreedSlot(ctx, cancelled, now)
+		}
+	}
+
+	if scope == scopeThis {
+		return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Appointment with the ID " + appointmentId + " was cancelled correctly.", Data: &fiber.Map{"data": cancelledAppointment}})
+	}
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Appointment with the ID " + appointmentId + " and " + strconv.Itoa(len(cancelledAppointments)-1) + " other occurrences were cancelled correctly.", Data: &fiber.Map{"data": cancelledAppointment, "series": cancelledAppointments}})
+}
+
+// cancelAppointment claims the cancellation of a single appointment, settles it and stores the outcome with its
+// event. The appointment is only settled by whoever moves it out of scheduled, so cancelling it twice at once never
+// refunds or charges the fee twice.
+func cancelAppointment(ctx context.Context, appointment models.Appointment, request cancelRequest, now time.Time) (models.Appointment, error) {
+	outcome, owed, appointmentPayments, err := cancellationOutcome(ctx, appointment, request, now)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	status := models.AppointmentCancelled
+	if request.NoShow {
+		status = models.AppointmentNoShow
+	}
+
+	//the status moves first, together with the fee still owed, under the lock payments are created with
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		outcome.FeePaymentId = ""
+		if err := lockAppointmentPayments(sc, appointment.Id.Hex()); err != nil {
+			return err
+		}
+		filter := bson.M{"id": appointment.Id, "status": bson.M{"$in": models.ScheduledStatuses}}
+		update := bson.M{"status": status, "cancellation": outcome, "updatedate": now}
+		result, err := appointmentCollection.UpdateOne(sc, filter, bson.M{"$set": update, "$inc": bson.M{"sequence": 1}})
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+			return err
 		}
+		if result.ModifiedCount != 1 {
+			return errNotScheduled
+		}
+		return recordCancellationFee(sc, appointment, owed, &outcome, now)
+	})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	//the refunds go through the payment providers, so they can't be part of the transaction
+	if err := refundCancellation(ctx, appointmentPayments, &outcome); err != nil {
+		return models.Appointment{}, err
 	}
 
-	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Appointment with the ID " + appointmentId + " was edited correctly.", Data: &fiber.Map{"data": updatedAppointment}})
+	appointmentId := appointment.Id.Hex()
+	var cancelledAppointment models.Appointment
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := appointmentCollection.UpdateOne(sc, bson.M{"id": appointment.Id}, bson.M{"$set": bson.M{"cancellation": outcome}}); err != nil {
+			return err
+		}
+
+		if err := refreshAppointmentPayments(sc, appointmentId); err != nil {
+			return err
+		}
+
+		var err error
+		if cancelledAppointment, err = findAppointment(sc, appointmentId); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventAppointmentCancelled, cancelledAppointment)
+	})
+	return cancelledAppointment, err
+}
+
+// cancellationOutcome computes the fee owed for a cancelled appointment under the policy of its partner and how much
+// of what was paid is refunded, returning what is still owed and the payments of the appointment too.
+func cancellationOutcome(ctx context.Context, appointment models.Appointment, request cancelRequest, now time.Time) (models.CancellationOutcome, models.Money, []models.Payment, error) {
+	partner, err := findPartner(ctx, appointment.PartnerId)
+	if err != nil {
+		return models.CancellationOutcome{}, models.Money{}, nil, err
+	}
+
+	policy := partner.Policy()
+	feePercent, fee := payments.CancellationFee(policy, appointment.Pricing.Total, appointment.StartTime, now, request.NoShow)
+
+	outcome := models.CancellationOutcome{
+		CancelledAt:      now,
+		Reason:           request.Reason,
+		NoShow:           request.NoShow,
+		HoursBeforeStart: appointment.StartTime.Sub(now).Hours(),
+		Policy:           policy,
+		FeePercent:       feePercent,
+		Fee:              fee,
+	}
+
+	appointmentPayments, err := findPayments(ctx, appointment.Id.Hex())
+	if err != nil {
+		return outcome, models.Money{}, nil, err
+	}
+
+	paid, _, err := payments.Summarize(appointment.Pricing.Total, appointmentPayments)
+	if err != nil {
+		return outcome, models.Money{}, nil, err
+	}
+
+	refund, owed, err := payments.Settle(fee, paid)
+	if err != nil {
+		return outcome, models.Money{}, nil, err
+	}
+	outcome.Refund = refund
+	return outcome, owed, appointmentPayments, nil
+}
+
+// recordCancellationFee records a pending fee payment for whatever is still owed after a cancellation.
+func recordCancellationFee(sc mongo.SessionContext, appointment models.Appointment, owed models.Money, outcome *models.CancellationOutcome, now time.Time) error {
+	if owed.Amount <= 0 {
+		return nil
+	}
+
+	feePayment := models.Payment{
+		Id:             primitive.NewObjectID(),
+		AppointmentId:  appointment.Id.Hex(),
+		Method:         appointment.PaymentType,
+		Kind:           models.PaymentKindFee,
+		Amount:         owed,
+		RefundedAmount: models.Zero(owed.Currency),
+		Status:         models.PaymentStatusPending,
+		CreationDate:   now,
+		UpdateDate:     now,
+	}
+
+	if provider, err := payments.ProviderFor(feePayment.Method); err == nil {
+		feePayment.Provider = provider.Name()
+	}
+
+	if _, err := paymentCollection.InsertOne(sc, feePayment); err != nil {
+		return err
+	}
+	outcome.FeePaymentId = feePayment.Id.Hex()
+	return nil
+}
+
+// refundCancellation refunds what was paid above the cancellation fee, the newest captured payments first.
+func refundCancellation(ctx context.Context, appointmentPayments []models.Payment, outcome *models.CancellationOutcome) error {
+	remaining := outcome.Refund
+	for _, payment := range appointmentPayments {
+		if remaining.Amount <= 0 {
+			break
+		}
+		if payment.Status != models.PaymentStatusCaptured {
+			continue
+		}
+
+		refundable, err := payment.Amount.Sub(payment.RefundedAmount)
+		if err != nil {
+			return err
+		}
+		if refundable.Amount > remaining.Amount {
+			refundable = remaining
+		}
+
+		if _, err := refundPayment(ctx, payment, &refundable); err != nil {
+			return err
+		}
+
+		outcome.RefundPayments = append(outcome.RefundPayments, payment.Id.Hex())
+		remaining.Amount -= refundable.Amount
+	}
+	return nil
 }
 
 // Delete an Appointment
@@ -119,19 +621,24 @@ func DeleteAppointment(c *fiber.Ctx) error {
 
 	objId, _ := primitive.ObjectIDFromHex(appointmentId)
 
-	result, err := appointmentCollection.DeleteOne(ctx, bson.M{"id": objId})
-
-	//validate if the DeleteOne functions returns an Error
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no appointment with that ID. ", Data: &fiber.Map{"data": err.Error()}})
-	}
+	//the event tells the partner of the appointment, so its webhooks hear about it
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var deleted models.Appointment
+		if err := appointmentCollection.FindOneAndDelete(sc, bson.M{"id": objId}).Decode(&deleted); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventAppointmentDeleted, fiber.Map{"id": appointmentId, "partnerId": deleted.PartnerId})
+	})
 
 	//validate the ID number
-	if result.DeletedCount < 1 {
+	if err == mongo.ErrNoDocuments {
 		return c.Status(http.StatusNotFound).JSON(
 			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The appointment with the ID " + appointmentId + " does not exists."}},
 		)
 	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment deletion process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
 
 	return c.Status(http.StatusOK).JSON(
 		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The appointment was deleted successfully."}},
@@ -166,3 +673,24 @@ func GetAllAppointments(c *fiber.Ctx) error {
 		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": appointments}},
 	)
 }
+
+// findAppointment loads an Appointment by its ID.
+func findAppointment(ctx context.Context, appointmentId string) (models.Appointment, error) {
+	var appointment models.Appointment
+
+	objId, err := primitive.ObjectIDFromHex(appointmentId)
+	if err != nil {
+		return appointment, err
+	}
+
+	err = appointmentCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&appointment)
+	return appointment, err
+}
+
+// pricingError answers a request whose appointment could not be priced.
+func pricingError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errInvalidCoupon) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the coupon code is invalid.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment pricing process failed.", Data: &fiber.Map{"data": err.Error()}})
+}
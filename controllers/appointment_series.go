@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pet-appointments-api/calendar"
+	"pet-appointments-api/models"
+	"strings"
+	"time"
+)
+
+// The occurrences of a series an edit or a cancellation applies to.
+const (
+	scopeThis      = "this"
+	scopeFollowing = "following"
+	scopeAll       = "all"
+)
+
+const (
+	maxSeriesOccurrences = 104
+	maxSeriesSpan        = 2 * 365 * 24 * time.Hour
+)
+
+// occurrenceConflict is an occurrence that can't be booked because the partner is busy.
+type occurrenceConflict struct {
+	Start     time.Time    `json:"start"`
+	Conflicts []busyPeriod `json:"conflicts"`
+}
+
+// appointmentEdit is an occurrence as it was and as it will be once edited.
+type appointmentEdit struct {
+	previous  models.Appointment
+	edited    models.Appointment
+	breakdown models.PriceBreakdown
+}
+
+// seriesStarts expands the recurrence rule of a new appointment into the starts of its occurrences. The expansion
+// happens in the time zone of the partner, so occurrences keep their local time across DST changes. Only FREQ,
+// INTERVAL, COUNT, UNTIL and BYDAY are supported, and the series must end.
+func seriesStarts(recurrence string, start time.Time, location *time.Location) (calendar.Rule, []time.Time, error) {
+	rule, err := calendar.ParseRule(strings.TrimPrefix(strings.TrimSpace(recurrence), "RRULE:"), location)
+	if err != nil {
+		return rule, nil, fmt.Errorf("%w: %v", errInvalidRecurrence, err)
+	}
+
+	switch {
+	case len(rule.ByMonthDay) > 0 || len(rule.ByMonth) > 0 || len(rule.BySetPos) > 0:
+		return rule, nil, fmt.Errorf("%w: only FREQ, INTERVAL, COUNT, UNTIL and BYDAY are supported", errInvalidRecurrence)
+	case rule.Count == 0 && rule.Until.IsZero():
+		return rule, nil, fmt.Errorf("%w: the series needs a COUNT or an UNTIL", errInvalidRecurrence)
+	case !rule.Until.IsZero() && rule.Until.After(start.Add(maxSeriesSpan)):
+		return rule, nil, fmt.Errorf("%w: the series can't last longer than two years", errInvalidRecurrence)
+	}
+
+	starts := rule.Occurrences(start.In(location), start, start.Add(maxSeriesSpan))
+	if len(starts) > maxSeriesOccurrences || (rule.Count > 0 && len(starts) < rule.Count) {
+		return rule, nil, fmt.Errorf("%w: a series can't have more than %d occurrences or last longer than two years", errInvalidRecurrence, maxSeriesOccurrences)
+	}
+	return rule, starts, nil
+}
+
+// seriesScope reads the ?scope= query parameter of an edit or a cancellation, which defaults to this occurrence only.
+func seriesScope(c *fiber.Ctx) (string, error) {
+	scope := c.Query("scope", scopeThis)
+	if scope != scopeThis && scope != scopeFollowing && scope != scopeAll {
+		return scope, errors.New("the scope must be this, following or all")
+	}
+	return scope, nil
+}
+
+// findSeriesOccurrences returns the scheduled occurrences of the series of an appointment the scope applies to,
+// in order, starting from the appointment itself, which has to be scheduled too. An appointment that is not part of
+// a series is its own only occurrence.
+func findSeriesOccurrences(ctx context.Context, appointment models.Appointment, scope string) ([]models.Appointment, error) {
+	if !appointment.IsScheduled() {
+		return nil, errNotScheduled
+	}
+	if scope == scopeThis || appointment.SeriesId == "" {
+		return []models.Appointment{appointment}, nil
+	}
+
+	filter := bson.M{"seriesid": appointment.SeriesId, "status": bson.M{"$in": models.ScheduledStatuses}}
+	if scope == scopeFollowing {
+		filter["occurrenceindex"] = bson.M{"$gte": appointment.OccurrenceIndex}
+	}
+
+	var occurrences []models.Appointment
+	results, err := appointmentCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"occurrenceindex": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = results.All(ctx, &occurrences)
+	return occurrences, err
+}
+
+// findConflicts returns the scheduled appointments and blocked time of a partner overlapping [start, end), leaving
+// out the given appointments, which are the ones being moved.
+func findConflicts(ctx context.Context, partnerId string, start, end time.Time, exclude ...models.Appointment) ([]busyPeriod, error) {
+	busy, err := partnerBusyPeriods(ctx, partnerId, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []busyPeriod
+	for _, period := range busy {
+		excluded := false
+		for _, appointment := range exclude {
+			if period.AppointmentId == appointment.Id.Hex() {
+				excluded = true
+			}
+		}
+		if !excluded {
+			conflicts = append(conflicts, period)
+		}
+	}
+	return conflicts, nil
+}
+
+// saveAppointmentEdit stores an edited occurrence with its new price and records its event. It runs inside the
+// transaction of the edit.
+func saveAppointmentEdit(sc mongo.SessionContext, edit appointmentEdit, service models.Service) (models.Appointment, error) {
+	edited := edit.edited
+	update := bson.M{"petid": edited.PetId, "partnerid": edited.PartnerId, "serviceid": edited.ServiceId, "service": service.Name, "couponcode": strings.ToUpper(edited.CouponCode), "pricing": edit.breakdown, "paymenttype": edited.PaymentType, "starttime": edited.StartTime, "endtime": edited.StartTime.Add(service.Duration()), "updatedate": time.Now()}
+
+	//calendar apps only pick up the change when the sequence grows, and an occurrence completed or cancelled since
+	//it was read is left as it is
+	filter := bson.M{"id": edited.Id, "status": bson.M{"$in": models.ScheduledStatuses}}
+	result, err := appointmentCollection.UpdateOne(sc, filter, bson.M{"$set": update, "$inc": bson.M{"sequence": 1}})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if result.MatchedCount == 0 {
+		return models.Appointment{}, errNotScheduled
+	}
+
+	//the price may have changed, so the payment summary has to be recomputed
+	if err := refreshAppointmentPayments(sc, edited.Id.Hex()); err != nil {
+		return models.Appointment{}, err
+	}
+
+	updated, err := findAppointment(sc, edited.Id.Hex())
+	if err != nil {
+		return updated, err
+	}
+
+	if edit.previous.StartTime.Equal(updated.StartTime) {
+		return updated, recordEvent(sc, models.EventAppointmentUpdated, updated)
+	}
+	return updated, recordEvent(sc, models.EventAppointmentRescheduled, updated)
+}
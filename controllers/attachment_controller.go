@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"pet-appointments-api/attachments"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"pet-appointments-api/storage"
+	"strconv"
+	"time"
+)
+
+var attachmentCollection *mongo.Collection = configs.GetCollection(configs.DB, "attachments")
+
+// attachmentMaxSize is the largest file that can be uploaded, in bytes.
+var attachmentMaxSize = configs.EnvAttachmentMaxSize()
+
+// attachmentURLTTL is how long download URLs work for.
+var attachmentURLTTL = configs.EnvAttachmentURLTTL()
+
+// attachmentURLSecret signs the download URLs.
+var attachmentURLSecret = attachmentSecret()
+
+// attachmentView is an Attachment with the URLs it can be downloaded from for a while.
+type attachmentView struct {
+	models.Attachment
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl,omitempty"`
+	URLExpiresAt time.Time `json:"urlExpiresAt"`
+}
+
+// Upload a file for a Pet, as its owner or a Partner who treated it
+func UploadPetAttachment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	petId := c.Params("petId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	attachment := models.Attachment{PetId: petId}
+	if ownerId := c.Get(headerOwnerId); ownerId != "" && ownerId == pet.OwnerId {
+		attachment.OwnerId = ownerId
+	} else {
+		partnerId, allowed, err := medicalRecordAuthor(ctx, c, pet)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+		if !allowed {
+			return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can upload files for it.", Data: &fiber.Map{"data": petId}})
+		}
+		attachment.PartnerId = partnerId
+	}
+
+	return uploadAttachment(ctx, c, attachment)
+}
+
+// Upload a file for an Appointment, like lab results or an X-ray, as the Partner of the appointment
+func UploadAppointmentAttachment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if c.Get(headerPartnerId) != appointment.PartnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partner of the appointment can upload files for it.", Data: &fiber.Map{"data": appointmentId}})
+	}
+
+	return uploadAttachment(ctx, c, models.Attachment{PetId: appointment.PetId, AppointmentId: appointmentId, PartnerId: appointment.PartnerId})
+}
+
+// Get the files of a Pet, including the ones of its appointments, with URLs to download them
+func GetPetAttachments(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadMedicalRecord(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can see its files.", Data: &fiber.Map{"data": petId}})
+	}
+
+	views, err := findAttachments(ctx, bson.M{"petid": petId})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": views}},
+	)
+}
+
+// Get the files of an Appointment, with URLs to download them
+func GetAppointmentAttachments(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadAttachment(ctx, c, models.Attachment{PetId: appointment.PetId, PartnerId: appointment.PartnerId})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and its partners can see the files of the appointment.", Data: &fiber.Map{"data": appointmentId}})
+	}
+
+	views, err := findAttachments(ctx, bson.M{"appointmentid": appointmentId})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": views}},
+	)
+}
+
+// Get an Attachment with fresh URLs to download it
+func GetAttachment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	attachmentId := c.Params("attachmentId")
+	defer cancel()
+
+	attachment, err := findAttachment(ctx, attachmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid attachment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadAttachment(ctx, c, attachment)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and its partners can see its files.", Data: &fiber.Map{"data": attachmentId}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": viewAttachment(attachment, time.Now())}})
+}
+
+// Download an Attachment, or its thumbnail with ?variant=thumbnail, from a signed URL
+func DownloadAttachment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	attachmentId := c.Params("attachmentId")
+	variant := c.Query("variant", attachments.VariantOriginal)
+	defer cancel()
+
+	//the URL is the authorization, it was only handed to someone allowed to see the file
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || !attachments.Verify(attachmentURLSecret, c.Query("signature"), attachmentId, variant, expires, time.Now()) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: the download URL is invalid or expired.", Data: &fiber.Map{"data": attachmentId}})
+	}
+
+	attachment, err := findAttachment(ctx, attachmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid attachment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	key, contentType, fileName := attachment.BlobKey, attachment.ContentType, attachment.FileName
+	if variant == attachments.VariantThumbnail {
+		if attachment.ThumbnailKey == "" {
+			return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: the attachment has no thumbnail.", Data: &fiber.Map{"data": attachmentId}})
+		}
+		key, contentType, fileName = attachment.ThumbnailKey, "image/jpeg", "thumbnail.jpg"
+	}
+
+	blob, err := storage.Default().Get(ctx, key)
+	if err == storage.ErrNotFound {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: the file is missing from the store.", Data: &fiber.Map{"data": attachmentId}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the file could not be read.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//pictures are shown in place, everything else is saved
+	disposition := "attachment"
+	if attachments.IsImage(contentType) || contentType == "image/webp" || contentType == "application/pdf" {
+		disposition = "inline"
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, "private, max-age="+strconv.FormatInt(expires-time.Now().Unix(), 10))
+	return c.SendStream(blob)
+}
+
+// Delete an Attachment, as whoever uploaded it
+func DeleteAttachment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	attachmentId := c.Params("attachmentId")
+	defer cancel()
+
+	attachment, err := findAttachment(ctx, attachmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid attachment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	ownerId, partnerId := c.Get(headerOwnerId), c.Get(headerPartnerId)
+	if !(attachment.OwnerId != "" && ownerId == attachment.OwnerId) && !(attachment.PartnerId != "" && partnerId == attachment.PartnerId) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only whoever uploaded a file can delete it.", Data: &fiber.Map{"data": attachmentId}})
+	}
+
+	if _, err := attachmentCollection.DeleteOne(ctx, bson.M{"id": attachment.Id}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the attachment could not be deleted.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the files are removed once nothing points to them, a failure only leaves them behind
+	for _, key := range []string{attachment.BlobKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := storage.Default().Delete(ctx, key); err != nil {
+			log.Println("Error: the file " + key + " could not be deleted, " + err.Error())
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The attachment was deleted successfully."}},
+	)
+}
+
+// uploadAttachment stores the file of a multipart upload and saves the attachment describing it.
+func uploadAttachment(ctx context.Context, c *fiber.Ctx, attachment models.Attachment) error {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the upload needs a multipart \"file\" field.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if header.Size > int64(attachmentMaxSize) {
+		return c.Status(http.StatusRequestEntityTooLarge).JSON(responses.Response{Status: http.StatusRequestEntityTooLarge, Message: "Error: the file is larger than " + strconv.Itoa(attachmentMaxSize) + " bytes.", Data: &fiber.Map{"data": header.Size}})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the file could not be read.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(attachmentMaxSize)+1))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the file could not be read.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if len(data) > attachmentMaxSize {
+		return c.Status(http.StatusRequestEntityTooLarge).JSON(responses.Response{Status: http.StatusRequestEntityTooLarge, Message: "Error: the file is larger than " + strconv.Itoa(attachmentMaxSize) + " bytes.", Data: &fiber.Map{"data": len(data)}})
+	}
+
+	//the content decides the type, not the name or the header the client sent
+	contentType, allowed := attachments.Sniff(data)
+	if !allowed {
+		return c.Status(http.StatusUnsupportedMediaType).JSON(responses.Response{Status: http.StatusUnsupportedMediaType, Message: "Error: only JPEG, PNG, GIF and WebP pictures, PDF documents and DICOM images can be uploaded.", Data: &fiber.Map{"data": contentType}})
+	}
+
+	attachment.Id = primitive.NewObjectID()
+	attachment.FileName = filepath.Base(header.Filename)
+	attachment.Description = c.FormValue("description")
+	attachment.ContentType = contentType
+	attachment.Size = int64(len(data))
+	attachment.BlobKey = attachment.PetId + "/" + attachment.Id.Hex()
+	attachment.CreationDate = time.Now()
+
+	store := storage.Default()
+	if err := store.Put(ctx, attachment.BlobKey, data, contentType); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the file could not be stored.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//an upload is not failed for its thumbnail, the file is still there to download
+	if attachments.IsImage(contentType) {
+		if thumbnail, err := attachments.Thumbnail(data); err != nil {
+			log.Println("Error: no thumbnail for the attachment " + attachment.Id.Hex() + ", " + err.Error())
+		} else if err := store.Put(ctx, attachment.BlobKey+".thumbnail.jpg", thumbnail, "image/jpeg"); err != nil {
+			log.Println("Error: the thumbnail of the attachment " + attachment.Id.Hex() + " could not be stored, " + err.Error())
+		} else {
+			attachment.ThumbnailKey = attachment.BlobKey + ".thumbnail.jpg"
+		}
+	}
+
+	if _, err := attachmentCollection.InsertOne(ctx, attachment); err != nil {
+		for _, key := range []string{attachment.BlobKey, attachment.ThumbnailKey} {
+			if key != "" {
+				store.Delete(ctx, key)
+			}
+		}
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Attachment creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The file was uploaded successfully.", Data: &fiber.Map{"data": viewAttachment(attachment, time.Now())}})
+}
+
+// canReadAttachment reports whether the caller can see a file: the owner of the pet, the partners who treated it
+// and the partner it was uploaded by or for.
+func canReadAttachment(ctx context.Context, c *fiber.Ctx, attachment models.Attachment) (bool, error) {
+	if partnerId := c.Get(headerPartnerId); partnerId != "" && partnerId == attachment.PartnerId {
+		return true, nil
+	}
+
+	pet, err := findPet(ctx, attachment.PetId)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return canReadMedicalRecord(ctx, c, pet)
+}
+
+// findAttachment loads an Attachment by its ID.
+func findAttachment(ctx context.Context, attachmentId string) (models.Attachment, error) {
+	var attachment models.Attachment
+
+	objId, err := primitive.ObjectIDFromHex(attachmentId)
+	if err != nil {
+		return attachment, err
+	}
+
+	err = attachmentCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&attachment)
+	return attachment, err
+}
+
+// findAttachments loads the attachments matching a filter, the newest first, with their download URLs.
+func findAttachments(ctx context.Context, filter bson.M) ([]attachmentView, error) {
+	results, err := attachmentCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "creationdate", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.Attachment
+	if err := results.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	views := []attachmentView{}
+	for _, attachment := range found {
+		views = append(views, viewAttachment(attachment, now))
+	}
+	return views, nil
+}
+
+func viewAttachment(attachment models.Attachment, now time.Time) attachmentView {
+	expires := now.Add(attachmentURLTTL)
+	view := attachmentView{Attachment: attachment, URLExpiresAt: expires}
+	view.URL = downloadURL(attachment, attachments.VariantOriginal, expires)
+	if attachment.ThumbnailKey != "" {
+		view.ThumbnailURL = downloadURL(attachment, attachments.VariantThumbnail, expires)
+	}
+	return view
+}
+
+// downloadURL is the path a variant of an attachment can be downloaded from until it expires.
+func downloadURL(attachment models.Attachment, variant string, expires time.Time) string {
+	query := url.Values{}
+	query.Set("variant", variant)
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", attachments.Sign(attachmentURLSecret, attachment.Id.Hex(), variant, expires.Unix()))
+	return "/attachment/" + attachment.Id.Hex() + "/download?" + query.Encode()
+}
+
+func attachmentSecret() string {
+	if secret := configs.EnvAttachmentURLSecret(); secret != "" {
+		return secret
+	}
+	return attachments.NewSecret()
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"pet-appointments-api/calendar"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+// calendarFeedHistory is how far back in time the feeds go, so calendar apps keep showing recent appointments.
+const calendarFeedHistory = 90 * 24 * time.Hour
+
+// deletedPartner and deletedPet stand in for the partner or the pet of an appointment once they were deleted.
+var (
+	deletedPartner = models.Partner{Name: "Deleted", LastName: "partner"}
+	deletedPet     = models.Pet{Name: "a deleted pet", PetType: "unknown"}
+)
+
+// Get the calendar feed of a Partner, to subscribe to it from a calendar app
+func GetPartnerCalendar(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	partnerId := c.Params("partnerId")
+	defer cancel()
+
+	partner, err := findPartner(ctx, partnerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if !validCalendarToken(partner.CalendarToken, c.Query("token")) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: invalid calendar token.", Data: &fiber.Map{"data": "Error: the token is missing, wrong or was rotated."}})
+	}
+
+	feed, err := calendarFeed(ctx, bson.M{"partnerid": partnerId}, "Appointments of "+partner.Name+" "+partner.LastName)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the calendar generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return sendCalendar(c, feed, "partner-"+partnerId+".ics")
+}
+
+// Get the calendar feed of an Owner, to subscribe to it from a calendar app
+func GetOwnerCalendar(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ownerId := c.Params("ownerId")
+	defer cancel()
+
+	owner, err := findOwner(ctx, ownerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid owner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if !validCalendarToken(owner.CalendarToken, c.Query("token")) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: invalid calendar token.", Data: &fiber.Map{"data": "Error: the token is missing, wrong or was rotated."}})
+	}
+
+	feed, err := calendarFeed(ctx, bson.M{"ownerid": ownerId}, "Appointments of "+owner.Name+" "+owner.LastName)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the calendar generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return sendCalendar(c, feed, "owner-"+ownerId+".ics")
+}
+
+// Download an Appointment as an .ics file
+func GetAppointmentCalendar(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//only the owner and the partner of the appointment can download it
+	if !appointmentParty(c, appointment) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner and the partner of the appointment can download it.", Data: &fiber.Map{"data": appointmentId}})
+	}
+
+	events, err := appointmentEvents(ctx, []models.Appointment{appointment})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the calendar generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return sendCalendar(c, calendar.Calendar{Events: events}, "appointment-"+appointmentId+".ics")
+}
+
+// Rotate the calendar token of a Partner, invalidating the previous feed URL
+func RotatePartnerCalendarToken(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	partnerId := c.Params("partnerId")
+	defer cancel()
+
+	//the new token gives access to the feed, so only the partner gets it
+	if c.Get(headerPartnerId) != partnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: partners can only rotate their own calendar token.", Data: &fiber.Map{"data": partnerId}})
+	}
+
+	partner, err := findPartner(ctx, partnerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	token := calendar.NewToken()
+	if _, err := partnerCollection.UpdateOne(ctx, bson.M{"id": partner.Id}, bson.M{"$set": bson.M{"calendartoken": token}}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the calendar token rotation failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The calendar token was rotated, the previous feed URL no longer works.", Data: &fiber.Map{"data": fiber.Map{"token": token, "url": c.BaseURL() + "/partner/" + partnerId + "/calendar.ics?token=" + token}}})
+}
+
+// Rotate the calendar token of an Owner, invalidating the previous feed URL
+func RotateOwnerCalendarToken(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ownerId := c.Params("ownerId")
+	defer cancel()
+
+	//the new token gives access to the feed, so only the owner gets it
+	if c.Get(headerOwnerId) != ownerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: owners can only rotate their own calendar token.", Data: &fiber.Map{"data": ownerId}})
+	}
+
+	owner, err := findOwner(ctx, ownerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid owner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	token := calendar.NewToken()
+	if _, err := ownerCollection.UpdateOne(ctx, bson.M{"id": owner.Id}, bson.M{"$set": bson.M{"calendartoken": token}}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the calendar token rotation failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The calendar token was rotated, the previous feed URL no longer works.", Data: &fiber.Map{"data": fiber.Map{"token": token, "url": c.BaseURL() + "/owner/" + ownerId + "/calendar.ics?token=" + token}}})
+}
+
+// validCalendarToken compares the tokens in constant time. A feed without a token was never enabled.
+func validCalendarToken(expected, given string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(given)) == 1
+}
+
+// calendarFeed builds a feed with the recent and upcoming appointments matching the filter. Cancelled appointments
+// are kept, so calendar apps remove them instead of showing them forever.
+func calendarFeed(ctx context.Context, filter bson.M, name string) (calendar.Calendar, error) {
+	filter["starttime"] = bson.M{"$gte": time.Now().Add(-calendarFeedHistory)}
+
+	var appointments []models.Appointment
+	results, err := appointmentCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"starttime": 1}))
+	if err != nil {
+		return calendar.Calendar{}, err
+	}
+	if err := results.All(ctx, &appointments); err != nil {
+		return calendar.Calendar{}, err
+	}
+
+	events, err := appointmentEvents(ctx, appointments)
+	if err != nil {
+		return calendar.Calendar{}, err
+	}
+
+	return calendar.Calendar{Name: name, RefreshInterval: time.Hour, Events: events}, nil
+}
+
+// appointmentEvents turns appointments into calendar events in the time zone of their partner.
+func appointmentEvents(ctx context.Context, appointments []models.Appointment) ([]calendar.Event, error) {
+	partners := map[string]models.Partner{}
+	pets := map[string]models.Pet{}
+
+	var events []calendar.Event
+	for _, appointment := range appointments {
+		//pets and partners deleted since the booking keep their appointments, which are shown with placeholder names
+		partner, ok := partners[appointment.PartnerId]
+		if !ok {
+			var err error
+			partner, err = findPartner(ctx, appointment.PartnerId)
+			if err == mongo.ErrNoDocuments {
+				partner = deletedPartner
+			} else if err != nil {
+				return nil, err
+			}
+			partners[appointment.PartnerId] = partner
+		}
+
+		pet, ok := pets[appointment.PetId]
+		if !ok {
+			var err error
+			pet, err = findPet(ctx, appointment.PetId)
+			if err == mongo.ErrNoDocuments {
+				pet = deletedPet
+			} else if err != nil {
+				return nil, err
+			}
+			pets[appointment.PetId] = pet
+		}
+
+		status := calendar.StatusConfirmed
+		if appointment.Status == models.AppointmentCancelled || appointment.Status == models.AppointmentNoShow {
+			status = calendar.StatusCancelled
+		}
+
+		events = append(events, calendar.Event{
+			UID:          appointment.Id.Hex() + "@pet-appointments-api",
+			Sequence:     appointment.Sequence,
+			Status:       status,
+			Summary:      appointment.Service + " for " + pet.Name,
+			Description:  "Pet: " + pet.Name + " (" + pet.PetType + ")\nPartner: " + partner.Name + " " + partner.LastName + "\nStatus: " + string(appointment.Status),
+			Start:        appointment.StartTime,
+			End:          appointment.EndTime,
+			TimeZone:     partner.Location(),
+			Created:      appointment.Date,
+			LastModified: appointment.UpdateDate,
+		})
+	}
+	return events, nil
+}
+
+func sendCalendar(c *fiber.Ctx, cal calendar.Calendar, filename string) error {
+	var body bytes.Buffer
+	if err := calendar.Write(&body, cal, time.Now()); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the calendar generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	c.Set(fiber.HeaderContentType, calendar.MIMEType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Status(http.StatusOK).Send(body.Bytes())
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"net/http"
+	"pet-appointments-api/calendar"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"pet-appointments-api/safehttp"
+	"sort"
+	"time"
+)
+
+var blockedSlotCollection *mongo.Collection = configs.GetCollection(configs.DB, "blockedSlots")
+var calendarSourceCollection *mongo.Collection = configs.GetCollection(configs.DB, "calendarSources")
+var validateCalendarSource = validator.New()
+
+// calendarImportHorizon is how far ahead recurring events are expanded into blocked slots.
+const calendarImportHorizon = 365 * 24 * time.Hour
+
+// importResult tells what an import changed.
+type importResult struct {
+	Events  int `json:"events"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+}
+
+// Import an iCalendar file sent in the request body as blocked time of a Partner
+func ImportPartnerCalendar(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	partnerId := c.Params("partnerId")
+	defer cancel()
+
+	partner, err := findPartner(ctx, partnerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//uploading the same calendar again replaces what the previous upload blocked
+	source := "upload:" + c.Query("source", "default")
+
+	result, err := importCalendar(ctx, partner, source, bytes.NewReader(c.Body()), time.Now())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the calendar could not be imported.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The calendar was imported successfully.", Data: &fiber.Map{"data": result}})
+}
+
+// Create a new CalendarSource for a Partner and import it right away
+func CreateCalendarSource(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	partnerId := c.Params("partnerId")
+	var source models.CalendarSource
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&source); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateCalendarSource.Struct(&source); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	partner, err := findPartner(ctx, partnerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the platform must not be made to fetch from itself or the services of its private network
+	if err := safehttp.CheckURL(ctx, calendar.FeedURL(source.URL)); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the calendar URL can't be fetched.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	newSource := models.CalendarSource{
+		Id:           primitive.NewObjectID(),
+		PartnerId:    partnerId,
+		Name:         source.Name,
+		URL:          source.URL,
+		Disabled:     source.Disabled,
+		NextSync:     time.Now(),
+		CreationDate: time.Now(),
+	}
+
+	if _, err := calendarSourceCollection.InsertOne(ctx, newSource); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The CalendarSource creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//a failed first import is kept on the source and retried later, the source itself was created
+	result, err := syncCalendarSource(ctx, partner, newSource)
+	if err != nil {
+		return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new CalendarSource was created, but importing it failed.", Data: &fiber.Map{"data": newSource, "error": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new CalendarSource was created successfully.", Data: &fiber.Map{"data": newSource, "import": result}})
+}
+
+// Get the CalendarSources of a Partner
+func GetPartnerCalendarSources(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	partnerId := c.Params("partnerId")
+	var sources []models.CalendarSource
+	defer cancel()
+
+	results, err := calendarSourceCollection.Find(ctx, bson.M{"partnerid": partnerId})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if err := results.All(ctx, &sources); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": sources}},
+	)
+}
+
+// Import a CalendarSource now instead of waiting for its next sync
+func SyncCalendarSource(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	sourceId := c.Params("sourceId")
+	defer cancel()
+
+	source, err := findCalendarSource(ctx, sourceId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid calendar source ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partner, err := findPartner(ctx, source.PartnerId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	result, err := syncCalendarSource(ctx, partner, source)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(responses.Response{Status: http.StatusBadGateway, Message: "Error: the calendar could not be imported.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The calendar was imported successfully.", Data: &fiber.Map{"data": result}})
+}
+
+// Delete a CalendarSource together with the time it blocked
+func DeleteCalendarSource(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	sourceId := c.Params("sourceId")
+	defer cancel()
+
+	source, err := findCalendarSource(ctx, sourceId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The CalendarSource with the ID " + sourceId + " does not exists."}},
+		)
+	}
+
+	if _, err := calendarSourceCollection.DeleteOne(ctx, bson.M{"id": source.Id}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no calendar source with that ID. ", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if _, err := blockedSlotCollection.DeleteMany(ctx, bson.M{"partnerid": source.PartnerId, "source": calendarSourceKey(source)}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the blocked time of the calendar source could not be removed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The CalendarSource was deleted successfully."}},
+	)
+}
+
+// Get the BlockedSlots of a Partner, optionally between ?from= and ?to= (RFC 3339)
+func GetPartnerBlockedSlots(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	partnerId := c.Params("partnerId")
+	defer cancel()
+
+	from, to, err := periodQuery(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid period, from and to must be RFC 3339 times.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	slots, err := findBlockedSlots(ctx, partnerId, from, to)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": slots}},
+	)
+}
+
+// busyPeriod is time a Partner can't take a booking.
+type busyPeriod struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	Kind            string    `json:"kind"`
+	AppointmentId   string    `json:"appointmentId,omitempty"`
+	WaitlistEntryId string    `json:"waitlistEntryId,omitempty"`
+	HoldId          string    `json:"holdId,omitempty"`
+	Summary         string    `json:"summary,omitempty"`
+}
+
+// Get when a Partner is busy between ?from= and ?to= (RFC 3339), from their appointments and blocked time
+func GetPartnerAvailability(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	partnerId := c.Params("partnerId")
+	defer cancel()
+
+	from, to, err := periodQuery(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid period, from and to must be RFC 3339 times.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	busy, err := partnerBusyPeriods(ctx, partnerId, from, to)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": fiber.Map{"from": from, "to": to, "busy": busy}}},
+	)
+}
+
+// partnerBusyPeriods returns the scheduled appointments, blocked time, slots held for the waitlist and slot holds of
+// a partner overlapping [from, to).
+func partnerBusyPeriods(ctx context.Context, partnerId string, from, to time.Time) ([]busyPeriod, error) {
+	var appointments []models.Appointment
+	filter := bson.M{"partnerid": partnerId, "status": bson.M{"$in": models.ScheduledStatuses}, "starttime": bson.M{"$lt": to}, "endtime": bson.M{"$gt": from}}
+	results, err := appointmentCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &appointments); err != nil {
+		return nil, err
+	}
+
+	slots, err := findBlockedSlots(ctx, partnerId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	//a slot offered to the waitlist is held until the offer is answered or expires
+	var offers []models.WaitlistEntry
+	filter = bson.M{"partnerid": partnerId, "status": models.WaitlistOffered, "offer.expiresat": bson.M{"$gt": time.Now()}, "offer.start": bson.M{"$lt": to}, "offer.end": bson.M{"$gt": from}}
+	results, err = waitlistCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &offers); err != nil {
+		return nil, err
+	}
+
+	//so are the slots held while someone fills in a booking
+	var holds []models.SlotHold
+	filter = bson.M{"partnerid": partnerId, "expiresat": bson.M{"$gt": time.Now()}, "starttime": bson.M{"$lt": to}, "endtime": bson.M{"$gt": from}}
+	results, err = slotHoldCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &holds); err != nil {
+		return nil, err
+	}
+
+	busy := []busyPeriod{}
+	for _, appointment := range appointments {
+		busy = append(busy, busyPeriod{Start: appointment.StartTime, End: appointment.EndTime, Kind: "appointment", AppointmentId: appointment.Id.Hex()})
+	}
+	for _, slot := range slots {
+		busy = append(busy, busyPeriod{Start: slot.Start, End: slot.End, Kind: "blocked", Summary: slot.Summary})
+	}
+	for _, entry := range offers {
+		busy = append(busy, busyPeriod{Start: entry.Offer.Start, End: entry.Offer.End, Kind: "waitlist_offer", WaitlistEntryId: entry.Id.Hex()})
+	}
+	for _, hold := range holds {
+		busy = append(busy, busyPeriod{Start: hold.StartTime, End: hold.EndTime, Kind: "hold", HoldId: hold.Id.Hex()})
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+	return busy, nil
+}
+
+// findBlockedSlots returns the blocked slots of a partner overlapping [from, to).
+func findBlockedSlots(ctx context.Context, partnerId string, from, to time.Time) ([]models.BlockedSlot, error) {
+	slots := []models.BlockedSlot{}
+
+	filter := bson.M{"partnerid": partnerId, "start": bson.M{"$lt": to}, "end": bson.M{"$gt": from}}
+	results, err := blockedSlotCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	err = results.All(ctx, &slots)
+	return slots, err
+}
+
+// periodQuery reads the ?from= and ?to= query parameters, defaulting to the next week.
+func periodQuery(c *fiber.Ctx) (time.Time, time.Time, error) {
+	from, to := time.Now(), time.Now().AddDate(0, 0, 7)
+
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, err
+		}
+		if c.Query("to") == "" {
+			to = from.AddDate(0, 0, 7)
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, err
+		}
+	}
+	return from, to, nil
+}
+
+// syncCalendarSource fetches and imports a calendar source, recording the outcome on it.
+func syncCalendarSource(ctx context.Context, partner models.Partner, source models.CalendarSource) (importResult, error) {
+	document, err := calendar.Fetch(ctx, calendar.Client, source.URL)
+
+	var result importResult
+	if err == nil {
+		result, err = importCalendar(ctx, partner, calendarSourceKey(source), bytes.NewReader(document), time.Now())
+	}
+
+	if err != nil {
+		CalendarSourceStore{}.SourceFailed(ctx, source, err, time.Now())
+		return result, err
+	}
+	return result, calendarSourceSynced(ctx, source, time.Now())
+}
+
+// importCalendar replaces the blocked slots of a calendar with the busy occurrences of its events from now until
+// the import horizon. Occurrences already imported are updated in place and the ones that disappeared are removed,
+// so importing the same calendar twice changes nothing.
+func importCalendar(ctx context.Context, partner models.Partner, source string, document io.Reader, now time.Time) (importResult, error) {
+	var result importResult
+
+	events, err := calendar.Parse(document, partner.Location())
+	if err != nil {
+		return result, err
+	}
+
+	//Mongo keeps milliseconds, the import date has to match exactly to find the stale slots afterwards
+	now = now.Truncate(time.Millisecond)
+	partnerId := partner.Id.Hex()
+
+	var writes []mongo.WriteModel
+	for _, occurrence := range calendar.Expand(events, now, now.Add(calendarImportHorizon)) {
+		if occurrence.Transparent {
+			continue
+		}
+		result.Events++
+
+		filter := bson.M{"partnerid": partnerId, "source": source, "uid": occurrence.UID, "occurrencestart": occurrence.RecurrenceId}
+		update := bson.M{
+			"$set":         bson.M{"start": occurrence.Start, "end": occurrence.End, "allday": occurrence.AllDay, "summary": occurrence.Summary, "importdate": now},
+			"$setOnInsert": bson.M{"id": primitive.NewObjectID(), "creationdate": now},
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	if len(writes) > 0 {
+		written, err := blockedSlotCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return result, err
+		}
+		result.Created = int(written.UpsertedCount)
+		result.Updated = int(written.ModifiedCount)
+	}
+
+	removed, err := blockedSlotCollection.DeleteMany(ctx, bson.M{"partnerid": partnerId, "source": source, "importdate": bson.M{"$ne": now}})
+	if err != nil {
+		return result, err
+	}
+	result.Removed = int(removed.DeletedCount)
+
+	return result, nil
+}
+
+// calendarSourceKey is the source of the blocked slots imported from a calendar source.
+func calendarSourceKey(source models.CalendarSource) string {
+	return "url:" + source.Id.Hex()
+}
+
+func calendarSourceSynced(ctx context.Context, source models.CalendarSource, now time.Time) error {
+	update := bson.M{"lastsync": now, "lasterror": "", "nextsync": now.Add(calendar.SyncInterval)}
+	_, err := calendarSourceCollection.UpdateOne(ctx, bson.M{"id": source.Id}, bson.M{"$set": update})
+	return err
+}
+
+// findCalendarSource loads a CalendarSource by its ID.
+func findCalendarSource(ctx context.Context, sourceId string) (models.CalendarSource, error) {
+	var source models.CalendarSource
+
+	objId, err := primitive.ObjectIDFromHex(sourceId)
+	if err != nil {
+		return source, err
+	}
+
+	err = calendarSourceCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&source)
+	return source, err
+}
+
+// CalendarSourceStore is the MongoDB backed calendar.SourceStore.
+type CalendarSourceStore struct{}
+
+func (CalendarSourceStore) DueSources(ctx context.Context, now time.Time, lease time.Duration) ([]models.CalendarSource, error) {
+	filter := bson.M{"disabled": false, "nextsync": bson.M{"$lte": now}}
+
+	var sources []models.CalendarSource
+	for {
+		var source models.CalendarSource
+		err := calendarSourceCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"nextsync": now.Add(lease)}}).Decode(&source)
+		if err == mongo.ErrNoDocuments {
+			return sources, nil
+		}
+		if err != nil {
+			return sources, err
+		}
+		sources = append(sources, source)
+	}
+}
+
+func (CalendarSourceStore) ImportSource(ctx context.Context, source models.CalendarSource, document io.Reader, now time.Time) error {
+	partner, err := findPartner(ctx, source.PartnerId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := importCalendar(ctx, partner, calendarSourceKey(source), document, now); err != nil {
+		return err
+	}
+	return calendarSourceSynced(ctx, source, now)
+}
+
+func (CalendarSourceStore) SourceFailed(ctx context.Context, source models.CalendarSource, err error, now time.Time) error {
+	update := bson.M{"lasterror": err.Error(), "nextsync": now.Add(calendar.SyncInterval)}
+	_, updateErr := calendarSourceCollection.UpdateOne(ctx, bson.M{"id": source.Id}, bson.M{"$set": update})
+	return updateErr
+}
@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"pet-appointments-api/catalog"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"strings"
+	"time"
+)
+
+var speciesCollection *mongo.Collection = configs.GetCollection(configs.DB, "species")
+var breedCollection *mongo.Collection = configs.GetCollection(configs.DB, "breeds")
+var validateCatalog = validator.New()
+
+// catalogSuggestions is how many entries autocomplete answers with.
+const catalogSuggestions = 10
+
+// Add a Species to the catalog
+func CreateSpecies(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var species models.Species
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&species); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateCatalog.Struct(&species); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	newSpecies := models.Species{
+		Id:           primitive.NewObjectID(),
+		Code:         catalog.Code(species.Code),
+		Names:        species.Names,
+		Aliases:      species.Aliases,
+		CreationDate: time.Now(),
+	}
+
+	//every name must point to a single species
+	if taken := speciesTaken(cat, newSpecies); len(taken) > 0 {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: some names already belong to other species.", Data: &fiber.Map{"data": taken}})
+	}
+
+	if _, err := speciesCollection.InsertOne(ctx, newSpecies); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Species creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Species was created successfully.", Data: &fiber.Map{"data": newSpecies}})
+}
+
+// Edit the names and aliases of a Species. Its code can't change, as pets are stored with it.
+func EditSpecies(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	speciesCode := c.Params("speciesCode")
+	var species models.Species
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&species); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	species.Code = speciesCode
+	if validationErr := validateCatalog.Struct(&species); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if taken := speciesTaken(cat, species); len(taken) > 0 {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: some names already belong to other species.", Data: &fiber.Map{"data": taken}})
+	}
+
+	var updatedSpecies models.Species
+	update := bson.M{"$set": bson.M{"names": species.Names, "aliases": species.Aliases, "updatedate": time.Now()}}
+	err = speciesCollection.FindOneAndUpdate(ctx, bson.M{"code": speciesCode}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updatedSpecies)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid species code.", Data: &fiber.Map{"data": speciesCode}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Species edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Species " + speciesCode + " was edited correctly.", Data: &fiber.Map{"data": updatedSpecies}})
+}
+
+// Get the Species of the catalog, or the ones matching what was typed in ?q= named in ?lang=
+func GetAllSpecies(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if query := c.Query("q"); query != "" {
+		return c.Status(http.StatusOK).JSON(
+			responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": cat.SuggestSpecies(query, catalogLanguage(c), catalogSuggestions)}},
+		)
+	}
+
+	species := cat.AllSpecies()
+	if species == nil {
+		species = []models.Species{}
+	}
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": species}},
+	)
+}
+
+// Add a Breed to a Species of the catalog
+func CreateBreed(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	speciesCode := c.Params("speciesCode")
+	var breed models.Breed
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&breed); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateCatalog.Struct(&breed); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if !hasSpecies(cat, speciesCode) {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid species code.", Data: &fiber.Map{"data": speciesCode}})
+	}
+
+	newBreed := models.Breed{
+		Id:           primitive.NewObjectID(),
+		SpeciesCode:  speciesCode,
+		Code:         catalog.Code(breed.Code),
+		Names:        breed.Names,
+		Aliases:      breed.Aliases,
+		CreationDate: time.Now(),
+	}
+
+	if taken := breedTaken(cat, newBreed); len(taken) > 0 {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: some names already belong to other breeds of the species.", Data: &fiber.Map{"data": taken}})
+	}
+
+	if _, err := breedCollection.InsertOne(ctx, newBreed); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Breed creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Breed was created successfully.", Data: &fiber.Map{"data": newBreed}})
+}
+
+// Edit the names and aliases of a Breed
+func EditBreed(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	speciesCode := c.Params("speciesCode")
+	breedCode := c.Params("breedCode")
+	var breed models.Breed
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&breed); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	breed.SpeciesCode, breed.Code = speciesCode, breedCode
+	if validationErr := validateCatalog.Struct(&breed); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if taken := breedTaken(cat, breed); len(taken) > 0 {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: some names already belong to other breeds of the species.", Data: &fiber.Map{"data": taken}})
+	}
+
+	var updatedBreed models.Breed
+	update := bson.M{"$set": bson.M{"names": breed.Names, "aliases": breed.Aliases, "updatedate": time.Now()}}
+	err = breedCollection.FindOneAndUpdate(ctx, bson.M{"speciescode": speciesCode, "code": breedCode}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updatedBreed)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid breed code.", Data: &fiber.Map{"data": speciesCode + "/" + breedCode}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Breed edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Breed " + breedCode + " was edited correctly.", Data: &fiber.Map{"data": updatedBreed}})
+}
+
+// Get the Breeds of a Species, or the ones matching what was typed in ?q= named in ?lang=
+func GetSpeciesBreeds(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	speciesCode := c.Params("speciesCode")
+	defer cancel()
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if !hasSpecies(cat, speciesCode) {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid species code.", Data: &fiber.Map{"data": speciesCode}})
+	}
+
+	if query := c.Query("q"); query != "" {
+		return c.Status(http.StatusOK).JSON(
+			responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": cat.SuggestBreeds(speciesCode, query, catalogLanguage(c), catalogSuggestions)}},
+		)
+	}
+
+	breeds := cat.Breeds(speciesCode)
+	if breeds == nil {
+		breeds = []models.Breed{}
+	}
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": breeds}},
+	)
+}
+
+// Look up the catalog codes of a ?species= and ?breed= written in any language or with an alias
+func LookupCatalog(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	language := catalogLanguage(c)
+	species, breed, suggestions := classify(cat, c.Query("species"), c.Query("breed"), language)
+	if suggestions != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: the catalog has no such species or breed, did you mean one of these?", Data: &fiber.Map{"data": suggestions}})
+	}
+
+	lookup := fiber.Map{"species": catalog.Suggestion{Code: species.Code, Name: species.Name(language)}}
+	if c.Query("breed") != "" {
+		lookup["breed"] = catalog.Suggestion{Code: breed.Code, Name: breed.Name(language), SpeciesCode: species.Code}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": lookup}})
+}
+
+// classifyPet replaces the species and breed a pet was written with by their catalog codes. When either is not in
+// the catalog, it returns what the caller may have meant instead.
+func classifyPet(ctx context.Context, c *fiber.Ctx, pet *models.Pet) (fiber.Map, error) {
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	species, breed, suggestions := classify(cat, pet.PetType, pet.Breed, catalogLanguage(c))
+	if suggestions != nil {
+		return suggestions, nil
+	}
+
+	pet.PetType, pet.Breed = species.Code, breed.Code
+	return nil, nil
+}
+
+// classify finds a species and one of its breeds in the catalog, or suggests what was meant. An empty breed is only
+// looked up when given.
+func classify(cat *catalog.Catalog, speciesName string, breedName string, language string) (models.Species, models.Breed, fiber.Map) {
+	species, ok := cat.Species(speciesName)
+	if !ok {
+		return species, models.Breed{}, fiber.Map{"petType": cat.SuggestSpecies(speciesName, language, catalogSuggestions)}
+	}
+	if breedName == "" {
+		return species, models.Breed{}, nil
+	}
+
+	breed, ok := cat.Breed(species.Code, breedName)
+	if !ok {
+		return species, breed, fiber.Map{"breed": cat.SuggestBreeds(species.Code, breedName, language, catalogSuggestions)}
+	}
+	return species, breed, nil
+}
+
+// speciesTaken returns the names of a species that already name another one.
+func speciesTaken(cat *catalog.Catalog, species models.Species) []string {
+	var taken []string
+	for _, name := range catalogTerms(species.Code, species.Names, species.Aliases) {
+		if other, ok := cat.Species(name); ok && other.Code != species.Code {
+			taken = append(taken, name)
+		}
+	}
+	return taken
+}
+
+// breedTaken returns the names of a breed that already name another breed of its species.
+func breedTaken(cat *catalog.Catalog, breed models.Breed) []string {
+	var taken []string
+	for _, name := range catalogTerms(breed.Code, breed.Names, breed.Aliases) {
+		if other, ok := cat.Breed(breed.SpeciesCode, name); ok && other.Code != breed.Code {
+			taken = append(taken, name)
+		}
+	}
+	return taken
+}
+
+func catalogTerms(code string, names map[string]string, aliases []string) []string {
+	terms := []string{code}
+	for _, name := range names {
+		terms = append(terms, name)
+	}
+	return append(terms, aliases...)
+}
+
+func hasSpecies(cat *catalog.Catalog, speciesCode string) bool {
+	species, ok := cat.Species(speciesCode)
+	return ok && species.Code == speciesCode
+}
+
+// catalogLanguage is the language of ?lang=, or else the first one of the Accept-Language header.
+func catalogLanguage(c *fiber.Ctx) string {
+	if language := c.Query("lang"); language != "" {
+		return strings.ToLower(language)
+	}
+	if accepted := c.Get(fiber.HeaderAcceptLanguage); len(accepted) >= 2 {
+		return strings.ToLower(accepted[:2])
+	}
+	return models.CatalogLanguage
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/contact"
+	"pet-appointments-api/models"
+)
+
+// phoneRegion is the country phone numbers written without their calling code are read in.
+var phoneRegion = configs.EnvPhoneRegion()
+
+// normalizeContact rewrites the phones of an owner or a partner in E.164 and their emails normalised, so they
+// are stored and compared the same way however they were written.
+func normalizeContact(phone *string, email *string, methods []models.ContactMethod) error {
+	parsedPhone, err := contact.ParsePhone(*phone, phoneRegion)
+	if err != nil {
+		return fmt.Errorf("%w: %q is not a valid phone number", errInvalidContact, *phone)
+	}
+	parsedEmail, err := contact.ParseEmail(*email)
+	if err != nil {
+		return fmt.Errorf("%w: %q is not a valid email address", errInvalidContact, *email)
+	}
+	*phone, *email = parsedPhone, parsedEmail
+
+	preferred := 0
+	for i, method := range methods {
+		var value string
+		switch method.Type {
+		case models.ContactPhone:
+			value, err = contact.ParsePhone(method.Value, phoneRegion)
+		case models.ContactEmail:
+			value, err = contact.ParseEmail(method.Value)
+		default:
+			err = errInvalidContact
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s", errInvalidContact, method.Value, method.Type)
+		}
+		methods[i].Value = value
+		if method.Preferred {
+			preferred++
+		}
+	}
+	if preferred > 1 {
+		return fmt.Errorf("%w: only one contact method can be preferred", errInvalidContact)
+	}
+	return nil
+}
+
+// defaultOptIns opts new owners in to every channel when they didn't say, as appointment reminders were always
+// sent to them.
+func defaultOptIns(optIns map[string]bool) map[string]bool {
+	if optIns != nil {
+		return optIns
+	}
+	optIns = map[string]bool{}
+	for _, channel := range models.ContactChannels {
+		optIns[channel] = true
+	}
+	return optIns
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pet-appointments-api/configs"
+)
+
+var counterCollection *mongo.Collection = configs.GetCollection(configs.DB, "counters")
+
+// nextSequence atomically increments and returns the named counter, starting at 1.
+func nextSequence(ctx context.Context, name string) (int64, error) {
+	var counter struct {
+		Name     string
+		Sequence int64
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := counterCollection.FindOneAndUpdate(ctx, bson.M{"name": name}, bson.M{"$inc": bson.M{"sequence": 1}}, opts).Decode(&counter)
+	return counter.Sequence, err
+}
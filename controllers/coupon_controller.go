@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"strings"
+	"time"
+)
+
+var couponCollection *mongo.Collection = configs.GetCollection(configs.DB, "coupons")
+var validateCoupon = validator.New()
+
+// Create a new Coupon
+func CreateCoupon(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var coupon models.Coupon
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&coupon); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateCoupon.Struct(&coupon); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	newCoupon := models.Coupon{
+		Id:             primitive.NewObjectID(),
+		Code:           strings.ToUpper(coupon.Code),
+		Kind:           coupon.Kind,
+		BasisPoints:    coupon.BasisPoints,
+		Amount:         coupon.Amount,
+		PartnerId:      coupon.PartnerId,
+		ValidFrom:      coupon.ValidFrom,
+		ValidUntil:     coupon.ValidUntil,
+		MaxRedemptions: coupon.MaxRedemptions,
+		Disabled:       coupon.Disabled,
+		CreationDate:   time.Now(),
+	}
+
+	result, err := couponCollection.InsertOne(ctx, newCoupon)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Coupon creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Coupon was created successfully.", Data: &fiber.Map{"data": result}})
+}
+
+// Get a Coupon
+func GetCoupon(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	couponId := c.Params("couponId")
+	defer cancel()
+
+	//validate if the coupon ID exists
+	coupon, err := findCoupon(ctx, couponId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid coupon ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": coupon}})
+}
+
+// Edit a Coupon
+func EditCoupon(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	couponId := c.Params("couponId")
+	var coupon models.Coupon
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(couponId)
+
+	//validate the request body
+	if err := c.BodyParser(&coupon); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateCoupon.Struct(&coupon); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	update := bson.M{"code": strings.ToUpper(coupon.Code), "kind": coupon.Kind, "basispoints": coupon.BasisPoints, "amount": coupon.Amount, "partnerid": coupon.PartnerId, "validfrom": coupon.ValidFrom, "validuntil": coupon.ValidUntil, "maxredemptions": coupon.MaxRedemptions, "disabled": coupon.Disabled}
+
+	result, err := couponCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Coupon edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//get updated coupon details
+	var updatedCoupon models.Coupon
+	if result.MatchedCount == 1 {
+		err := couponCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedCoupon)
+
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Coupon edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Coupon with the ID " + couponId + " was edited correctly.", Data: &fiber.Map{"data": updatedCoupon}})
+}
+
+// Delete a Coupon
+func DeleteCoupon(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	couponId := c.Params("couponId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(couponId)
+
+	result, err := couponCollection.DeleteOne(ctx, bson.M{"id": objId})
+
+	//validate if the DeleteOne functions returns an Error
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no coupon with that ID. ", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//validate the ID number
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Coupon with the ID " + couponId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Coupon was deleted successfully."}},
+	)
+}
+
+// Get All Coupons
+func GetAllCoupons(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var coupons []models.Coupon
+	defer cancel()
+
+	results, err := couponCollection.Find(ctx, bson.M{})
+
+	//validate if the context has a collection
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//reading from the db in an optimal way
+	defer results.Close(ctx)
+	for results.Next(ctx) {
+		var singleCoupon models.Coupon
+		if err = results.Decode(&singleCoupon); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+
+		coupons = append(coupons, singleCoupon)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": coupons}},
+	)
+}
+
+// findCoupon loads a Coupon by its ID.
+func findCoupon(ctx context.Context, couponId string) (models.Coupon, error) {
+	var coupon models.Coupon
+
+	objId, err := primitive.ObjectIDFromHex(couponId)
+	if err != nil {
+		return coupon, err
+	}
+
+	err = couponCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&coupon)
+	return coupon, err
+}
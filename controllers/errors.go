@@ -0,0 +1,22 @@
+package controllers
+
+import "errors"
+
+var (
+	errPaymentNotCaptured  = errors.New("only captured payments can be refunded")
+	errInvalidRefundAmount = errors.New("the refund amount must be positive and not bigger than the refundable amount")
+	errInvalidPayAmount    = errors.New("the payment amount must be positive and not bigger than the outstanding amount")
+	errNothingOwed         = errors.New("no cancellation fee is owed for the appointment")
+	errInvalidCoupon       = errors.New("the coupon code can't be used")
+	errInvalidRecurrence   = errors.New("the recurrence rule can't be used")
+	errOfferUnavailable    = errors.New("the waitlist offer is no longer available")
+	errHoldUnavailable     = errors.New("the slot hold expired or was already used")
+	errTooManyHolds        = errors.New("the owner holds too many slots already")
+	errSlotUnavailable     = errors.New("the partner is not available at that time")
+	errNotScheduled        = errors.New("the appointment is no longer scheduled")
+	errTransferUnavailable = errors.New("the pet transfer is no longer pending")
+	errInvalidAgeRange     = errors.New("the age range is invalid")
+	errInvalidContact      = errors.New("the contact details are invalid")
+	errAddressNotFound     = errors.New("the address could not be located")
+	errInvalidLocation     = errors.New("the location is invalid")
+)
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"pet-appointments-api/models"
+	"strings"
+	"time"
+)
+
+// QueueWebhooks is the event bus handler that tells the outside world that something happened to an entity, queueing
+// a webhook delivery for every subscription of the partners the event concerns that is interested in it. An event
+// published twice is only queued once.
+func QueueWebhooks(ctx context.Context, event models.Event) error {
+	partnerIds, err := eventPartners(ctx, event)
+	if err != nil || len(partnerIds) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []models.WebhookSubscription
+	filter := bson.M{"partnerid": bson.M{"$in": partnerIds}, "disabled": false, "events": bson.M{"$in": []string{event.Type, "*"}}}
+	results, err := webhookCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err := results.All(ctx, &subscriptions); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		delivery := models.WebhookDelivery{
+			Id:             primitive.NewObjectID(),
+			SubscriptionId: subscription.Id.Hex(),
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookPending,
+			NextAttempt:    now,
+			CreationDate:   now,
+			UpdateDate:     now,
+		}
+
+		if _, err := webhookDeliveryCollection.InsertOne(ctx, delivery); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventSubject is where the owner or the pet an event tells about is found in its data: at the top for the ones
+// created, updated or deleted, under "owner" for merges and under "pet" for transfers.
+type eventSubject struct {
+	Id    string `json:"id"`
+	Owner struct {
+		Id string `json:"id"`
+	} `json:"owner"`
+	Pet struct {
+		Id string `json:"id"`
+	} `json:"pet"`
+}
+
+// eventPartners returns the partners an event concerns: the one it was recorded for, or every partner with
+// appointments of the owner or the pet an event about them tells of. Owners and pets without appointments concern
+// no partner, so their events aren't sent anywhere.
+func eventPartners(ctx context.Context, event models.Event) ([]string, error) {
+	if event.PartnerId != "" {
+		return []string{event.PartnerId}, nil
+	}
+
+	var field string
+	switch {
+	case strings.HasPrefix(event.Type, "owner."):
+		field = "ownerid"
+	case strings.HasPrefix(event.Type, "pet."):
+		field = "petid"
+	default:
+		return nil, nil
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	var subject eventSubject
+	if err := json.Unmarshal(data, &subject); err != nil {
+		return nil, err
+	}
+	id := subject.Id
+	for _, nested := range []string{subject.Owner.Id, subject.Pet.Id} {
+		if nested != "" {
+			id = nested
+		}
+	}
+	if id == "" {
+		return nil, nil
+	}
+
+	values, err := appointmentCollection.Distinct(ctx, "partnerid", bson.M{field: id})
+	if err != nil {
+		return nil, err
+	}
+	var partnerIds []string
+	for _, value := range values {
+		if partnerId, ok := value.(string); ok && partnerId != "" {
+			partnerIds = append(partnerIds, partnerId)
+		}
+	}
+	return partnerIds, nil
+}
+
+// eventPartner returns the partner an event concerns: the one of an appointment, or the partner itself. Deletions
+// only tell the ID of what was removed, along with the partner of a removed appointment.
+func eventPartner(eventType string, data interface{}) string {
+	switch data := data.(type) {
+	case models.Appointment:
+		return data.PartnerId
+	case models.Partner:
+		return data.Id.Hex()
+	case fiber.Map:
+		key := "partnerId"
+		if eventType == models.EventPartnerDeleted {
+			key = "id"
+		}
+		partnerId, _ := data[key].(string)
+		return partnerId
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// EnsureIndexes creates the indexes the controllers rely on, like the uniqueness of invoice numbers and coupon codes.
+// Creating an index that already exists is a no-op, so it is safe to run on every start. It also creates the
+// collections written inside transactions, which older MongoDB versions can't create on the fly.
+func EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indexes := map[*mongo.Collection][]mongo.IndexModel{
+		invoiceCollection: {
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "number", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "appointmentid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		counterCollection: {
+			{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		appointmentCollection: {
+			{Keys: bson.D{{Key: "seriesid", Value: 1}, {Key: "occurrenceindex", Value: 1}}},
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "starttime", Value: 1}}},
+			{Keys: bson.D{{Key: "petid", Value: 1}, {Key: "partnerid", Value: 1}, {Key: "status", Value: 1}}},
+			{Keys: bson.D{{Key: "ownerid", Value: 1}, {Key: "partnerid", Value: 1}}},
+		},
+		paymentCollection: {
+			{Keys: bson.D{{Key: "appointmentid", Value: 1}, {Key: "creationdate", Value: -1}}},
+		},
+		payoutCollection: {
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "status", Value: 1}}},
+		},
+		notificationTemplateCollection: {
+			{Keys: bson.D{{Key: "event", Value: 1}, {Key: "language", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		webhookCollection: {
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "events", Value: 1}}},
+		},
+		webhookDeliveryCollection: {
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextattempt", Value: 1}}},
+			{Keys: bson.D{{Key: "subscriptionid", Value: 1}, {Key: "creationdate", Value: -1}}},
+			{Keys: bson.D{{Key: "subscriptionid", Value: 1}, {Key: "eventid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		outboxCollection: {
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextattempt", Value: 1}, {Key: "creationdate", Value: 1}}},
+		},
+		blockedSlotCollection: {
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "source", Value: 1}, {Key: "uid", Value: 1}, {Key: "occurrencestart", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "start", Value: 1}, {Key: "end", Value: 1}}},
+		},
+		calendarSourceCollection: {
+			{Keys: bson.D{{Key: "disabled", Value: 1}, {Key: "nextsync", Value: 1}}},
+		},
+		waitlistCollection: {
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "status", Value: 1}, {Key: "creationdate", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "offer.expiresat", Value: 1}}},
+		},
+		lockCollection: {
+			{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		slotHoldCollection: {
+			{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "partnerid", Value: 1}, {Key: "starttime", Value: 1}, {Key: "endtime", Value: 1}}},
+			{Keys: bson.D{{Key: "ownerid", Value: 1}, {Key: "expiresat", Value: 1}}},
+			{Keys: bson.D{{Key: "expiresat", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		visitNoteCollection: {
+			{Keys: bson.D{{Key: "appointmentid", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "petid", Value: 1}, {Key: "visitdate", Value: -1}}},
+		},
+		weightCollection: {
+			{Keys: bson.D{{Key: "petid", Value: 1}, {Key: "date", Value: -1}}},
+		},
+		allergyCollection: {
+			{Keys: bson.D{{Key: "petid", Value: 1}}},
+		},
+		conditionCollection: {
+			{Keys: bson.D{{Key: "petid", Value: 1}}},
+		},
+		prescriptionCollection: {
+			{Keys: bson.D{{Key: "petid", Value: 1}, {Key: "startdate", Value: -1}}},
+		},
+		speciesCollection: {
+			{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		breedCollection: {
+			{Keys: bson.D{{Key: "speciescode", Value: 1}, {Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		attachmentCollection: {
+			{Keys: bson.D{{Key: "petid", Value: 1}, {Key: "creationdate", Value: -1}}},
+			{Keys: bson.D{{Key: "appointmentid", Value: 1}}},
+		},
+		petTransferCollection: {
+			{Keys: bson.D{{Key: "petid", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.TransferPending})},
+			{Keys: bson.D{{Key: "toownerid", Value: 1}, {Key: "status", Value: 1}}},
+		},
+		petCollection: {
+			{Keys: bson.D{{Key: "birthdate", Value: 1}}},
+			{Keys: bson.D{{Key: "vaccinations.nextduedate", Value: 1}}},
+		},
+		partnerCollection: {
+			{Keys: bson.D{{Key: "address.location", Value: "2dsphere"}}},
+		},
+		couponCollection: {
+			{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureOwnerIndexes creates the uniqueness of owner ID numbers and emails. It runs after the migrations, which
+// normalise the stored emails, and fails while duplicated owners remain: they have to be merged first, see
+// GetOwnerDuplicates.
+func EnsureOwnerIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := ownerCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "idnumber", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	return err
+}
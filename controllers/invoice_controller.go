@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/invoices"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var invoiceCollection *mongo.Collection = configs.GetCollection(configs.DB, "invoices")
+
+const mimeApplicationPDF = "application/pdf"
+
+// Issue the Invoice of a completed Appointment, with the next number of its clinic
+func IssueAppointmentInvoice(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the invoice holds the personal data of the owner, so only the parties of the appointment see it
+	if !appointmentParty(c, appointment) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner and the partner of the appointment can get its invoice.", Data: &fiber.Map{"data": appointmentId}})
+	}
+
+	if appointment.Status != models.AppointmentCompleted {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: invoices are only issued for completed appointments.", Data: &fiber.Map{"data": appointment.Status}})
+	}
+
+	invoice, issued, err := issueInvoice(ctx, appointment)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Invoice generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if !issued {
+		return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Invoice was already issued.", Data: &fiber.Map{"data": invoice}})
+	}
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The Invoice " + invoice.Number + " was issued successfully.", Data: &fiber.Map{"data": invoice}})
+}
+
+// Get the issued Invoice of an Appointment as JSON, HTML or PDF
+func GetAppointmentInvoice(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the invoice holds the personal data of the owner, so only the parties of the appointment see it
+	if !appointmentParty(c, appointment) {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner and the partner of the appointment can get its invoice.", Data: &fiber.Map{"data": appointmentId}})
+	}
+
+	var invoice models.Invoice
+	err = invoiceCollection.FindOne(ctx, bson.M{"appointmentid": appointmentId}).Decode(&invoice)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: the invoice of the appointment was not issued yet.", Data: &fiber.Map{"data": appointmentId}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Invoice generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the format query parameter wins over the Accept header, which is handy for download links
+	format := c.Query("format")
+	switch format {
+	case "pdf":
+		format = mimeApplicationPDF
+	case "html":
+		format = fiber.MIMETextHTML
+	case "json":
+		format = fiber.MIMEApplicationJSON
+	default:
+		format = c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML, mimeApplicationPDF)
+	}
+
+	var body bytes.Buffer
+	switch format {
+	case mimeApplicationPDF:
+		err = invoices.RenderPDF(&body, invoice)
+		c.Set(fiber.HeaderContentDisposition, `inline; filename="`+invoice.Number+`.pdf"`)
+		c.Set(fiber.HeaderContentType, mimeApplicationPDF)
+	case fiber.MIMETextHTML:
+		err = invoices.RenderHTML(&body, invoice)
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	default:
+		return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": invoice}})
+	}
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Invoice generation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).Send(body.Bytes())
+}
+
+// issueInvoice returns the invoice of an appointment, creating it with the next number of the clinic when it was
+// never issued, and tells whether it was created. The number is taken in the transaction inserting the invoice, so
+// when two requests race the one losing on the unique index of the appointment gives its number back and the
+// numbers stay without gaps.
+func issueInvoice(ctx context.Context, appointment models.Appointment) (models.Invoice, bool, error) {
+	var invoice models.Invoice
+
+	err := invoiceCollection.FindOne(ctx, bson.M{"appointmentid": appointment.Id.Hex()}).Decode(&invoice)
+	if err != mongo.ErrNoDocuments {
+		return invoice, false, err
+	}
+
+	owner, err := findOwner(ctx, appointment.OwnerId)
+	if err != nil {
+		return invoice, false, err
+	}
+	pet, err := findPet(ctx, appointment.PetId)
+	if err != nil {
+		return invoice, false, err
+	}
+	partner, err := findPartner(ctx, appointment.PartnerId)
+	if err != nil {
+		return invoice, false, err
+	}
+
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		sequence, err := nextSequence(sc, "invoice:"+appointment.PartnerId)
+		if err != nil {
+			return err
+		}
+
+		invoice = invoices.Build(appointment, owner, pet, partner, time.Now())
+		invoice.Id = primitive.NewObjectID()
+		invoice.Sequence = sequence
+		invoice.Number = invoices.Number(sequence)
+
+		_, err = invoiceCollection.InsertOne(sc, invoice)
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		//another request issued the invoice first, so that one is the invoice of the appointment
+		err = invoiceCollection.FindOne(ctx, bson.M{"appointmentid": appointment.Id.Hex()}).Decode(&invoice)
+		return invoice, false, err
+	}
+	if err != nil {
+		return invoice, false, err
+	}
+
+	notifyAppointment(ctx, models.EventInvoiceReady, appointment, &invoice)
+	return invoice, true, nil
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pet-appointments-api/configs"
+	"time"
+)
+
+var lockCollection *mongo.Collection = configs.GetCollection(configs.DB, "locks")
+
+// lock serialises the transactions that check something before writing it, like a free slot or an outstanding
+// amount. Each one writes the lock document of its key first, so when two of them run together the second one hits
+// a write conflict and is retried, and its check then sees what the first one wrote.
+func lock(sc mongo.SessionContext, key string) error {
+	update := bson.M{"$inc": bson.M{"version": 1}, "$set": bson.M{"updatedate": time.Now()}}
+	_, err := lockCollection.UpdateOne(sc, bson.M{"key": key}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// lockPartnerAgenda serialises the transactions that take time of a partner's agenda.
+func lockPartnerAgenda(sc mongo.SessionContext, partnerId string) error {
+	return lock(sc, "agenda:"+partnerId)
+}
+
+// lockOwnerHolds serialises the transactions that hold slots for an owner.
+func lockOwnerHolds(sc mongo.SessionContext, ownerId string) error {
+	return lock(sc, "holds:"+ownerId)
+}
+
+// lockAppointmentPayments serialises the transactions that add payments to an appointment.
+func lockAppointmentPayments(sc mongo.SessionContext, appointmentId string) error {
+	return lock(sc, "payments:"+appointmentId)
+}
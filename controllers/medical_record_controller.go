@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var visitNoteCollection *mongo.Collection = configs.GetCollection(configs.DB, "visitNotes")
+var weightCollection *mongo.Collection = configs.GetCollection(configs.DB, "weightHistory")
+var allergyCollection *mongo.Collection = configs.GetCollection(configs.DB, "allergies")
+var conditionCollection *mongo.Collection = configs.GetCollection(configs.DB, "chronicConditions")
+var validateMedicalRecord = validator.New()
+
+// Get the medical record of a Pet, for its owner and the partners who treated it
+func GetPetMedicalRecord(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadMedicalRecord(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can see its medical record.", Data: &fiber.Map{"data": petId}})
+	}
+
+	record := models.MedicalRecord{Pet: pet.WithAge(time.Now()), Visits: []models.VisitNote{}, Weights: []models.WeightEntry{}, Allergies: []models.Allergy{}, Conditions: []models.ChronicCondition{}, Vaccinations: sortedVaccinations(pet), Prescriptions: []models.Prescription{}}
+	if err := findPetEntries(ctx, visitNoteCollection, petId, "visitdate", &record.Visits); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err := findPetEntries(ctx, weightCollection, petId, "date", &record.Weights); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err := findPetEntries(ctx, allergyCollection, petId, "creationdate", &record.Allergies); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err := findPetEntries(ctx, conditionCollection, petId, "creationdate", &record.Conditions); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err := findPetEntries(ctx, prescriptionCollection, petId, "startdate", &record.Prescriptions); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": record}})
+}
+
+// Write the VisitNote of a completed Appointment, as the Partner who attended it
+func CreateVisitNote(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	var note models.VisitNote
+	defer cancel()
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if c.Get(headerPartnerId) != appointment.PartnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partner of the appointment can write its visit note.", Data: &fiber.Map{"data": appointmentId}})
+	}
+
+	if appointment.Status != models.AppointmentCompleted {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: visit notes can only be written for completed appointments.", Data: &fiber.Map{"data": appointment.Status}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&note); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&note); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	now := time.Now()
+	newNote := models.VisitNote{
+		Id:            primitive.NewObjectID(),
+		PetId:         appointment.PetId,
+		AppointmentId: appointmentId,
+		PartnerId:     appointment.PartnerId,
+		Notes:         note.Notes,
+		Diagnoses:     note.Diagnoses,
+		WeightKg:      note.WeightKg,
+		VisitDate:     appointment.StartTime,
+		CreationDate:  now,
+	}
+
+	//the note and the weight taken in the visit are saved together
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := visitNoteCollection.InsertOne(sc, newNote); err != nil {
+			return err
+		}
+		return saveVisitWeight(sc, newNote, now)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the appointment already has a visit note, edit it instead.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the VisitNote creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new VisitNote was created successfully.", Data: &fiber.Map{"data": newNote}})
+}
+
+// Edit a VisitNote, as the Partner who wrote it
+func EditVisitNote(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	noteId := c.Params("noteId")
+	var note models.VisitNote
+	defer cancel()
+
+	previous, err := findVisitNote(ctx, noteId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid visit note ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if c.Get(headerPartnerId) != previous.PartnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partner who wrote the visit note can edit it.", Data: &fiber.Map{"data": noteId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&note); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&note); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	now := time.Now()
+	update := bson.M{"notes": note.Notes, "diagnoses": note.Diagnoses, "weightkg": note.WeightKg, "updatedate": now}
+
+	var updatedNote models.VisitNote
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := visitNoteCollection.UpdateOne(sc, bson.M{"id": previous.Id}, bson.M{"$set": update}); err != nil {
+			return err
+		}
+
+		var err error
+		if updatedNote, err = findVisitNote(sc, noteId); err != nil {
+			return err
+		}
+		return saveVisitWeight(sc, updatedNote, now)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the VisitNote edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The VisitNote with the ID " + noteId + " was edited correctly.", Data: &fiber.Map{"data": updatedNote}})
+}
+
+// Add a weight to the history of a Pet
+func AddPetWeight(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	var weight models.WeightEntry
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partnerId, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can write in its medical record.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&weight); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&weight); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	now := time.Now()
+	if weight.Date.IsZero() {
+		weight.Date = now
+	}
+
+	newWeight := models.WeightEntry{
+		Id:           primitive.NewObjectID(),
+		PetId:        petId,
+		WeightKg:     weight.WeightKg,
+		Date:         weight.Date,
+		PartnerId:    partnerId,
+		CreationDate: now,
+	}
+
+	if _, err := weightCollection.InsertOne(ctx, newWeight); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the weight could not be saved.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The weight was added to the medical record.", Data: &fiber.Map{"data": newWeight}})
+}
+
+// Add an Allergy to the medical record of a Pet
+func AddPetAllergy(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	var allergy models.Allergy
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partnerId, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can write in its medical record.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&allergy); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&allergy); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	newAllergy := models.Allergy{
+		Id:           primitive.NewObjectID(),
+		PetId:        petId,
+		Substance:    allergy.Substance,
+		Reaction:     allergy.Reaction,
+		Severity:     allergy.Severity,
+		PartnerId:    partnerId,
+		CreationDate: time.Now(),
+	}
+
+	if _, err := allergyCollection.InsertOne(ctx, newAllergy); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the allergy could not be saved.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The allergy was added to the medical record.", Data: &fiber.Map{"data": newAllergy}})
+}
+
+// Remove an Allergy recorded by mistake from the medical record of a Pet
+func DeletePetAllergy(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	allergyId := c.Params("allergyId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	_, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can write in its medical record.", Data: &fiber.Map{"data": petId}})
+	}
+
+	objId, _ := primitive.ObjectIDFromHex(allergyId)
+	result, err := allergyCollection.DeleteOne(ctx, bson.M{"id": objId, "petid": petId})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Allergy with the ID " + allergyId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Allergy was deleted successfully."}},
+	)
+}
+
+// Add a ChronicCondition to the medical record of a Pet
+func AddPetCondition(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	var condition models.ChronicCondition
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partnerId, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can write in its medical record.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&condition); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&condition); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	newCondition := models.ChronicCondition{
+		Id:           primitive.NewObjectID(),
+		PetId:        petId,
+		Name:         condition.Name,
+		Since:        condition.Since,
+		Notes:        condition.Notes,
+		Resolved:     condition.Resolved,
+		PartnerId:    partnerId,
+		CreationDate: time.Now(),
+	}
+
+	if _, err := conditionCollection.InsertOne(ctx, newCondition); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the condition could not be saved.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The condition was added to the medical record.", Data: &fiber.Map{"data": newCondition}})
+}
+
+// Edit a ChronicCondition of a Pet, for example to mark it as resolved
+func EditPetCondition(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	conditionId := c.Params("conditionId")
+	var condition models.ChronicCondition
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	_, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can write in its medical record.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&condition); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&condition); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	objId, _ := primitive.ObjectIDFromHex(conditionId)
+	update := bson.M{"name": condition.Name, "since": condition.Since, "notes": condition.Notes, "resolved": condition.Resolved, "updatedate": time.Now()}
+
+	var updatedCondition models.ChronicCondition
+	err = conditionCollection.FindOneAndUpdate(ctx, bson.M{"id": objId, "petid": petId}, bson.M{"$set": update}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updatedCondition)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid condition ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the condition could not be saved.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The condition with the ID " + conditionId + " was edited correctly.", Data: &fiber.Map{"data": updatedCondition}})
+}
+
+// findVisitNote loads a VisitNote by its ID.
+func findVisitNote(ctx context.Context, noteId string) (models.VisitNote, error) {
+	var note models.VisitNote
+
+	objId, err := primitive.ObjectIDFromHex(noteId)
+	if err != nil {
+		return note, err
+	}
+
+	err = visitNoteCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&note)
+	return note, err
+}
+
+// findPetEntries loads the entries of a pet in a medical record collection, the newest first.
+func findPetEntries(ctx context.Context, collection *mongo.Collection, petId string, dateField string, entries interface{}) error {
+	results, err := collection.Find(ctx, bson.M{"petid": petId}, options.Find().SetSort(bson.M{dateField: -1}))
+	if err != nil {
+		return err
+	}
+	return results.All(ctx, entries)
+}
+
+// saveVisitWeight keeps the weight history in line with the weight of a visit note, there is one entry per visit.
+func saveVisitWeight(sc mongo.SessionContext, note models.VisitNote, now time.Time) error {
+	filter := bson.M{"petid": note.PetId, "appointmentid": note.AppointmentId}
+	if note.WeightKg <= 0 {
+		_, err := weightCollection.DeleteOne(sc, filter)
+		return err
+	}
+
+	update := bson.M{
+		"$set":         bson.M{"weightkg": note.WeightKg, "date": note.VisitDate, "partnerid": note.PartnerId},
+		"$setOnInsert": bson.M{"id": primitive.NewObjectID(), "creationdate": now},
+	}
+	_, err := weightCollection.UpdateOne(sc, filter, update, options.Update().SetUpsert(true))
+	return err
+}
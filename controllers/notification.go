@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"pet-appointments-api/models"
+	"pet-appointments-api/notifications"
+)
+
+// notificationData loads the owner, pet and partner of an appointment to render its notifications.
+func notificationData(ctx context.Context, appointment models.Appointment) (notifications.Data, error) {
+	data := notifications.Data{Appointment: appointment}
+
+	var err error
+	if data.Owner, err = findOwner(ctx, appointment.OwnerId); err != nil {
+		return data, err
+	}
+	if data.Pet, err = findPet(ctx, appointment.PetId); err != nil {
+		return data, err
+	}
+	data.Partner, err = findPartner(ctx, appointment.PartnerId)
+	return data, err
+}
+
+// notifyAppointment sends the notification of an event to the owner of an appointment. A failure to load the data
+// is only logged, notifications never fail the request that triggered them.
+func notifyAppointment(ctx context.Context, event models.NotificationEvent, appointment models.Appointment, invoice *models.Invoice) {
+	data, err := notificationData(ctx, appointment)
+	if err != nil {
+		log.Println("notifications:", event, "for appointment", appointment.Id.Hex(), "skipped:", err)
+		return
+	}
+
+	data.Invoice = invoice
+	notifications.Notify(event, data)
+}
@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/notifications"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var notificationTemplateCollection *mongo.Collection = configs.GetCollection(configs.DB, "notificationTemplates")
+var validateNotificationTemplate = validator.New()
+
+// Create a new Notification Template
+func CreateNotificationTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var notificationTemplate models.NotificationTemplate
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&notificationTemplate); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateNotificationTemplate.Struct(&notificationTemplate); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	//the subject and the body must be valid Go templates
+	if templateErr := notifications.Validate(notificationTemplate); templateErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the template is invalid.", Data: &fiber.Map{"data": templateErr.Error()}})
+	}
+
+	newNotificationTemplate := models.NotificationTemplate{
+		Id:           primitive.NewObjectID(),
+		Event:        notificationTemplate.Event,
+		Language:     notificationTemplate.Language,
+		Subject:      notificationTemplate.Subject,
+		Body:         notificationTemplate.Body,
+		CreationDate: time.Now(),
+		UpdateDate:   time.Now(),
+	}
+
+	result, err := notificationTemplateCollection.InsertOne(ctx, newNotificationTemplate)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: there is already a template for this event and language.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Notification Template creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Notification Template was created successfully.", Data: &fiber.Map{"data": result}})
+}
+
+// Get a Notification Template
+func GetNotificationTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	notificationTemplateId := c.Params("notificationTemplateId")
+	defer cancel()
+
+	//validate if the notification template ID exists
+	notificationTemplate, err := findNotificationTemplate(ctx, notificationTemplateId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid notification template ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": notificationTemplate}})
+}
+
+// Edit a Notification Template
+func EditNotificationTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	notificationTemplateId := c.Params("notificationTemplateId")
+	var notificationTemplate models.NotificationTemplate
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(notificationTemplateId)
+
+	//validate the request body
+	if err := c.BodyParser(&notificationTemplate); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateNotificationTemplate.Struct(&notificationTemplate); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	//the subject and the body must be valid Go templates
+	if templateErr := notifications.Validate(notificationTemplate); templateErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the template is invalid.", Data: &fiber.Map{"data": templateErr.Error()}})
+	}
+
+	update := bson.M{"event": notificationTemplate.Event, "language": notificationTemplate.Language, "subject": notificationTemplate.Subject, "body": notificationTemplate.Body, "updatedate": time.Now()}
+
+	result, err := notificationTemplateCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: there is already a template for this event and language.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Notification Template edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//get updated notification template details
+	var updatedNotificationTemplate models.NotificationTemplate
+	if result.MatchedCount == 1 {
+		err := notificationTemplateCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedNotificationTemplate)
+
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Notification Template edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Notification Template with the ID " + notificationTemplateId + " was edited correctly.", Data: &fiber.Map{"data": updatedNotificationTemplate}})
+}
+
+// Delete a Notification Template
+func DeleteNotificationTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	notificationTemplateId := c.Params("notificationTemplateId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(notificationTemplateId)
+
+	result, err := notificationTemplateCollection.DeleteOne(ctx, bson.M{"id": objId})
+
+	//validate if the DeleteOne functions returns an Error
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no notification template with that ID. ", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//validate the ID number
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Notification Template with the ID " + notificationTemplateId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Notification Template was deleted successfully."}},
+	)
+}
+
+// Get All Notification Templates
+func GetAllNotificationTemplates(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var notificationTemplates []models.NotificationTemplate
+	defer cancel()
+
+	results, err := notificationTemplateCollection.Find(ctx, bson.M{})
+
+	//validate if the context has a collection
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//reading from the db in an optimal way
+	defer results.Close(ctx)
+	for results.Next(ctx) {
+		var singleNotificationTemplate models.NotificationTemplate
+		if err = results.Decode(&singleNotificationTemplate); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+
+		notificationTemplates = append(notificationTemplates, singleNotificationTemplate)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": notificationTemplates}},
+	)
+}
+
+// findNotificationTemplate loads a Notification Template by its ID.
+func findNotificationTemplate(ctx context.Context, notificationTemplateId string) (models.NotificationTemplate, error) {
+	var notificationTemplate models.NotificationTemplate
+
+	objId, err := primitive.ObjectIDFromHex(notificationTemplateId)
+	if err != nil {
+		return notificationTemplate, err
+	}
+
+	err = notificationTemplateCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&notificationTemplate)
+	return notificationTemplate, err
+}
+
+// Preview a Notification Template rendered against a real Appointment
+func PreviewNotificationTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	notificationTemplateId := c.Params("notificationTemplateId")
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	notificationTemplate, err := findNotificationTemplate(ctx, notificationTemplateId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid notification template ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	data, err := notificationData(ctx, appointment)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the appointment data could not be loaded.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//invoice templates are previewed against the invoice of the appointment, when it was issued
+	if notificationTemplate.Event == models.EventInvoiceReady {
+		var invoice models.Invoice
+		if err := invoiceCollection.FindOne(ctx, bson.M{"appointmentid": appointmentId}).Decode(&invoice); err == nil {
+			data.Invoice = &invoice
+		}
+	}
+
+	//waitlist offers are previewed as if the slot of the appointment had just been offered
+	if notificationTemplate.Event == models.EventWaitlistOffer {
+		data.Offer = &models.WaitlistOffer{Start: appointment.StartTime, End: appointment.EndTime, OfferDate: time.Now(), ExpiresAt: time.Now().Add(waitlistHold)}
+	}
+
+	//vaccination templates are previewed against the next dose due of the pet, or a made up one
+	if notificationTemplate.Event == models.EventVaccinationDue {
+		due := appointment.StartTime.AddDate(1, 0, 0)
+		data.Vaccination = &models.Vaccination{Vaccine: "Rabies", DateGiven: appointment.StartTime, PartnerId: appointment.PartnerId, NextDueDate: &due}
+		for _, vaccination := range data.Pet.CurrentVaccinations() {
+			if vaccination.NextDueDate != nil {
+				vaccination := vaccination
+				data.Vaccination = &vaccination
+				break
+			}
+		}
+	}
+
+	message, err := notifications.Render(notificationTemplate, data)
+	if err != nil {
+		return c.Status(http.StatusUnprocessableEntity).JSON(responses.Response{Status: http.StatusUnprocessableEntity, Message: "Error: the template could not be rendered for this appointment.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": fiber.Map{"to": message.To, "subject": message.Subject, "body": message.Body}}})
+}
+
+// TemplateStore gives the notification dispatcher access to the templates edited by the administrators.
+type TemplateStore struct{}
+
+func (TemplateStore) FindTemplate(ctx context.Context, event models.NotificationEvent, language string) (models.NotificationTemplate, error) {
+	var notificationTemplate models.NotificationTemplate
+
+	err := notificationTemplateCollection.FindOne(ctx, bson.M{"event": event, "language": language}).Decode(&notificationTemplate)
+	if err == mongo.ErrNoDocuments {
+		return notificationTemplate, notifications.ErrTemplateNotFound
+	}
+	return notificationTemplate, err
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/events"
+	"pet-appointments-api/models"
+	"time"
+)
+
+var outboxCollection *mongo.Collection = configs.GetCollection(configs.DB, "outbox")
+
+// withTransaction runs fn inside a MongoDB transaction, retrying it on transient errors. Transactions need MongoDB
+// to run as a replica set.
+func withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := configs.DB.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// recordEvent writes a domain event to the outbox. It must be called with the session context of the transaction that
+// changes the entity, so both are committed or rolled back together.
+func recordEvent(sc mongo.SessionContext, eventType string, data interface{}) error {
+	record, err := events.NewRecord(eventType, eventPartner(eventType, data), data, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = outboxCollection.InsertOne(sc, record)
+	return err
+}
+
+// OutboxStore is the MongoDB backed events.OutboxStore.
+type OutboxStore struct{}
+
+func (OutboxStore) ClaimPendingRecords(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxRecord, error) {
+	filter := bson.M{"status": models.OutboxPending, "nextattempt": bson.M{"$lte": now}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "nextattempt", Value: 1}, {Key: "creationdate", Value: 1}}).SetReturnDocument(options.After)
+
+	var records []models.OutboxRecord
+	for len(records) < limit {
+		var record models.OutboxRecord
+		err := outboxCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"nextattempt": now.Add(lease)}}, opts).Decode(&record)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (OutboxStore) SaveRecord(ctx context.Context, record models.OutboxRecord) error {
+	update := bson.M{"status": record.Status, "attempts": record.Attempts, "lasterror": record.LastError, "nextattempt": record.NextAttempt, "publishdate": record.PublishDate}
+	_, err := outboxCollection.UpdateOne(ctx, bson.M{"id": record.Id}, bson.M{"$set": update})
+	return err
+}
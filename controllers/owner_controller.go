@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
 	"pet-appointments-api/configs"
+	"pet-appointments-api/dedup"
 	"pet-appointments-api/models"
 	"pet-appointments-api/responses"
 	"time"
@@ -28,23 +29,55 @@ func CreateOwner(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
+	//the phones and emails are stored the same way however they were written
+	if err := normalizeContact(&owner.Phone, &owner.Email, owner.ContactMethods); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
 	//use the validator library to validate required fields
 	if validationErr := validateOwner.Struct(&owner); validationErr != nil {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
+	//addresses are stored with their location, so partners can be searched by distance
+	if err := locateAddress(ctx, owner.Address); err != nil {
+		return addressError(c, err)
+	}
+
+	//an ID number or an email already registered is the same person, not a new owner
+	if existing, err := findOwnerConflict(ctx, owner, primitive.NilObjectID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	} else if existing != nil {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: an owner with that ID number or email already exists.", Data: &fiber.Map{"data": existing.Id.Hex()}})
+	}
+
 	newOwner := models.Owner{
-		Id:           primitive.NewObjectID(),
-		Name:         owner.Name,
-		LastName:     owner.LastName,
-		IdNumber:     owner.IdNumber,
-		Phone:        owner.Phone,
-		Email:        owner.Email,
-		CreationDate: time.Now(),
-		Pets:         owner.Pets,
+		Id:             primitive.NewObjectID(),
+		Name:           owner.Name,
+		LastName:       owner.LastName,
+		IdNumber:       owner.IdNumber,
+		Phone:          owner.Phone,
+		Email:          owner.Email,
+		ContactMethods: owner.ContactMethods,
+		OptIns:         defaultOptIns(owner.OptIns),
+		Address:        owner.Address,
+		CreationDate:   time.Now(),
+		Pets:           owner.Pets,
+		Language:       owner.Language,
 	}
 
-	result, err := ownerCollection.InsertOne(ctx, newOwner)
+	//the Owner and its event are saved together
+	var result *mongo.InsertOneResult
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = ownerCollection.InsertOne(sc, newOwner); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventOwnerCreated, newOwner)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: an owner with that ID number or email already exists.", Data: &fiber.Map{"data": err.Error()}})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Owner creation process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
@@ -84,27 +117,52 @@ func EditOwner(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
+	//the phones and emails are stored the same way however they were written
+	if err := normalizeContact(&owner.Phone, &owner.Email, owner.ContactMethods); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
 	//use the validator library to validate required fields
 	if validationErr := validateOwner.Struct(&owner); validationErr != nil {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
-	update := bson.M{"name": owner.Name, "lastName": owner.LastName, "idNumber": owner.IdNumber, "phone": owner.Phone, "email": owner.Email, "pets": owner.Pets}
+	//addresses are stored with their location, so partners can be searched by distance
+	if err := locateAddress(ctx, owner.Address); err != nil {
+		return addressError(c, err)
+	}
 
-	result, err := ownerCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+	if existing, err := findOwnerConflict(ctx, owner, objId); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	} else if existing != nil {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: another owner has that ID number or email, merge them instead.", Data: &fiber.Map{"data": existing.Id.Hex()}})
+	}
 
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Owner edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	update := bson.M{"name": owner.Name, "lastname": owner.LastName, "idnumber": owner.IdNumber, "phone": owner.Phone, "email": owner.Email, "contactmethods": owner.ContactMethods, "address": owner.Address, "pets": owner.Pets, "language": owner.Language}
+	//opt-ins only change when they are sent, an owner never opts in to a channel by omission
+	if owner.OptIns != nil {
+		update["optins"] = owner.OptIns
 	}
 
-	//get updated owner details
 	var updatedOwner models.Owner
-	if result.MatchedCount == 1 {
-		err := ownerCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedOwner)
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := ownerCollection.UpdateOne(sc, bson.M{"id": objId}, bson.M{"$set": update})
+		if err != nil || result.MatchedCount != 1 {
+			return err
+		}
 
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Owner edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		//get updated owner details
+		if err := ownerCollection.FindOne(sc, bson.M{"id": objId}).Decode(&updatedOwner); err != nil {
+			return err
 		}
+		return recordEvent(sc, models.EventOwnerUpdated, updatedOwner)
+	})
+
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: another owner has that ID number or email, merge them instead.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Owner edit process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
 	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Owner with the ID " + ownerId + " was edited correctly.", Data: &fiber.Map{"data": updatedOwner}})
@@ -118,7 +176,14 @@ func DeleteOwner(c *fiber.Ctx) error {
 
 	objId, _ := primitive.ObjectIDFromHex(ownerId)
 
-	result, err := ownerCollection.DeleteOne(ctx, bson.M{"id": objId})
+	var result *mongo.DeleteResult
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = ownerCollection.DeleteOne(sc, bson.M{"id": objId}); err != nil || result.DeletedCount < 1 {
+			return err
+		}
+		return recordEvent(sc, models.EventOwnerDeleted, fiber.Map{"id": ownerId})
+	})
 
 	//validate if the DeleteOne functions returns an Error
 	if err != nil {
@@ -165,3 +230,31 @@ func GetAllOwners(c *fiber.Ctx) error {
 		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": owners}},
 	)
 }
+
+// findOwner loads an Owner by its ID.
+func findOwner(ctx context.Context, ownerId string) (models.Owner, error) {
+	var owner models.Owner
+
+	objId, err := primitive.ObjectIDFromHex(ownerId)
+	if err != nil {
+		return owner, err
+	}
+
+	err = ownerCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&owner)
+	return owner, err
+}
+
+// findOwnerConflict returns the owner other than the one being saved that has its ID number or email, if any.
+func findOwnerConflict(ctx context.Context, owner models.Owner, exceptId primitive.ObjectID) (*models.Owner, error) {
+	filter := bson.M{"id": bson.M{"$ne": exceptId}, "$or": bson.A{bson.M{"idnumber": owner.IdNumber}, bson.M{"email": dedup.Email(owner.Email)}}}
+
+	var existing models.Owner
+	err := ownerCollection.FindOne(ctx, filter).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
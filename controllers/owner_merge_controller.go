@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"pet-appointments-api/dedup"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"strconv"
+	"time"
+)
+
+var validateOwnerMerge = validator.New()
+
+// mergeRequest is the body accepted to merge two Owners: the one merged is folded into the one kept and removed.
+type mergeRequest struct {
+	KeepId  string `json:"keepId" validate:"required"`
+	MergeId string `json:"mergeId" validate:"required,nefield=KeepId"`
+}
+
+// mergedOwner is what the owner.merged event tells.
+type mergedOwner struct {
+	Owner              models.Owner     `json:"owner"`
+	MergedOwner        models.Owner     `json:"mergedOwner"`
+	Repointed          map[string]int64 `json:"repointed"`
+	CancelledTransfers int64            `json:"cancelledTransfers"`
+}
+
+// Get the pairs of Owners that look like the same person registered twice, the likeliest first
+func GetOwnerDuplicates(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	minScore := dedup.DefaultMinScore
+	if value := c.Query("minScore"); value != "" {
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || score <= 0 || score > 1 {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: minScore must be a number between 0 and 1.", Data: &fiber.Map{"data": value}})
+		}
+		minScore = score
+	}
+
+	var owners []models.Owner
+	projection := bson.M{"id": 1, "name": 1, "lastname": 1, "idnumber": 1, "phone": 1, "email": 1}
+	results, err := ownerCollection.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err := results.All(ctx, &owners); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": dedup.FindOwners(owners, minScore)}},
+	)
+}
+
+// Merge an Owner into another one. Its pets, appointments, waitlist entries, attachments and transfers move to the
+// owner kept, and so do its phones and emails, as extra contact methods. The owner kept also takes its language and
+// address when it had none, but keeps its own opt-ins, and the merged owner is removed.
+func MergeOwners(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var request mergeRequest
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateOwnerMerge.Struct(&request); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if _, err := findOwner(ctx, request.KeepId); err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid owner ID to keep.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if _, err := findOwner(ctx, request.MergeId); err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid owner ID to merge.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	var event mergedOwner
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		event = mergedOwner{Repointed: map[string]int64{}}
+
+		//the merged owner goes first, so its ID number and email no longer collide with the owner kept
+		merged, err := findOwner(sc, request.MergeId)
+		if err != nil {
+			return err
+		}
+		result, err := ownerCollection.DeleteOne(sc, bson.M{"id": merged.Id})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		event.MergedOwner = merged
+
+		//both owners are read in the transaction, so the contact methods merged are the current ones
+		kept, err := findOwner(sc, request.KeepId)
+		if err != nil {
+			return err
+		}
+
+		//a transfer between the two owners would now hand a pet to whoever already has it
+		between := bson.A{
+			bson.M{"fromownerid": request.MergeId, "toownerid": request.KeepId},
+			bson.M{"fromownerid": request.KeepId, "toownerid": request.MergeId},
+		}
+		update := bson.M{"$set": bson.M{"status": models.TransferCancelled, "decisiondate": time.Now()}}
+		cancelled, err := petTransferCollection.UpdateMany(sc, bson.M{"status": models.TransferPending, "$or": between}, update)
+		if err != nil {
+			return err
+		}
+		event.CancelledTransfers = cancelled.ModifiedCount
+
+		repoint := map[string]*mongo.Collection{"pets": petCollection, "appointments": appointmentCollection, "waitlist": waitlistCollection, "attachments": attachmentCollection}
+		for name, collection := range repoint {
+			result, err := collection.UpdateMany(sc, bson.M{"ownerid": request.MergeId}, bson.M{"$set": bson.M{"ownerid": request.KeepId}})
+			if err != nil {
+				return err
+			}
+			event.Repointed[name] = result.ModifiedCount
+		}
+
+		pastOwners := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"past.ownerid": request.MergeId}}})
+		if _, err := petCollection.UpdateMany(sc, bson.M{"pastowners.ownerid": request.MergeId}, bson.M{"$set": bson.M{"pastowners.$[past].ownerid": request.KeepId}}, pastOwners); err != nil {
+			return err
+		}
+		for _, field := range []string{"fromownerid", "toownerid"} {
+			result, err := petTransferCollection.UpdateMany(sc, bson.M{field: request.MergeId}, bson.M{"$set": bson.M{field: request.KeepId}})
+			if err != nil {
+				return err
+			}
+			event.Repointed["petTransfers"] += result.ModifiedCount
+		}
+
+		set := bson.M{"contactmethods": dedup.MergeContacts(kept, merged)}
+		if kept.Language == "" && merged.Language != "" {
+			set["language"] = merged.Language
+		}
+		if kept.Address == nil && merged.Address != nil {
+			set["address"] = merged.Address
+		}
+		update = bson.M{"$set": set, "$addToSet": bson.M{"pets": bson.M{"$each": append([]string{}, merged.Pets...)}}}
+		if err := ownerCollection.FindOneAndUpdate(sc, bson.M{"id": kept.Id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&event.Owner); err != nil {
+			return err
+		}
+
+		return recordEvent(sc, models.EventOwnerMerged, event)
+	})
+
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: one of the owners was removed in the meantime.", Data: &fiber.Map{"data": request}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the owners could not be merged.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The owners were merged successfully.", Data: &fiber.Map{"data": event}})
+}
@@ -2,21 +2,38 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"math"
 	"net/http"
 	"pet-appointments-api/configs"
 	"pet-appointments-api/models"
 	"pet-appointments-api/responses"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var partnerCollection *mongo.Collection = configs.GetCollection(configs.DB, "partners")
 var validatePartner = validator.New()
 
+// Radius of the proximity search of partners, in kilometres.
+const (
+	defaultSearchRadiusKm = 10.0
+	maxSearchRadiusKm     = 200.0
+)
+
+// nearbyPartner is a Partner found by a proximity search, with how far it is.
+type nearbyPartner struct {
+	models.Partner `bson:",inline"`
+	Distance       float64 `json:"-"`
+	DistanceKm     float64 `json:"distanceKm" bson:"-"`
+}
+
 // Create a new Partner
 func CreatePartner(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -28,23 +45,48 @@ func CreatePartner(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
+	//the phones and emails are stored the same way however they were written
+	if err := normalizeContact(&partner.Phone, &partner.Email, partner.ContactMethods); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
 	//use the validator library to validate required fields
 	if validationErr := validatePartner.Struct(&partner); validationErr != nil {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
+	//addresses are stored with their location, so partners can be searched by distance
+	if err := locateAddress(ctx, partner.Address); err != nil {
+		return addressError(c, err)
+	}
+
 	newPartner := models.Partner{
-		Id:           primitive.NewObjectID(),
-		Name:         partner.Name,
-		LastName:     partner.LastName,
-		IdNumber:     partner.IdNumber,
-		Phone:        partner.Phone,
-		Email:        partner.Email,
-		CreationDate: time.Now(),
-		Services:     partner.Services,
+		Id:                 primitive.NewObjectID(),
+		Name:               partner.Name,
+		LastName:           partner.LastName,
+		IdNumber:           partner.IdNumber,
+		Phone:              partner.Phone,
+		Email:              partner.Email,
+		ContactMethods:     partner.ContactMethods,
+		Address:            partner.Address,
+		CreationDate:       time.Now(),
+		Services:           partner.Services,
+		CancellationPolicy: partner.CancellationPolicy,
+		Region:             partner.Region,
+		MultiPetDiscount:   partner.MultiPetDiscount,
+		Commission:         partner.Commission,
+		TimeZone:           partner.TimeZone,
 	}
 
-	result, err := partnerCollection.InsertOne(ctx, newPartner)
+	//the Partner and its event are saved together
+	var result *mongo.InsertOneResult
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = partnerCollection.InsertOne(sc, newPartner); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventPartnerCreated, newPartner)
+	})
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Partner creation process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
@@ -84,27 +126,39 @@ func EditPartner(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
+	//the phones and emails are stored the same way however they were written
+	if err := normalizeContact(&partner.Phone, &partner.Email, partner.ContactMethods); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
 	//use the validator library to validate required fields
 	if validationErr := validatePartner.Struct(&partner); validationErr != nil {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
-	update := bson.M{"name": partner.Name, "lastName": partner.LastName, "idNumber": partner.IdNumber, "phone": partner.Phone, "email": partner.Email, "services": partner.Services}
-
-	result, err := partnerCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
-
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Partner edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	//addresses are stored with their location, so partners can be searched by distance
+	if err := locateAddress(ctx, partner.Address); err != nil {
+		return addressError(c, err)
 	}
 
-	//get updated partner details
+	update := bson.M{"name": partner.Name, "lastname": partner.LastName, "idnumber": partner.IdNumber, "phone": partner.Phone, "email": partner.Email, "contactmethods": partner.ContactMethods, "address": partner.Address, "services": partner.Services, "cancellationpolicy": partner.CancellationPolicy, "region": partner.Region, "multipetdiscount": partner.MultiPetDiscount, "commission": partner.Commission, "timezone": partner.TimeZone}
+
 	var updatedPartner models.Partner
-	if result.MatchedCount == 1 {
-		err := partnerCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedPartner)
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := partnerCollection.UpdateOne(sc, bson.M{"id": objId}, bson.M{"$set": update})
+		if err != nil || result.MatchedCount != 1 {
+			return err
+		}
 
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Partner edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		//get updated partner details
+		if err := partnerCollection.FindOne(sc, bson.M{"id": objId}).Decode(&updatedPartner); err != nil {
+			return err
 		}
+		return recordEvent(sc, models.EventPartnerUpdated, updatedPartner)
+	})
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Partner edit process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
 	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Partner with the ID " + partnerId + " was edited correctly.", Data: &fiber.Map{"data": updatedPartner}})
@@ -118,7 +172,14 @@ func DeletePartner(c *fiber.Ctx) error {
 
 	objId, _ := primitive.ObjectIDFromHex(partnerId)
 
-	result, err := partnerCollection.DeleteOne(ctx, bson.M{"id": objId})
+	var result *mongo.DeleteResult
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = partnerCollection.DeleteOne(sc, bson.M{"id": objId}); err != nil || result.DeletedCount < 1 {
+			return err
+		}
+		return recordEvent(sc, models.EventPartnerDeleted, fiber.Map{"id": partnerId})
+	})
 
 	//validate if the DeleteOne functions returns an Error
 	if err != nil {
@@ -139,6 +200,10 @@ func DeletePartner(c *fiber.Ctx) error {
 
 // Get All Partners
 func GetAllPartners(c *fiber.Ctx) error {
+	if c.Query("near") != "" {
+		return getNearbyPartners(c)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	var partners []models.Partner
 	defer cancel()
@@ -165,3 +230,80 @@ func GetAllPartners(c *fiber.Ctx) error {
 		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": partners}},
 	)
 }
+
+// getNearbyPartners answers GET /partners?near=lat,lng&radius=km&service=serviceId with the partners located
+// within the radius, offering the service when one is given, the closest first.
+func getNearbyPartners(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	near, err := parseNear(c.Query("near"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: near must be a latitude and a longitude, like near=40.4168,-3.7038.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	radius := defaultSearchRadiusKm
+	if value := c.Query("radius"); value != "" {
+		radius, err = strconv.ParseFloat(value, 64)
+		if err != nil || radius <= 0 || radius > maxSearchRadiusKm {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: fmt.Sprintf("Error: radius must be a number of kilometres up to %g.", maxSearchRadiusKm), Data: &fiber.Map{"data": value}})
+		}
+	}
+
+	//$geoNear must be the first stage, it sorts the partners by distance and filters them by service itself
+	geoNear := bson.M{"near": near, "key": "address.location", "distanceField": "distance", "maxDistance": radius * 1000, "spherical": true}
+	if serviceId := c.Query("service"); serviceId != "" {
+		geoNear["query"] = bson.M{"services": serviceId}
+	}
+	results, err := partnerCollection.Aggregate(ctx, mongo.Pipeline{{{Key: "$geoNear", Value: geoNear}}})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partners := []nearbyPartner{}
+	if err := results.All(ctx, &partners); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	for i := range partners {
+		partners[i].DistanceKm = math.Round(partners[i].Distance/10) / 100
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": partners}},
+	)
+}
+
+// parseNear reads a point written as "latitude,longitude".
+func parseNear(value string) (models.GeoPoint, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return models.GeoPoint{}, errInvalidLocation
+	}
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return models.GeoPoint{}, err
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return models.GeoPoint{}, err
+	}
+
+	point := models.NewGeoPoint(latitude, longitude)
+	if !point.Valid() {
+		return models.GeoPoint{}, errInvalidLocation
+	}
+	return point, nil
+}
+
+// findPartner loads a Partner by its ID.
+func findPartner(ctx context.Context, partnerId string) (models.Partner, error) {
+	var partner models.Partner
+
+	objId, err := primitive.ObjectIDFromHex(partnerId)
+	if err != nil {
+		return partner, err
+	}
+
+	err = partnerCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&partner)
+	return partner, err
+}
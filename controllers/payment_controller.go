@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/payments"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var paymentCollection *mongo.Collection = configs.GetCollection(configs.DB, "payments")
+var validatePayment = validator.New()
+
+// paymentRequest is the body accepted to pay an Appointment. The card token is only handed to the provider and never stored.
+type paymentRequest struct {
+	Method    models.PaymentMethod `json:"method,omitempty" validate:"required,oneof=cash card transfer"`
+	Kind      models.PaymentKind   `json:"kind,omitempty" validate:"required,oneof=deposit partial full"`
+	Amount    models.Money         `json:"amount" validate:"required"`
+	CardToken string               `json:"cardToken,omitempty"`
+}
+
+// captureRequest is the body accepted to capture a Payment. Only pending cancellation fees need it: they were never
+// authorized, so they are charged to the card given here.
+type captureRequest struct {
+	CardToken string `json:"cardToken,omitempty"`
+}
+
+// refundRequest is the body accepted to refund a Payment. When no amount is given the whole remaining amount is refunded.
+type refundRequest struct {
+	Amount *models.Money `json:"amount,omitempty"`
+}
+
+// Create a new Payment for an Appointment
+func CreatePayment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	var request paymentRequest
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validatePayment.Struct(&request); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if _, err := findAppointment(ctx, appointmentId); err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	provider, err := payments.ProviderFor(request.Method)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the payment method is not available.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	newPayment := models.Payment{
+		Id:             primitive.NewObjectID(),
+		AppointmentId:  appointmentId,
+		Method:         request.Method,
+		Kind:           request.Kind,
+		Amount:         request.Amount,
+		RefundedAmount: models.Zero(request.Amount.Currency),
+		Status:         models.PaymentStatusPending,
+		Provider:       provider.Name(),
+		CreationDate:   time.Now(),
+		UpdateDate:     time.Now(),
+	}
+
+	//the payment can't be bigger than what is still owed for the appointment, which is checked under the lock of its
+	//payments, and the pending payment is stored before calling the provider, so a crash never loses track of the money
+	var outstanding models.Money
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := lockAppointmentPayments(sc, appointmentId); err != nil {
+			return err
+		}
+
+		//the appointment is read again under the lock, as a cancellation changes what is owed
+		appointment, err := findAppointment(sc, appointmentId)
+		if err != nil {
+			return err
+		}
+		if appointment.Cancellation != nil && appointment.Cancellation.Fee.IsZero() {
+			return errNothingOwed
+		}
+
+		existing, err := findPayments(sc, appointmentId)
+		if err != nil {
+			return err
+		}
+		if outstanding, err = payments.Outstanding(owedAmount(appointment), existing); err != nil {
+			return err
+		}
+		if request.Amount.Currency != outstanding.Currency {
+			return models.ErrCurrencyMismatch
+		}
+		if request.Amount.Amount <= 0 || request.Amount.Amount > outstanding.Amount {
+			return errInvalidPayAmount
+		}
+
+		_, err = paymentCollection.InsertOne(sc, newPayment)
+		return err
+	})
+	if errors.Is(err, models.ErrCurrencyMismatch) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the payment currency does not match the appointment price.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if errors.Is(err, errNothingOwed) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the appointment was cancelled without a fee.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if errors.Is(err, errInvalidPayAmount) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the payment amount is invalid.", Data: &fiber.Map{"data": "The outstanding amount is " + outstanding.String() + "."}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Payment creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	result, err := provider.Authorize(ctx, newPayment, request.CardToken)
+	if err != nil {
+		result = payments.Result{Status: models.PaymentStatusFailed, FailureReason: err.Error()}
+	}
+
+	payment, err := applyPaymentResult(ctx, newPayment, result)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Payment creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if payment.Status == models.PaymentStatusFailed {
+		return c.Status(http.StatusPaymentRequired).JSON(responses.Response{Status: http.StatusPaymentRequired, Message: "Error: the payment was rejected.", Data: &fiber.Map{"data": payment}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Payment was created successfully.", Data: &fiber.Map{"data": payment}})
+}
+
+// Get a Payment
+func GetPayment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	paymentId := c.Params("paymentId")
+	defer cancel()
+
+	//validate if the payment ID exists
+	payment, err := findPayment(ctx, paymentId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid payment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": payment}})
+}
+
+// Get All Payments of an Appointment
+func GetAppointmentPayments(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	appointmentId := c.Params("appointmentId")
+	defer cancel()
+
+	appointmentPayments, err := findPayments(ctx, appointmentId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": appointmentPayments}},
+	)
+}
+
+// Capture an authorized Payment
+func CapturePayment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	paymentId := c.Params("paymentId")
+	defer cancel()
+
+	payment, err := findPayment(ctx, paymentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid payment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//cancellation fees are recorded as pending and charged by capturing them
+	capturable := payment.Status == models.PaymentStatusAuthorized || (payment.Status == models.PaymentStatusPending && payment.Kind == models.PaymentKindFee)
+	if !capturable {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only authorized payments and pending cancellation fees can be captured.", Data: &fiber.Map{"data": payment}})
+	}
+
+	provider, err := payments.ProviderFor(payment.Method)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the payment method is not available.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	var request captureRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	//the payment is claimed before calling the provider, so two captures at once can't both charge the card
+	claimed, err := paymentCollection.UpdateOne(ctx, bson.M{"id": payment.Id, "status": payment.Status}, bson.M{"$set": bson.M{"status": models.PaymentStatusProcessing, "updatedate": time.Now()}})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Payment capture process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if claimed.ModifiedCount != 1 {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the Payment is already being captured.", Data: &fiber.Map{"data": paymentId}})
+	}
+
+	//a cancellation fee is authorized first, it is only captured when the provider doesn't collect it right away
+	if payment.Status == models.PaymentStatusPending {
+		result, err := provider.Authorize(ctx, payment, request.CardToken)
+		if err != nil {
+			releasePayment(ctx, payment)
+			return c.Status(http.StatusBadGateway).JSON(responses.Response{Status: http.StatusBadGateway, Message: "Error: the Payment capture process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+		//a rejected card leaves the fee pending, so it can be charged to another one
+		if result.Status == models.PaymentStatusFailed {
+			releasePayment(ctx, payment)
+			return c.Status(http.StatusPaymentRequired).JSON(responses.Response{Status: http.StatusPaymentRequired, Message: "Error: the payment was rejected.", Data: &fiber.Map{"data": result.FailureReason}})
+		}
+
+		if result.Status == models.PaymentStatusCaptured {
+			payment, err = applyPaymentResult(ctx, payment, result)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Payment capture process failed.", Data: &fiber.Map{"data": err.Error()}})
+			}
+			return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Payment with the ID " + paymentId + " was captured correctly.", Data: &fiber.Map{"data": payment}})
+		}
+
+		//the authorization is stored while the payment stays claimed, so a failed capture leaves it authorized
+		payment.Status, payment.ProviderReference = result.Status, result.Reference
+		if _, err := paymentCollection.UpdateOne(ctx, bson.M{"id": payment.Id}, bson.M{"$set": bson.M{"providerreference": payment.ProviderReference}}); err != nil {
+			releasePayment(ctx, payment)
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Payment capture process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	result, err := provider.Capture(ctx, payment)
+	if err != nil {
+		releasePayment(ctx, payment)
+		return c.Status(http.StatusBadGateway).JSON(responses.Response{Status: http.StatusBadGateway, Message: "Error: the Payment capture process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	payment, err = applyPaymentResult(ctx, payment, result)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Payment capture process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Payment with the ID " + paymentId + " was captured correctly.", Data: &fiber.Map{"data": payment}})
+}
+
+// releasePayment gives back a payment claimed for a capture that didn't go through, in the status it had.
+func releasePayment(ctx context.Context, payment models.Payment) {
+	release := bson.M{"$set": bson.M{"status": payment.Status, "updatedate": time.Now()}}
+	if _, err := paymentCollection.UpdateOne(ctx, bson.M{"id": payment.Id, "status": models.PaymentStatusProcessing}, release); err != nil {
+		log.Println("payments: releasing payment", payment.Id.Hex(), "failed:", err)
+	}
+}
+
+// Refund a captured Payment, totally or partially
+func RefundPayment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	paymentId := c.Params("paymentId")
+	var request refundRequest
+	defer cancel()
+
+	//the body is optional, an empty one refunds everything
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	payment, err := findPayment(ctx, paymentId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid payment ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	payment, err = refundPayment(ctx, payment, request.Amount)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the Payment refund process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Payment with the ID " + paymentId + " was refunded correctly.", Data: &fiber.Map{"data": payment}})
+}
+
+// refundPayment gives back the given amount of a captured payment through its provider. A nil amount refunds what
+// was not refunded yet.
+func refundPayment(ctx context.Context, payment models.Payment, amount *models.Money) (models.Payment, error) {
+	if payment.Status != models.PaymentStatusCaptured {
+		return payment, errPaymentNotCaptured
+	}
+
+	refundable, err := payment.Amount.Sub(payment.RefundedAmount)
+	if err != nil {
+		return payment, err
+	}
+
+	refund := refundable
+	if amount != nil {
+		refund = *amount
+	}
+	if refund.Currency != refundable.Currency {
+		return payment, models.ErrCurrencyMismatch
+	}
+	if refund.Amount <= 0 || refund.Amount > refundable.Amount {
+		return payment, errInvalidRefundAmount
+	}
+
+	provider, err := payments.ProviderFor(payment.Method)
+	if err != nil {
+		return payment, err
+	}
+
+	//the refund is counted before calling the provider and only while it fits in what is left, so two refunds at once
+	//can't give back more than was paid
+	filter := bson.M{
+		"id":                      payment.Id,
+		"status":                  models.PaymentStatusCaptured,
+		"refundedamount.currency": refund.Currency,
+		"$expr":                   bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$refundedamount.amount", refund.Amount}}, "$amount.amount"}},
+	}
+	counted := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = paymentCollection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"refundedamount.amount": refund.Amount}}, counted).Decode(&payment)
+	if err == mongo.ErrNoDocuments {
+		return payment, errInvalidRefundAmount
+	}
+	if err != nil {
+		return payment, err
+	}
+
+	if _, err := provider.Refund(ctx, payment, refund); err != nil {
+		//nothing was given back, so the refund is no longer counted
+		if _, undoErr := paymentCollection.UpdateOne(ctx, bson.M{"id": payment.Id}, bson.M{"$inc": bson.M{"refundedamount.amount": -refund.Amount}}); undoErr != nil {
+			return payment, fmt.Errorf("%v, and the refund could not be uncounted: %w", err, undoErr)
+		}
+		return payment, err
+	}
+
+	//a partial refund keeps the payment captured for the remaining amount, only the one giving back the rest marks it
+	//as refunded
+	payment.UpdateDate = time.Now()
+	update := bson.M{"updatedate": payment.UpdateDate}
+	if payment.RefundedAmount.Amount >= payment.Amount.Amount {
+		payment.Status = models.PaymentStatusRefunded
+		update["status"] = payment.Status
+	}
+	if _, err := paymentCollection.UpdateOne(ctx, bson.M{"id": payment.Id}, bson.M{"$set": update}); err != nil {
+		return payment, err
+	}
+
+	return payment, refreshAppointmentPayments(ctx, payment.AppointmentId)
+}
+
+// applyPaymentResult stores what the provider answered and refreshes the payment summary of the appointment.
+func applyPaymentResult(ctx context.Context, payment models.Payment, result payments.Result) (models.Payment, error) {
+	payment.Status = result.Status
+	payment.FailureReason = result.FailureReason
+	payment.UpdateDate = time.Now()
+	if result.Reference != "" {
+		payment.ProviderReference = result.Reference
+	}
+
+	update := bson.M{"status": payment.Status, "failurereason": payment.FailureReason, "providerreference": payment.ProviderReference, "updatedate": payment.UpdateDate}
+	if _, err := paymentCollection.UpdateOne(ctx, bson.M{"id": payment.Id}, bson.M{"$set": update}); err != nil {
+		return payment, err
+	}
+
+	return payment, refreshAppointmentPayments(ctx, payment.AppointmentId)
+}
+
+// refreshAppointmentPayments recomputes how much of an appointment was paid from its payment records.
+func refreshAppointmentPayments(ctx context.Context, appointmentId string) error {
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	appointmentPayments, err := findPayments(ctx, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	paid, status, err := payments.Summarize(owedAmount(appointment), appointmentPayments)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"amountpaid": paid, "paymentstatus": status}
+	_, err = appointmentCollection.UpdateOne(ctx, bson.M{"id": appointment.Id}, bson.M{"$set": update})
+	return err
+}
+
+// owedAmount returns what the owner pays for an appointment: its price, or only the fee once it is cancelled.
+func owedAmount(appointment models.Appointment) models.Money {
+	if appointment.Cancellation != nil {
+		return appointment.Cancellation.Fee
+	}
+	return appointment.Pricing.Total
+}
+
+// findPayment loads a Payment by its ID.
+func findPayment(ctx context.Context, paymentId string) (models.Payment, error) {
+	var payment models.Payment
+
+	objId, err := primitive.ObjectIDFromHex(paymentId)
+	if err != nil {
+		return payment, err
+	}
+
+	err = paymentCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&payment)
+	return payment, err
+}
+
+// findPayments loads every Payment recorded for an Appointment, the newest first.
+func findPayments(ctx context.Context, appointmentId string) ([]models.Payment, error) {
+	var appointmentPayments []models.Payment
+
+	newestFirst := options.Find().SetSort(bson.D{{Key: "creationdate", Value: -1}, {Key: "id", Value: -1}})
+	results, err := paymentCollection.Find(ctx, bson.M{"appointmentid": appointmentId}, newestFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	err = results.All(ctx, &appointmentPayments)
+	return appointmentPayments, err
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/payouts"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var payoutCollection *mongo.Collection = configs.GetCollection(configs.DB, "payouts")
+var validatePayout = validator.New()
+
+// settlementRequest is the body accepted to run a settlement. Without a partner ID every partner is settled.
+type settlementRequest struct {
+	From      time.Time `json:"from" validate:"required"`
+	To        time.Time `json:"to" validate:"required,gtfield=From"`
+	PartnerId string    `json:"partnerId,omitempty"`
+}
+
+// markPaidRequest is the body accepted to mark a Payout as paid.
+type markPaidRequest struct {
+	PaymentReference string `json:"paymentReference,omitempty" validate:"required"`
+}
+
+// Run a settlement: group the completed and paid appointments of the period into draft Payouts
+func SettlePayouts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	var request settlementRequest
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validatePayout.Struct(&request); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	filter := bson.M{
+		"status":        models.AppointmentCompleted,
+		"paymentstatus": models.AppointmentPaid,
+		"starttime":     bson.M{"$gte": request.From, "$lt": request.To},
+		"payoutid":      bson.M{"$in": []interface{}{nil, ""}},
+	}
+	if request.PartnerId != "" {
+		filter["partnerid"] = request.PartnerId
+	}
+
+	partnerIds, err := appointmentCollection.Distinct(ctx, "partnerid", filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the settlement process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	var created []models.Payout
+	for _, partnerId := range partnerIds {
+		id, _ := partnerId.(string)
+		partnerPayouts, err := settlePartner(ctx, id, request.From, request.To, filter)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the settlement process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+		created = append(created, partnerPayouts...)
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The settlement was run successfully.", Data: &fiber.Map{"data": created}})
+}
+
+// settlePartner claims the pending appointments of a partner for new payouts, one per currency. Claiming marks the
+// appointments with the payout ID first, so two concurrent settlements never pay the same appointment twice, and the
+// payout is stored in the same transaction, so a failed settlement leaves the appointments to the next one.
+func settlePartner(ctx context.Context, partnerId string, from time.Time, to time.Time, filter bson.M) ([]models.Payout, error) {
+	partner, err := findPartner(ctx, partnerId)
+	if err != nil {
+		return nil, err
+	}
+	rule := partner.CommissionRule(configs.EnvCommissionBasisPoints())
+
+	partnerFilter := bson.M{"partnerid": partnerId}
+	for key, value := range filter {
+		partnerFilter[key] = value
+	}
+
+	currencies, err := appointmentCollection.Distinct(ctx, "pricing.total.currency", partnerFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []models.Payout
+	for _, currency := range currencies {
+		currencyFilter := bson.M{"pricing.total.currency": currency}
+		for key, value := range partnerFilter {
+			currencyFilter[key] = value
+		}
+
+		var payout models.Payout
+		err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+			payout = models.Payout{}
+			payoutId := primitive.NewObjectID()
+
+			if _, err := appointmentCollection.UpdateMany(sc, currencyFilter, bson.M{"$set": bson.M{"payoutid": payoutId.Hex()}}); err != nil {
+				return err
+			}
+
+			var appointments []models.Appointment
+			results, err := appointmentCollection.Find(sc, bson.M{"payoutid": payoutId.Hex()})
+			if err != nil {
+				return err
+			}
+			if err := results.All(sc, &appointments); err != nil {
+				return err
+			}
+			if len(appointments) == 0 {
+				return nil
+			}
+
+			if payout, err = payouts.Build(partnerId, from, to, rule, appointments); err != nil {
+				return err
+			}
+			payout.Id = payoutId
+			payout.CreationDate = time.Now()
+
+			_, err = payoutCollection.InsertOne(sc, payout)
+			return err
+		})
+		if err != nil {
+			return created, err
+		}
+		if !payout.Id.IsZero() {
+			created = append(created, payout)
+		}
+	}
+
+	return created, nil
+}
+
+// Get a Payout
+func GetPayout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	payoutId := c.Params("payoutId")
+	defer cancel()
+
+	//validate if the payout ID exists
+	payout, err := findPayout(ctx, payoutId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid payout ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": payout}})
+}
+
+// Get All Payouts, optionally filtered by partner and status
+func GetAllPayouts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var allPayouts []models.Payout
+	defer cancel()
+
+	filter := bson.M{}
+	if partnerId := c.Query("partnerId"); partnerId != "" {
+		filter["partnerid"] = partnerId
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	results, err := payoutCollection.Find(ctx, filter)
+
+	//validate if the context has a collection
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//reading from the db in an optimal way
+	defer results.Close(ctx)
+	for results.Next(ctx) {
+		var singlePayout models.Payout
+		if err = results.Decode(&singlePayout); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+
+		allPayouts = append(allPayouts, singlePayout)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": allPayouts}},
+	)
+}
+
+// Approve a draft Payout
+func ApprovePayout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	payoutId := c.Params("payoutId")
+	defer cancel()
+
+	now := time.Now()
+	return transitionPayout(ctx, c, payoutId, models.PayoutDraft, bson.M{"status": models.PayoutApproved, "approvaldate": now})
+}
+
+// Mark an approved Payout as paid
+func MarkPayoutPaid(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	payoutId := c.Params("payoutId")
+	var request markPaidRequest
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validatePayout.Struct(&request); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	now := time.Now()
+	return transitionPayout(ctx, c, payoutId, models.PayoutApproved, bson.M{"status": models.PayoutPaid, "paymentdate": now, "paymentreference": request.PaymentReference})
+}
+
+// Export a Payout as CSV
+func ExportPayoutCSV(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	payoutId := c.Params("payoutId")
+	defer cancel()
+
+	payout, err := findPayout(ctx, payoutId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid payout ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	var body bytes.Buffer
+	if err := payouts.WriteCSV(&body, payout); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Payout export process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="payout-`+payoutId+`.csv"`)
+	return c.Status(http.StatusOK).Send(body.Bytes())
+}
+
+// transitionPayout moves a payout from the expected status to a new one, answering with the updated payout.
+func transitionPayout(ctx context.Context, c *fiber.Ctx, payoutId string, from models.PayoutStatus, update bson.M) error {
+	objId, _ := primitive.ObjectIDFromHex(payoutId)
+
+	result, err := payoutCollection.UpdateOne(ctx, bson.M{"id": objId, "status": from}, bson.M{"$set": update})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Payout update process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	payout, err := findPayout(ctx, payoutId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid payout ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if result.MatchedCount == 0 {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the Payout must be " + string(from) + " for this operation.", Data: &fiber.Map{"data": payout}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Payout with the ID " + payoutId + " is now " + string(payout.Status) + ".", Data: &fiber.Map{"data": payout}})
+}
+
+// findPayout loads a Payout by its ID.
+func findPayout(ctx context.Context, payoutId string) (models.Payout, error) {
+	var payout models.Payout
+
+	objId, err := primitive.ObjectIDFromHex(payoutId)
+	if err != nil {
+		return payout, err
+	}
+
+	err = payoutCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&payout)
+	return payout, err
+}
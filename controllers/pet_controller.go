@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,12 +12,27 @@ import (
 	"pet-appointments-api/configs"
 	"pet-appointments-api/models"
 	"pet-appointments-api/responses"
+	"sort"
+	"strconv"
 	"time"
 )
 
 var petCollection *mongo.Collection = configs.GetCollection(configs.DB, "pets")
 var validatePet = validator.New()
 
+// dueVaccinationHorizon is how far ahead upcoming vaccinations are listed by default.
+const dueVaccinationHorizon = 30
+
+// dueVaccination is the current vaccination of a pet that is overdue or due soon.
+type dueVaccination struct {
+	PetId       string             `json:"petId"`
+	PetName     string             `json:"petName"`
+	OwnerId     string             `json:"ownerId"`
+	Status      string             `json:"status"`
+	DueDate     time.Time          `json:"dueDate"`
+	Vaccination models.Vaccination `json:"vaccination"`
+}
+
 // Create a new Pet
 func CreatePet(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -33,17 +49,35 @@ func CreatePet(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
+	//the species and breed must be in the catalog, and are stored by their codes
+	suggestions, err := classifyPet(ctx, c, &pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the species catalog could not be read.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if suggestions != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: unknown pet type or breed, did you mean one of these?", Data: &fiber.Map{"data": suggestions}})
+	}
+
 	newPet := models.Pet{
-		Id:           primitive.NewObjectID(),
-		OwnerId:      pet.OwnerId,
-		Name:         pet.Name,
-		Age:          pet.Age,
-		PetType:      pet.PetType,
-		Breed:        pet.Breed,
-		CreationDate: time.Now(),
+		Id:                 primitive.NewObjectID(),
+		OwnerId:            pet.OwnerId,
+		Name:               pet.Name,
+		BirthDate:          pet.BirthDate,
+		BirthDatePrecision: birthDatePrecision(pet.BirthDatePrecision),
+		PetType:            pet.PetType,
+		Breed:              pet.Breed,
+		CreationDate:       time.Now(),
 	}
 
-	result, err := petCollection.InsertOne(ctx, newPet)
+	//the Pet and its event are saved together
+	var result *mongo.InsertOneResult
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = petCollection.InsertOne(sc, newPet); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventPetCreated, newPet)
+	})
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Pet creation process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
@@ -66,7 +100,7 @@ func GetPet(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
-	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": pet}})
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": pet.WithAge(time.Now())}})
 }
 
 // Edit a Pet
@@ -88,25 +122,45 @@ func EditPet(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
 	}
 
-	update := bson.M{"ownerId": pet.OwnerId, "name": pet.Name, "age": pet.Age, "petType": pet.PetType, "breed": pet.Breed}
-
-	result, err := petCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+	//the species and breed must be in the catalog, and are stored by their codes
+	suggestions, err := classifyPet(ctx, c, &pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the species catalog could not be read.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if suggestions != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: unknown pet type or breed, did you mean one of these?", Data: &fiber.Map{"data": suggestions}})
+	}
 
+	//owners change through transfers, which the receiving owner accepts
+	current, err := findPet(ctx, petId)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Pet edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if pet.OwnerId != current.OwnerId {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the owner of a pet can't be edited, transfer the pet with POST /pet/" + petId + "/transfer instead.", Data: &fiber.Map{"data": current.OwnerId}})
 	}
 
-	//get updated owner details
+	update := bson.M{"name": pet.Name, "birthdate": pet.BirthDate, "birthdateprecision": birthDatePrecision(pet.BirthDatePrecision), "pettype": pet.PetType, "breed": pet.Breed}
+
 	var updatedPet models.Pet
-	if result.MatchedCount == 1 {
-		err := petCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedPet)
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := petCollection.UpdateOne(sc, bson.M{"id": objId}, bson.M{"$set": update})
+		if err != nil || result.MatchedCount != 1 {
+			return err
+		}
 
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Pet edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		//get updated pet details
+		if err := petCollection.FindOne(sc, bson.M{"id": objId}).Decode(&updatedPet); err != nil {
+			return err
 		}
+		return recordEvent(sc, models.EventPetUpdated, updatedPet)
+	})
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Pet edit process failed.", Data: &fiber.Map{"data": err.Error()}})
 	}
 
-	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Pet with the ID " + petId + " was edited correctly.", Data: &fiber.Map{"data": updatedPet}})
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Pet with the ID " + petId + " was edited correctly.", Data: &fiber.Map{"data": updatedPet.WithAge(time.Now())}})
 }
 
 // Delete a Pet
@@ -117,7 +171,14 @@ func DeletePet(c *fiber.Ctx) error {
 
 	objId, _ := primitive.ObjectIDFromHex(petId)
 
-	result, err := petCollection.DeleteOne(ctx, bson.M{"id": objId})
+	var result *mongo.DeleteResult
+	err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = petCollection.DeleteOne(sc, bson.M{"id": objId}); err != nil || result.DeletedCount < 1 {
+			return err
+		}
+		return recordEvent(sc, models.EventPetDeleted, fiber.Map{"id": petId})
+	})
 
 	//validate if the DeleteOne functions returns an Error
 	if err != nil {
@@ -136,13 +197,19 @@ func DeletePet(c *fiber.Ctx) error {
 	)
 }
 
-// Get All Pets
+// Get All Pets, optionally only the ones aged between ?minAge= and ?maxAge= years
 func GetAllPets(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	var pets []models.Pet
 	defer cancel()
 
-	results, err := petCollection.Find(ctx, bson.M{})
+	now := time.Now()
+	filter, err := ageFilter(c, now)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: minAge and maxAge must be whole years, with minAge not above maxAge.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	results, err := petCollection.Find(ctx, filter)
 
 	//validate if the context has a collection
 	if err != nil {
@@ -157,10 +224,255 @@ func GetAllPets(c *fiber.Ctx) error {
 			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
 		}
 
-		pets = append(pets, singlePet)
+		pets = append(pets, singlePet.WithAge(now))
 	}
 
 	return c.Status(http.StatusOK).JSON(
 		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": pets}},
 	)
 }
+
+// ageFilter turns the ?minAge= and ?maxAge= query parameters into a filter on the birth date. A pet is maxAge years
+// old until the day before it turns maxAge+1.
+func ageFilter(c *fiber.Ctx, now time.Time) (bson.M, error) {
+	birthDate := bson.M{}
+	minAge, maxAge := -1, -1
+
+	if value := c.Query("minAge"); value != "" {
+		age, err := strconv.Atoi(value)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("%w: minAge %q is not a whole number of years", errInvalidAgeRange, value)
+		}
+		minAge = age
+		birthDate["$lte"] = now.AddDate(-age, 0, 0)
+	}
+	if value := c.Query("maxAge"); value != "" {
+		age, err := strconv.Atoi(value)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("%w: maxAge %q is not a whole number of years", errInvalidAgeRange, value)
+		}
+		maxAge = age
+		birthDate["$gt"] = now.AddDate(-age-1, 0, 0)
+	}
+	if minAge >= 0 && maxAge >= 0 && minAge > maxAge {
+		return nil, fmt.Errorf("%w: minAge is above maxAge", errInvalidAgeRange)
+	}
+
+	if len(birthDate) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"birthdate": birthDate}, nil
+}
+
+// birthDatePrecision defaults to a birth date known to the day.
+func birthDatePrecision(precision models.BirthDatePrecision) models.BirthDatePrecision {
+	if precision == "" {
+		return models.BirthDateDay
+	}
+	return precision
+}
+
+// findPet loads a Pet by its ID.
+func findPet(ctx context.Context, petId string) (models.Pet, error) {
+	var pet models.Pet
+
+	objId, err := primitive.ObjectIDFromHex(petId)
+	if err != nil {
+		return pet, err
+	}
+
+	err = petCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&pet)
+	return pet, err
+}
+
+// Record a Vaccination given to a Pet, as a Partner who treated it
+func AddPetVaccination(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	var vaccination models.Vaccination
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partnerId, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can record its vaccinations.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&vaccination); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validatePet.Struct(&vaccination); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if vaccination.NextDueDate != nil && !vaccination.NextDueDate.After(vaccination.DateGiven) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the next dose must be due after the date it was given.", Data: &fiber.Map{"data": vaccination.NextDueDate}})
+	}
+
+	newVaccination := models.Vaccination{
+		Id:           primitive.NewObjectID(),
+		Vaccine:      vaccination.Vaccine,
+		Batch:        vaccination.Batch,
+		DateGiven:    vaccination.DateGiven,
+		PartnerId:    partnerId,
+		NextDueDate:  vaccination.NextDueDate,
+		CreationDate: time.Now(),
+	}
+
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := petCollection.UpdateOne(sc, bson.M{"id": pet.Id}, bson.M{"$push": bson.M{"vaccinations": newVaccination}}); err != nil {
+			return err
+		}
+		pet.Vaccinations = append(pet.Vaccinations, newVaccination)
+		return recordEvent(sc, models.EventPetUpdated, pet)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the vaccination could not be saved.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The vaccination was recorded successfully.", Data: &fiber.Map{"data": newVaccination}})
+}
+
+// Get the Vaccinations of a Pet, the most recent first
+func GetPetVaccinations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadMedicalRecord(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can see its vaccinations.", Data: &fiber.Map{"data": petId}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": sortedVaccinations(pet)}},
+	)
+}
+
+// Delete a Vaccination recorded by mistake
+func DeletePetVaccination(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	vaccinationId := c.Params("vaccinationId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	_, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can record its vaccinations.", Data: &fiber.Map{"data": petId}})
+	}
+
+	objId, _ := primitive.ObjectIDFromHex(vaccinationId)
+
+	var result *mongo.UpdateResult
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if result, err = petCollection.UpdateOne(sc, bson.M{"id": pet.Id}, bson.M{"$pull": bson.M{"vaccinations": bson.M{"id": objId}}}); err != nil || result.ModifiedCount < 1 {
+			return err
+		}
+
+		updatedPet, err := findPet(sc, petId)
+		if err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventPetUpdated, updatedPet)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if result.ModifiedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Vaccination with the ID " + vaccinationId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Vaccination was deleted successfully."}},
+	)
+}
+
+// Get the overdue vaccinations and the ones due in the next ?days= (30 by default) across all pets, optionally only
+// for the pets of an ?ownerId= or the doses given by a ?partnerId=
+func GetDueVaccinations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	days, err := strconv.Atoi(c.Query("days", strconv.Itoa(dueVaccinationHorizon)))
+	if err != nil || days < 0 {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: days must be a positive number.", Data: &fiber.Map{"data": c.Query("days")}})
+	}
+
+	now := time.Now()
+	horizon := now.AddDate(0, 0, days)
+
+	filter := bson.M{"vaccinations.nextduedate": bson.M{"$lte": horizon}}
+	if ownerId := c.Query("ownerId"); ownerId != "" {
+		filter["ownerid"] = ownerId
+	}
+
+	var pets []models.Pet
+	results, err := petCollection.Find(ctx, filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err := results.All(ctx, &pets); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//a dose given since makes the due date of the previous one irrelevant
+	due := []dueVaccination{}
+	for _, pet := range pets {
+		for _, vaccination := range pet.CurrentVaccinations() {
+			if vaccination.NextDueDate == nil || vaccination.NextDueDate.After(horizon) {
+				continue
+			}
+			if partnerId := c.Query("partnerId"); partnerId != "" && vaccination.PartnerId != partnerId {
+				continue
+			}
+
+			status := "upcoming"
+			if vaccination.NextDueDate.Before(now) {
+				status = "overdue"
+			}
+			due = append(due, dueVaccination{PetId: pet.Id.Hex(), PetName: pet.Name, OwnerId: pet.OwnerId, Status: status, DueDate: *vaccination.NextDueDate, Vaccination: vaccination})
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DueDate.Before(due[j].DueDate) })
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": due}},
+	)
+}
+
+// sortedVaccinations returns the vaccinations of a pet, the most recently given first.
+func sortedVaccinations(pet models.Pet) []models.Vaccination {
+	vaccinations := append([]models.Vaccination{}, pet.Vaccinations...)
+	sort.SliceStable(vaccinations, func(i, j int) bool { return vaccinations[i].DateGiven.After(vaccinations[j].DateGiven) })
+	return vaccinations
+}
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var petTransferCollection *mongo.Collection = configs.GetCollection(configs.DB, "petTransfers")
+var validatePetTransfer = validator.New()
+
+// petTransferTTL is how long the receiving owner has to accept a transfer.
+const petTransferTTL = 7 * 24 * time.Hour
+
+// transferredPet is what the pet.transferred event tells.
+type transferredPet struct {
+	Pet                    models.Pet         `json:"pet"`
+	Transfer               models.PetTransfer `json:"transfer"`
+	ReassignedAppointments int64              `json:"reassignedAppointments"`
+}
+
+// Ask to hand a Pet over to another Owner, as its current owner. The pet moves once the other owner accepts.
+func CreatePetTransfer(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	var transfer models.PetTransfer
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if ownerId := c.Get(headerOwnerId); ownerId == "" || ownerId != pet.OwnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet can transfer it.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&transfer); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validatePetTransfer.Struct(&transfer); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if transfer.ToOwnerId == pet.OwnerId {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the pet already belongs to that owner.", Data: &fiber.Map{"data": transfer.ToOwnerId}})
+	}
+
+	if _, err := findOwner(ctx, transfer.ToOwnerId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid owner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	now := time.Now()
+	newTransfer := models.PetTransfer{
+		Id:           primitive.NewObjectID(),
+		PetId:        petId,
+		FromOwnerId:  pet.OwnerId,
+		ToOwnerId:    transfer.ToOwnerId,
+		Reason:       transfer.Reason,
+		Status:       models.TransferPending,
+		ExpiresAt:    now.Add(petTransferTTL),
+		CreationDate: now,
+	}
+
+	//a pending transfer that expired doesn't keep the pet from being offered again
+	expirePetTransfers(ctx, petId, now)
+
+	//the unique index on pending transfers lets a pet have a single one at a time
+	if _, err := petTransferCollection.InsertOne(ctx, newTransfer); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the pet already has a pending transfer, cancel it first.", Data: &fiber.Map{"data": petId}})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Pet Transfer creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The transfer is waiting for the receiving owner to accept it until " + newTransfer.ExpiresAt.Format(time.RFC3339) + ".", Data: &fiber.Map{"data": newTransfer}})
+}
+
+// Get the transfers of a Pet, the most recent first, as its owner or one of the owners involved
+func GetPetTransfers(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	ownerId := c.Get(headerOwnerId)
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if ownerId == "" {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only owners can see the transfers of a pet.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//the current owner sees them all, anyone else only the ones they took part in
+	filter := bson.M{"petid": petId}
+	if ownerId != pet.OwnerId {
+		filter["$or"] = bson.A{bson.M{"fromownerid": ownerId}, bson.M{"toownerid": ownerId}}
+	}
+
+	transfers, err := findPetTransfers(ctx, filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": transfers}},
+	)
+}
+
+// Get the pending transfers offered to an Owner
+func GetOwnerPetTransfers(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ownerId := c.Params("ownerId")
+	defer cancel()
+
+	if c.Get(headerOwnerId) != ownerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: owners can only see the transfers offered to them.", Data: &fiber.Map{"data": ownerId}})
+	}
+
+	transfers, err := findPetTransfers(ctx, bson.M{"toownerid": ownerId, "status": models.TransferPending, "expiresat": bson.M{"$gt": time.Now()}})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": transfers}},
+	)
+}
+
+// Accept a transfer, as the receiving Owner. The pet, both owners' pet lists and its upcoming appointments move
+// together; past appointments stay with the owner who had the pet then.
+func AcceptPetTransfer(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	transferId := c.Params("transferId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	transfer, err := findPetTransfer(ctx, petId, transferId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid transfer ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if c.Get(headerOwnerId) != transfer.ToOwnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the receiving owner can accept a transfer.", Data: &fiber.Map{"data": transferId}})
+	}
+
+	now := time.Now()
+	var event transferredPet
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		//only a pending transfer of a pet still owned by whoever offered it goes through
+		update := bson.M{"$set": bson.M{"status": models.TransferAccepted, "decisiondate": now}}
+		filter := bson.M{"id": transfer.Id, "status": models.TransferPending, "expiresat": bson.M{"$gt": now}}
+		if err := petTransferCollection.FindOneAndUpdate(sc, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&event.Transfer); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errTransferUnavailable
+			}
+			return err
+		}
+
+		pastOwner := models.PastOwner{OwnerId: transfer.FromOwnerId, Until: now, TransferId: transfer.Id.Hex(), Reason: transfer.Reason}
+		petUpdate := bson.M{"$set": bson.M{"ownerid": transfer.ToOwnerId}, "$push": bson.M{"pastowners": pastOwner}}
+		err := petCollection.FindOneAndUpdate(sc, bson.M{"id": pet.Id, "ownerid": transfer.FromOwnerId}, petUpdate, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&event.Pet)
+		if err == mongo.ErrNoDocuments {
+			return errTransferUnavailable
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := movePetBetweenOwners(sc, petId, transfer.FromOwnerId, transfer.ToOwnerId); err != nil {
+			return err
+		}
+
+		//appointments still to come are the new owner's, the others remain the history of the previous one
+		filter = bson.M{"petid": petId, "ownerid": transfer.FromOwnerId, "status": models.AppointmentScheduled, "starttime": bson.M{"$gt": now}}
+		result, err := appointmentCollection.UpdateMany(sc, filter, bson.M{"$set": bson.M{"ownerid": transfer.ToOwnerId}})
+		if err != nil {
+			return err
+		}
+		event.ReassignedAppointments = result.ModifiedCount
+
+		return recordEvent(sc, models.EventPetTransferred, event)
+	})
+
+	if err == errTransferUnavailable {
+		expirePetTransfers(ctx, petId, now)
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the transfer is no longer pending, or the pet changed owner in the meantime.", Data: &fiber.Map{"data": transferId}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the transfer could not be completed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The pet was transferred successfully.", Data: &fiber.Map{"data": event}})
+}
+
+// Decline a transfer, as the receiving Owner
+func DeclinePetTransfer(c *fiber.Ctx) error {
+	return closePetTransfer(c, models.TransferDeclined)
+}
+
+// Cancel a transfer before it is accepted, as the Owner who offered it
+func CancelPetTransfer(c *fiber.Ctx) error {
+	return closePetTransfer(c, models.TransferCancelled)
+}
+
+// closePetTransfer ends a pending transfer without moving the pet. The receiving owner declines it, the one who
+// offered it cancels it.
+func closePetTransfer(c *fiber.Ctx, status models.PetTransferStatus) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	transferId := c.Params("transferId")
+	defer cancel()
+
+	transfer, err := findPetTransfer(ctx, petId, transferId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid transfer ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowedOwner := transfer.ToOwnerId
+	if status == models.TransferCancelled {
+		allowedOwner = transfer.FromOwnerId
+	}
+	if c.Get(headerOwnerId) != allowedOwner {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the receiving owner can decline a transfer and only the one who offered it can cancel it.", Data: &fiber.Map{"data": transferId}})
+	}
+
+	var closed models.PetTransfer
+	update := bson.M{"$set": bson.M{"status": status, "decisiondate": time.Now()}}
+	err = petTransferCollection.FindOneAndUpdate(ctx, bson.M{"id": transfer.Id, "status": models.TransferPending}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&closed)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the transfer is no longer pending.", Data: &fiber.Map{"data": transfer.Status}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The transfer was " + string(status) + ".", Data: &fiber.Map{"data": closed}})
+}
+
+// movePetBetweenOwners takes a pet out of the pet list of an owner and adds it to the list of another.
+func movePetBetweenOwners(ctx context.Context, petId string, fromOwnerId string, toOwnerId string) error {
+	if from, err := primitive.ObjectIDFromHex(fromOwnerId); err == nil {
+		if _, err := ownerCollection.UpdateOne(ctx, bson.M{"id": from}, bson.M{"$pull": bson.M{"pets": petId}}); err != nil {
+			return err
+		}
+	}
+
+	to, err := primitive.ObjectIDFromHex(toOwnerId)
+	if err != nil {
+		return err
+	}
+	_, err = ownerCollection.UpdateOne(ctx, bson.M{"id": to}, bson.M{"$addToSet": bson.M{"pets": petId}})
+	return err
+}
+
+// expirePetTransfers marks the pending transfers of a pet that were not accepted in time as expired.
+func expirePetTransfers(ctx context.Context, petId string, now time.Time) {
+	filter := bson.M{"petid": petId, "status": models.TransferPending, "expiresat": bson.M{"$lte": now}}
+	if _, err := petTransferCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"status": models.TransferExpired}}); err != nil {
+		log.Println("Error: the expired transfers of the pet " + petId + " could not be closed, " + err.Error())
+	}
+}
+
+// findPetTransfer loads a transfer of a pet by its ID.
+func findPetTransfer(ctx context.Context, petId string, transferId string) (models.PetTransfer, error) {
+	var transfer models.PetTransfer
+
+	objId, err := primitive.ObjectIDFromHex(transferId)
+	if err != nil {
+		return transfer, err
+	}
+
+	err = petTransferCollection.FindOne(ctx, bson.M{"id": objId, "petid": petId}).Decode(&transfer)
+	return transfer, err
+}
+
+// findPetTransfers loads the transfers matching a filter, the most recent first.
+func findPetTransfers(ctx context.Context, filter bson.M) ([]models.PetTransfer, error) {
+	results, err := petTransferCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "creationdate", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []models.PetTransfer{}
+	if err := results.All(ctx, &transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
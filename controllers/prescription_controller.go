@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/medication"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"sort"
+	"time"
+)
+
+var prescriptionCollection *mongo.Collection = configs.GetCollection(configs.DB, "prescriptions")
+
+// prescriptionView is a Prescription with what is computed from it.
+type prescriptionView struct {
+	models.Prescription
+	Active      bool      `json:"active"`
+	EndDate     time.Time `json:"endDate"`
+	RefillsLeft int       `json:"refillsLeft"`
+}
+
+// stopRequest is the body accepted to stop a Prescription early.
+type stopRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// Prescribe a medication to a Pet, as the Partner of the Appointment it was prescribed in
+func CreatePrescription(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	var prescription models.Prescription
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&prescription); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateMedicalRecord.Struct(&prescription); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	appointment, err := findAppointment(ctx, prescription.AppointmentId)
+	if err != nil || appointment.PetId != petId {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid appointment ID.", Data: &fiber.Map{"data": "Error: the pet has no appointment with the ID " + prescription.AppointmentId + "."}})
+	}
+
+	if c.Get(headerPartnerId) != appointment.PartnerId {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partner of the appointment can prescribe in it.", Data: &fiber.Map{"data": appointment.Id.Hex()}})
+	}
+
+	if appointment.Status == models.AppointmentCancelled || appointment.Status == models.AppointmentNoShow {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: nothing can be prescribed in a cancelled appointment.", Data: &fiber.Map{"data": appointment.Status}})
+	}
+
+	//the course starts with the appointment unless the partner says otherwise
+	if prescription.StartDate.IsZero() {
+		prescription.StartDate = appointment.StartTime
+	}
+
+	newPrescription := models.Prescription{
+		Id:             primitive.NewObjectID(),
+		PetId:          petId,
+		AppointmentId:  prescription.AppointmentId,
+		PartnerId:      appointment.PartnerId,
+		Drug:           prescription.Drug,
+		Dose:           prescription.Dose,
+		Frequency:      prescription.Frequency,
+		DurationDays:   prescription.DurationDays,
+		Instructions:   prescription.Instructions,
+		StartDate:      prescription.StartDate,
+		RefillsAllowed: prescription.RefillsAllowed,
+		CreationDate:   time.Now(),
+	}
+
+	if _, err := prescriptionCollection.InsertOne(ctx, newPrescription); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Prescription creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Prescription was created successfully.", Data: &fiber.Map{"data": viewPrescription(newPrescription, time.Now())}})
+}
+
+// Get the Prescriptions of a Pet, the most recent first, or only the ones being given with ?active=true
+func GetPetPrescriptions(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadMedicalRecord(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can see its prescriptions.", Data: &fiber.Map{"data": petId}})
+	}
+
+	var prescriptions []models.Prescription
+	if err := findPetEntries(ctx, prescriptionCollection, petId, "startdate", &prescriptions); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	now := time.Now()
+	views := []prescriptionView{}
+	for _, prescription := range prescriptions {
+		view := viewPrescription(prescription, now)
+		if c.QueryBool("active") && !view.Active {
+			continue
+		}
+		views = append(views, view)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": views}},
+	)
+}
+
+// Get a Prescription of a Pet
+func GetPetPrescription(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	prescriptionId := c.Params("prescriptionId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadMedicalRecord(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can see its prescriptions.", Data: &fiber.Map{"data": petId}})
+	}
+
+	prescription, err := findPrescription(ctx, petId, prescriptionId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid prescription ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": viewPrescription(prescription, time.Now())}})
+}
+
+// Get the doses of the active Prescriptions of a Pet between ?from= and ?to= (RFC 3339), the next week by default
+func GetPetMedicationSchedule(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	allowed, err := canReadMedicalRecord(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the owner of the pet and the partners who treated it can see its prescriptions.", Data: &fiber.Map{"data": petId}})
+	}
+
+	from, to, err := periodQuery(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid period, from and to must be RFC 3339 times.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	var prescriptions []models.Prescription
+	if err := findPetEntries(ctx, prescriptionCollection, petId, "startdate", &prescriptions); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//doses are given at local times of the partner who prescribed them
+	partners := map[string]*time.Location{}
+	doses := []medication.Dose{}
+	for _, prescription := range prescriptions {
+		location, ok := partners[prescription.PartnerId]
+		if !ok {
+			location = time.UTC
+			if partner, err := findPartner(ctx, prescription.PartnerId); err == nil {
+				location = partner.Location()
+			}
+			partners[prescription.PartnerId] = location
+		}
+		doses = append(doses, medication.Schedule(prescription, location, from, to)...)
+	}
+	sort.SliceStable(doses, func(i, j int) bool { return doses[i].Time.Before(doses[j].Time) })
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": fiber.Map{"from": from, "to": to, "doses": doses}}},
+	)
+}
+
+// Record a refill of a Prescription, as a Partner who treated the Pet
+func RefillPrescription(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	prescriptionId := c.Params("prescriptionId")
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	partnerId, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can refill its prescriptions.", Data: &fiber.Map{"data": petId}})
+	}
+
+	prescription, err := findPrescription(ctx, petId, prescriptionId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid prescription ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if prescription.StopDate != nil {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: a stopped prescription can't be refilled.", Data: &fiber.Map{"data": prescription.StopDate}})
+	}
+
+	//the refill only goes through while there are refills left, even with two partners refilling at once
+	refill := models.Refill{Date: time.Now(), PartnerId: partnerId}
+	filter := bson.M{"id": prescription.Id, "stopdate": nil, "$expr": bson.M{"$lt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$refills", bson.A{}}}}, "$refillsallowed"}}}
+
+	var refilled models.Prescription
+	err = prescriptionCollection.FindOneAndUpdate(ctx, filter, bson.M{"$push": bson.M{"refills": refill}}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&refilled)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the prescription has no refills left.", Data: &fiber.Map{"data": viewPrescription(prescription, time.Now())}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the refill could not be saved.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The prescription was refilled.", Data: &fiber.Map{"data": viewPrescription(refilled, time.Now())}})
+}
+
+// Stop a Prescription before its course ends, as a Partner who treated the Pet
+func StopPrescription(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	petId := c.Params("petId")
+	prescriptionId := c.Params("prescriptionId")
+	var request stopRequest
+	defer cancel()
+
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	_, allowed, err := medicalRecordAuthor(ctx, c, pet)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if !allowed {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only the partners who treated the pet can stop its prescriptions.", Data: &fiber.Map{"data": petId}})
+	}
+
+	//the body is optional
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	prescription, err := findPrescription(ctx, petId, prescriptionId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid prescription ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	now := time.Now()
+	if !medication.Active(prescription, now) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only active prescriptions can be stopped.", Data: &fiber.Map{"data": viewPrescription(prescription, now)}})
+	}
+
+	var stopped models.Prescription
+	update := bson.M{"$set": bson.M{"stopdate": now, "stopreason": request.Reason}}
+	err = prescriptionCollection.FindOneAndUpdate(ctx, bson.M{"id": prescription.Id, "stopdate": nil}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&stopped)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only active prescriptions can be stopped.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the prescription could not be stopped.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The prescription was stopped.", Data: &fiber.Map{"data": viewPrescription(stopped, now)}})
+}
+
+// findPrescription loads a Prescription of a pet by its ID.
+func findPrescription(ctx context.Context, petId string, prescriptionId string) (models.Prescription, error) {
+	var prescription models.Prescription
+
+	objId, err := primitive.ObjectIDFromHex(prescriptionId)
+	if err != nil {
+		return prescription, err
+	}
+
+	err = prescriptionCollection.FindOne(ctx, bson.M{"id": objId, "petid": petId}).Decode(&prescription)
+	return prescription, err
+}
+
+func viewPrescription(prescription models.Prescription, now time.Time) prescriptionView {
+	return prescriptionView{
+		Prescription: prescription,
+		Active:       medication.Active(prescription, now),
+		EndDate:      medication.End(prescription),
+		RefillsLeft:  medication.RefillsLeft(prescription),
+	}
+}
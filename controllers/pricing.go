@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"pet-appointments-api/models"
+	"pet-appointments-api/pricing"
+	"strings"
+	"time"
+)
+
+// priceAppointment computes the price breakdown of an appointment from the service catalog, the partner discounts,
+// the coupon given by the owner and the tax rates. The coupon is returned so the caller can redeem it once the
+// appointment is stored. redeemedCode is the coupon the stored appointment already redeemed, if any: while it is
+// kept, it isn't turned down for running out of uses, one of them is its own.
+func priceAppointment(ctx context.Context, appointment models.Appointment, service models.Service, redeemedCode string) (models.PriceBreakdown, *models.Coupon, error) {
+	partner, err := findPartner(ctx, appointment.PartnerId)
+	if err != nil {
+		return models.PriceBreakdown{}, nil, err
+	}
+
+	var coupon *models.Coupon
+	if appointment.CouponCode != "" {
+		found, err := findCouponByCode(ctx, appointment.CouponCode)
+		if err == mongo.ErrNoDocuments {
+			return models.PriceBreakdown{}, nil, fmt.Errorf("%w: unknown code %s", errInvalidCoupon, appointment.CouponCode)
+		}
+		if err != nil {
+			return models.PriceBreakdown{}, nil, err
+		}
+		check := found
+		if redeemedCode != "" && strings.EqualFold(redeemedCode, appointment.CouponCode) {
+			check.MaxRedemptions = 0
+		}
+		if err := check.Check(appointment.PartnerId, time.Now()); err != nil {
+			return models.PriceBreakdown{}, nil, fmt.Errorf("%w: %v", errInvalidCoupon, err)
+		}
+		coupon = &found
+	}
+
+	sameDayPets, err := countSameDayPets(ctx, appointment, partner.Location())
+	if err != nil {
+		return models.PriceBreakdown{}, nil, err
+	}
+
+	var taxRates []models.TaxRate
+	results, err := taxRateCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return models.PriceBreakdown{}, nil, err
+	}
+	if err := results.All(ctx, &taxRates); err != nil {
+		return models.PriceBreakdown{}, nil, err
+	}
+
+	breakdown, err := pricing.Calculate(pricing.Input{
+		Service:     service,
+		PartnerId:   appointment.PartnerId,
+		Region:      partner.Region,
+		Coupon:      coupon,
+		MultiPet:    partner.MultiPetDiscount,
+		SameDayPets: sameDayPets,
+		TaxRates:    taxRates,
+	})
+	if errors.Is(err, models.ErrCurrencyMismatch) {
+		err = fmt.Errorf("%w: %v", errInvalidCoupon, err)
+	}
+	return breakdown, coupon, err
+}
+
+// countSameDayPets counts the other pets of the owner booked with the same partner on the day of the appointment, in
+// the time zone of the partner.
+func countSameDayPets(ctx context.Context, appointment models.Appointment, location *time.Location) (int, error) {
+	start, end := pricing.Day(appointment.StartTime, location)
+
+	filter := bson.M{
+		"ownerid":   appointment.OwnerId,
+		"partnerid": appointment.PartnerId,
+		"petid":     bson.M{"$ne": appointment.PetId},
+		"starttime": bson.M{"$gte": start, "$lt": end},
+		"status":    bson.M{"$nin": []models.AppointmentStatus{models.AppointmentCancelled, models.AppointmentNoShow}},
+	}
+
+	pets, err := appointmentCollection.Distinct(ctx, "petid", filter)
+	return len(pets), err
+}
+
+// findCouponByCode loads a Coupon by its code, which is case-insensitive.
+func findCouponByCode(ctx context.Context, code string) (models.Coupon, error) {
+	var coupon models.Coupon
+	err := couponCollection.FindOne(ctx, bson.M{"code": strings.ToUpper(code)}).Decode(&coupon)
+	return coupon, err
+}
+
+// redeemCoupon counts one more use of the coupon, inside the transaction saving the booking, failing when it
+// reached its maximum number of redemptions in the meantime.
+func redeemCoupon(ctx context.Context, coupon *models.Coupon) error {
+	if coupon == nil {
+		return nil
+	}
+
+	filter := bson.M{"id": coupon.Id, "$or": []bson.M{{"maxredemptions": 0}, {"$expr": bson.M{"$lt": []string{"$redemptions", "$maxredemptions"}}}}}
+	result, err := couponCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"redemptions": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %v", errInvalidCoupon, models.ErrCouponExhausted)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"pet-appointments-api/models"
+	"pet-appointments-api/notifications"
+	"time"
+)
+
+// ReminderStore gives the reminder scheduler access to the appointments, owners, pets and partners.
+type ReminderStore struct{}
+
+func (ReminderStore) DueReminders(ctx context.Context, offset time.Duration, now time.Time) ([]notifications.Reminder, error) {
+	filter := bson.M{
+		"status":        bson.M{"$in": models.ScheduledStatuses},
+		"starttime":     bson.M{"$gt": now, "$lte": now.Add(offset)},
+		"reminderssent": bson.M{"$ne": offset.String()},
+	}
+
+	var appointments []models.Appointment
+	results, err := appointmentCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &appointments); err != nil {
+		return nil, err
+	}
+
+	var reminders []notifications.Reminder
+	for _, appointment := range appointments {
+		reminder := notifications.Reminder{Data: notifications.Data{Appointment: appointment}, Offset: offset}
+
+		//an appointment whose owner, pet or partner was deleted can't be reminded, but must not block the others
+		if reminder.Owner, err = findOwner(ctx, appointment.OwnerId); err != nil {
+			log.Println("reminders: owner of appointment", appointment.Id.Hex(), "not found:", err)
+			continue
+		}
+		if reminder.Pet, err = findPet(ctx, appointment.PetId); err != nil {
+			log.Println("reminders: pet of appointment", appointment.Id.Hex(), "not found:", err)
+			continue
+		}
+		if reminder.Partner, err = findPartner(ctx, appointment.PartnerId); err != nil {
+			log.Println("reminders: partner of appointment", appointment.Id.Hex(), "not found:", err)
+			continue
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, nil
+}
+
+func (ReminderStore) ClaimReminder(ctx context.Context, appointmentId string, offset time.Duration) (bool, error) {
+	appointment, err := findAppointment(ctx, appointmentId)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"id": appointment.Id, "reminderssent": bson.M{"$ne": offset.String()}}
+	result, err := appointmentCollection.UpdateOne(ctx, filter, bson.M{"$addToSet": bson.M{"reminderssent": offset.String()}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// VaccinationStore gives the vaccination reminder scheduler access to the pets, owners and partners.
+type VaccinationStore struct{}
+
+func (VaccinationStore) DueVaccinations(ctx context.Context, offset time.Duration, now time.Time) ([]notifications.VaccinationReminder, error) {
+	filter := bson.M{"vaccinations.nextduedate": bson.M{"$gt": now, "$lte": now.Add(offset)}}
+
+	var pets []models.Pet
+	results, err := petCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := results.All(ctx, &pets); err != nil {
+		return nil, err
+	}
+
+	var reminders []notifications.VaccinationReminder
+	for _, pet := range pets {
+		for _, vaccination := range pet.CurrentVaccinations() {
+			due := vaccination.NextDueDate
+			if due == nil || !due.After(now) || due.After(now.Add(offset)) || containsString(vaccination.RemindersSent, offset.String()) {
+				continue
+			}
+
+			vaccination := vaccination
+			reminder := notifications.VaccinationReminder{Data: notifications.Data{Pet: pet, Vaccination: &vaccination}, Offset: offset}
+
+			//a pet whose owner or partner was deleted can't be reminded, but must not block the others
+			if reminder.Owner, err = findOwner(ctx, pet.OwnerId); err != nil {
+				log.Println("reminders: owner of pet", pet.Id.Hex(), "not found:", err)
+				continue
+			}
+			if reminder.Partner, err = findPartner(ctx, vaccination.PartnerId); err != nil {
+				log.Println("reminders: partner of the vaccination", vaccination.Id.Hex(), "not found:", err)
+				continue
+			}
+
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	return reminders, nil
+}
+
+func (VaccinationStore) ClaimVaccinationReminder(ctx context.Context, petId string, vaccinationId string, offset time.Duration) (bool, error) {
+	pet, err := findPet(ctx, petId)
+	if err != nil {
+		return false, err
+	}
+	objId, err := primitive.ObjectIDFromHex(vaccinationId)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"id": pet.Id, "vaccinations": bson.M{"$elemMatch": bson.M{"id": objId, "reminderssent": bson.M{"$ne": offset.String()}}}}
+	result, err := petCollection.UpdateOne(ctx, filter, bson.M{"$addToSet": bson.M{"vaccinations.$.reminderssent": offset.String()}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
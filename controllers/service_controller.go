@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var serviceCollection *mongo.Collection = configs.GetCollection(configs.DB, "services")
+var validateService = validator.New()
+
+// Create a new Service in the catalog
+func CreateService(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var service models.Service
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&service); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateService.Struct(&service); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	newService := models.Service{
+		Id:              primitive.NewObjectID(),
+		Name:            service.Name,
+		Category:        service.Category,
+		DurationMinutes: service.DurationMinutes,
+		Price:           service.Price,
+		PartnerPrices:   service.PartnerPrices,
+		CreationDate:    time.Now(),
+	}
+
+	result, err := serviceCollection.InsertOne(ctx, newService)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Service creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Service was created successfully.", Data: &fiber.Map{"data": result}})
+}
+
+// Get a Service
+func GetService(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	serviceId := c.Params("serviceId")
+	defer cancel()
+
+	//validate if the service ID exists
+	service, err := findService(ctx, serviceId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid service ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": service}})
+}
+
+// Edit a Service
+func EditService(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	serviceId := c.Params("serviceId")
+	var service models.Service
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(serviceId)
+
+	//validate the request body
+	if err := c.BodyParser(&service); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateService.Struct(&service); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	update := bson.M{"name": service.Name, "category": service.Category, "durationminutes": service.DurationMinutes, "price": service.Price, "partnerprices": service.PartnerPrices}
+
+	result, err := serviceCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Service edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//get updated service details
+	var updatedService models.Service
+	if result.MatchedCount == 1 {
+		err := serviceCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedService)
+
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Service edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Service with the ID " + serviceId + " was edited correctly.", Data: &fiber.Map{"data": updatedService}})
+}
+
+// Delete a Service
+func DeleteService(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	serviceId := c.Params("serviceId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(serviceId)
+
+	result, err := serviceCollection.DeleteOne(ctx, bson.M{"id": objId})
+
+	//validate if the DeleteOne functions returns an Error
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no service with that ID. ", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//validate the ID number
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Service with the ID " + serviceId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Service was deleted successfully."}},
+	)
+}
+
+// Get All Services
+func GetAllServices(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var services []models.Service
+	defer cancel()
+
+	results, err := serviceCollection.Find(ctx, bson.M{})
+
+	//validate if the context has a collection
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//reading from the db in an optimal way
+	defer results.Close(ctx)
+	for results.Next(ctx) {
+		var singleService models.Service
+		if err = results.Decode(&singleService); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+
+		services = append(services, singleService)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": services}},
+	)
+}
+
+// findService loads a Service of the catalog by its ID.
+func findService(ctx context.Context, serviceId string) (models.Service, error) {
+	var service models.Service
+
+	objId, err := primitive.ObjectIDFromHex(serviceId)
+	if err != nil {
+		return service, err
+	}
+
+	err = serviceCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&service)
+	return service, err
+}
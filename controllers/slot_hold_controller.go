@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"strconv"
+	"time"
+)
+
+var slotHoldCollection *mongo.Collection = configs.GetCollection(configs.DB, "slotHolds")
+var validateSlotHold = validator.New()
+
+// slotHoldTTL is how long a hold reserves its slot.
+var slotHoldTTL = configs.EnvSlotHold()
+
+// slotHoldsPerOwner is how many slots an owner can hold at the same time, so no one holds a whole agenda.
+var slotHoldsPerOwner = configs.EnvSlotHoldsPerOwner()
+
+// Hold a Partner slot for a few minutes while the booking is filled in, as the owner making the call
+func CreateSlotHold(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var hold models.SlotHold
+	ownerId := c.Get(headerOwnerId)
+	defer cancel()
+
+	if ownerId == "" {
+		return c.Status(http.StatusForbidden).JSON(responses.Response{Status: http.StatusForbidden, Message: "Error: only owners can hold slots.", Data: &fiber.Map{"data": c.Get(headerPartnerId)}})
+	}
+
+	//validate the request body
+	if err := c.BodyParser(&hold); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateSlotHold.Struct(&hold); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	now := time.Now()
+	if !hold.StartTime.After(now) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: only future slots can be held.", Data: &fiber.Map{"data": hold.StartTime}})
+	}
+
+	if _, err := findPartner(ctx, hold.PartnerId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	service, err := findService(ctx, hold.ServiceId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid service ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	newHold := models.SlotHold{
+		Id:           primitive.NewObjectID(),
+		Token:        newHoldToken(),
+		OwnerId:      ownerId,
+		PartnerId:    hold.PartnerId,
+		ServiceId:    hold.ServiceId,
+		StartTime:    hold.StartTime,
+		EndTime:      hold.StartTime.Add(service.Duration()),
+		ExpiresAt:    now.Add(slotHoldTTL),
+		CreationDate: now,
+	}
+
+	//the slot is checked and held in one transaction, under the locks of the owner holds and the partner agenda
+	var conflicts []busyPeriod
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := lockOwnerHolds(sc, ownerId); err != nil {
+			return err
+		}
+		if err := lockPartnerAgenda(sc, newHold.PartnerId); err != nil {
+			return err
+		}
+
+		held, err := slotHoldCollection.CountDocuments(sc, bson.M{"ownerid": ownerId, "expiresat": bson.M{"$gt": now}})
+		if err != nil {
+			return err
+		}
+		if held >= int64(slotHoldsPerOwner) {
+			return errTooManyHolds
+		}
+
+		if conflicts, err = findConflicts(sc, newHold.PartnerId, newHold.StartTime, newHold.EndTime); err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return errSlotUnavailable
+		}
+
+		_, err = slotHoldCollection.InsertOne(sc, newHold)
+		return err
+	})
+	if errors.Is(err, errTooManyHolds) {
+		return c.Status(http.StatusTooManyRequests).JSON(responses.Response{Status: http.StatusTooManyRequests, Message: "Error: you can't hold more than " + strconv.Itoa(slotHoldsPerOwner) + " slots at the same time, book or release one first.", Data: &fiber.Map{"data": ownerId}})
+	}
+	if errors.Is(err, errSlotUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the partner is not available at that time.", Data: &fiber.Map{"data": conflicts}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the slot could not be held.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The slot is held until " + newHold.ExpiresAt.Format(time.RFC3339) + ", book it with the hold token.", Data: &fiber.Map{"data": newHold}})
+}
+
+// Get a SlotHold by its token
+func GetSlotHold(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	token := c.Params("token")
+	defer cancel()
+
+	hold, err := findSlotHold(ctx, token, time.Now())
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: the hold expired or does not exist.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": hold}})
+}
+
+// Release a SlotHold before it expires
+func DeleteSlotHold(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	token := c.Params("token")
+	defer cancel()
+
+	result, err := slotHoldCollection.DeleteOne(ctx, bson.M{"token": token})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the hold could not be released.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: the hold expired or does not exist."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The slot was released successfully."}},
+	)
+}
+
+// findSlotHold loads a SlotHold by its token, as long as it didn't expire. The TTL index removes expired holds
+// within a minute or so, until then they are ignored here.
+func findSlotHold(ctx context.Context, token string, now time.Time) (models.SlotHold, error) {
+	var hold models.SlotHold
+	err := slotHoldCollection.FindOne(ctx, bson.M{"token": token, "expiresat": bson.M{"$gt": now}}).Decode(&hold)
+	return hold, err
+}
+
+// consumeSlotHold deletes a hold as its slot is booked. It runs inside the transaction of the booking, so the slot
+// is either booked or still held.
+func consumeSlotHold(sc mongo.SessionContext, hold models.SlotHold, now time.Time) error {
+	result, err := slotHoldCollection.DeleteOne(sc, bson.M{"id": hold.Id, "expiresat": bson.M{"$gt": now}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount != 1 {
+		return errHoldUnavailable
+	}
+	return nil
+}
+
+// withoutHold leaves the slot of a hold out of the busy periods, so whoever holds it can book it.
+func withoutHold(busy []busyPeriod, hold models.SlotHold) []busyPeriod {
+	var periods []busyPeriod
+	for _, period := range busy {
+		if period.HoldId != hold.Id.Hex() {
+			periods = append(periods, period)
+		}
+	}
+	return periods
+}
+
+// newHoldToken returns a random token that can't be guessed from the other holds.
+func newHoldToken() string {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(token)
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"time"
+)
+
+var taxRateCollection *mongo.Collection = configs.GetCollection(configs.DB, "taxRates")
+var validateTaxRate = validator.New()
+
+// Create a new Tax Rate
+func CreateTaxRate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var taxRate models.TaxRate
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&taxRate); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateTaxRate.Struct(&taxRate); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	newTaxRate := models.TaxRate{
+		Id:           primitive.NewObjectID(),
+		Name:         taxRate.Name,
+		Category:     taxRate.Category,
+		Region:       taxRate.Region,
+		BasisPoints:  taxRate.BasisPoints,
+		CreationDate: time.Now(),
+	}
+
+	result, err := taxRateCollection.InsertOne(ctx, newTaxRate)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Tax Rate creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Tax Rate was created successfully.", Data: &fiber.Map{"data": result}})
+}
+
+// Get a Tax Rate
+func GetTaxRate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	taxRateId := c.Params("taxRateId")
+	defer cancel()
+
+	//validate if the tax rate ID exists
+	taxRate, err := findTaxRate(ctx, taxRateId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid tax rate ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": taxRate}})
+}
+
+// Edit a Tax Rate
+func EditTaxRate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	taxRateId := c.Params("taxRateId")
+	var taxRate models.TaxRate
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(taxRateId)
+
+	//validate the request body
+	if err := c.BodyParser(&taxRate); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateTaxRate.Struct(&taxRate); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	update := bson.M{"name": taxRate.Name, "category": taxRate.Category, "region": taxRate.Region, "basispoints": taxRate.BasisPoints}
+
+	result, err := taxRateCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Tax Rate edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//get updated tax rate details
+	var updatedTaxRate models.TaxRate
+	if result.MatchedCount == 1 {
+		err := taxRateCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedTaxRate)
+
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Tax Rate edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Tax Rate with the ID " + taxRateId + " was edited correctly.", Data: &fiber.Map{"data": updatedTaxRate}})
+}
+
+// Delete a Tax Rate
+func DeleteTaxRate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	taxRateId := c.Params("taxRateId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(taxRateId)
+
+	result, err := taxRateCollection.DeleteOne(ctx, bson.M{"id": objId})
+
+	//validate if the DeleteOne functions returns an Error
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no tax rate with that ID. ", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//validate the ID number
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Tax Rate with the ID " + taxRateId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Tax Rate was deleted successfully."}},
+	)
+}
+
+// Get All Tax Rates
+func GetAllTaxRates(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var taxRates []models.TaxRate
+	defer cancel()
+
+	results, err := taxRateCollection.Find(ctx, bson.M{})
+
+	//validate if the context has a collection
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//reading from the db in an optimal way
+	defer results.Close(ctx)
+	for results.Next(ctx) {
+		var singleTaxRate models.TaxRate
+		if err = results.Decode(&singleTaxRate); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+
+		taxRates = append(taxRates, singleTaxRate)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": taxRates}},
+	)
+}
+
+// findTaxRate loads a Tax Rate by its ID.
+func findTaxRate(ctx context.Context, taxRateId string) (models.TaxRate, error) {
+	var taxRate models.TaxRate
+
+	objId, err := primitive.ObjectIDFromHex(taxRateId)
+	if err != nil {
+		return taxRate, err
+	}
+
+	err = taxRateCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&taxRate)
+	return taxRate, err
+}
@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/notifications"
+	"pet-appointments-api/responses"
+	"pet-appointments-api/waitlist"
+	"time"
+)
+
+var waitlistCollection *mongo.Collection = configs.GetCollection(configs.DB, "waitlist")
+var validateWaitlistEntry = validator.New()
+
+// waitlistHold is how long a freed slot is held for a waitlisted owner.
+var waitlistHold = configs.EnvWaitlistHold()
+
+// Join the waitlist of a Partner for a service and date range
+func JoinWaitlist(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var entry models.WaitlistEntry
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&entry); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateWaitlistEntry.Struct(&entry); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if !entry.To.After(time.Now()) {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the date range is already over.", Data: &fiber.Map{"data": entry.To}})
+	}
+
+	if _, err := findOwner(ctx, entry.OwnerId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid owner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if _, err := findPet(ctx, entry.PetId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid pet ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if _, err := findPartner(ctx, entry.PartnerId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if _, err := findService(ctx, entry.ServiceId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid service ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	newEntry := models.WaitlistEntry{
+		Id:           primitive.NewObjectID(),
+		OwnerId:      entry.OwnerId,
+		PetId:        entry.PetId,
+		PartnerId:    entry.PartnerId,
+		ServiceId:    entry.ServiceId,
+		PaymentType:  entry.PaymentType,
+		From:         entry.From,
+		To:           entry.To,
+		Status:       models.WaitlistWaiting,
+		CreationDate: time.Now(),
+	}
+
+	if _, err := waitlistCollection.InsertOne(ctx, newEntry); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the waitlist could not be joined.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The waitlist was joined successfully.", Data: &fiber.Map{"data": newEntry}})
+}
+
+// Get a WaitlistEntry
+func GetWaitlistEntry(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	entryId := c.Params("entryId")
+	defer cancel()
+
+	entry, err := findWaitlistEntry(ctx, entryId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid waitlist entry ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": entry}})
+}
+
+// Get the waitlist of a Partner in the order slots are offered, optionally filtered by ?status=
+func GetPartnerWaitlist(c *fiber.Ctx) error {
+	return listWaitlist(c, bson.M{"partnerid": c.Params("partnerId")})
+}
+
+// Get the WaitlistEntries of an Owner, optionally filtered by ?status=
+func GetOwnerWaitlist(c *fiber.Ctx) error {
+	return listWaitlist(c, bson.M{"ownerid": c.Params("ownerId")})
+}
+
+func listWaitlist(c *fiber.Ctx, filter bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var entries []models.WaitlistEntry
+	defer cancel()
+
+	if status := c.Query("status"); status != "" {
+		filter["status"] = models.WaitlistStatus(status)
+	} else {
+		filter["status"] = bson.M{"$in": []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}}
+	}
+
+	results, err := waitlistCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"creationdate": 1}))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if err := results.All(ctx, &entries); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": entries}},
+	)
+}
+
+// Leave the waitlist, passing an offered slot on to the next owner
+func LeaveWaitlist(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	entryId := c.Params("entryId")
+	defer cancel()
+
+	entry, err := findWaitlistEntry(ctx, entryId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid waitlist entry ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	left, err := releaseWaitlistEntry(ctx, entry, models.WaitlistLeft, time.Now())
+	if errors.Is(err, errOfferUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: only waiting entries can leave the waitlist.", Data: &fiber.Map{"data": entry}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the waitlist could not be left.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The waitlist was left successfully.", Data: &fiber.Map{"data": left}})
+}
+
+// Decline the slot offered to a WaitlistEntry, which stays in the waitlist for the next one
+func DeclineWaitlistOffer(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	entryId := c.Params("entryId")
+	defer cancel()
+
+	entry, err := findWaitlistEntry(ctx, entryId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid waitlist entry ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if entry.Status != models.WaitlistOffered {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: there is no offer to decline.", Data: &fiber.Map{"data": entry}})
+	}
+
+	declined, err := releaseWaitlistEntry(ctx, entry, models.WaitlistWaiting, time.Now())
+	if errors.Is(err, errOfferUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: there is no offer to decline.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the offer could not be declined.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The offer was declined.", Data: &fiber.Map{"data": declined}})
+}
+
+// Accept the slot offered to a WaitlistEntry, booking the Appointment
+func AcceptWaitlistOffer(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	entryId := c.Params("entryId")
+	defer cancel()
+
+	entry, err := findWaitlistEntry(ctx, entryId)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(responses.Response{Status: http.StatusNotFound, Message: "Error: invalid waitlist entry ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	now := time.Now()
+	if entry.Status != models.WaitlistOffered || entry.Offer == nil || !now.Before(entry.Offer.ExpiresAt) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the offer expired or was already answered.", Data: &fiber.Map{"data": entry}})
+	}
+
+	service, err := findService(ctx, entry.ServiceId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid service ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	appointment := waitlistAppointment(entry, service)
+
+	breakdown, _, err := priceAppointment(ctx, appointment, service, "")
+	if err != nil {
+		return pricingError(c, err)
+	}
+	newAppointment := scheduledAppointment(appointment, service, breakdown)
+
+	//the entry is only booked once, even when the owner accepts twice
+	var conflicts []busyPeriod
+	err = withTransaction(ctx, func(sc mongo.SessionContext) error {
+		//the offer holds the slot, but the partner may have blocked it in their own calendar since
+		conflicts = nil
+		if err := lockPartnerAgenda(sc, entry.PartnerId); err != nil {
+			return err
+		}
+		busy, err := findConflicts(sc, entry.PartnerId, appointment.StartTime, appointment.StartTime.Add(service.Duration()))
+		if err != nil {
+			return err
+		}
+		for _, period := range busy {
+			if period.WaitlistEntryId != entryId {
+				conflicts = append(conflicts, period)
+			}
+		}
+		if len(conflicts) > 0 {
+			return errSlotUnavailable
+		}
+
+		filter := bson.M{"id": entry.Id, "status": models.WaitlistOffered, "offer.expiresat": bson.M{"$gt": now}}
+		update := bson.M{"status": models.WaitlistBooked, "appointmentid": newAppointment.Id.Hex(), "updatedate": now}
+		result, err := waitlistCollection.UpdateOne(sc, filter, bson.M{"$set": update})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount != 1 {
+			return errOfferUnavailable
+		}
+
+		if _, err := appointmentCollection.InsertOne(sc, newAppointment); err != nil {
+			return err
+		}
+		return recordEvent(sc, models.EventAppointmentCreated, newAppointment)
+	})
+	if errors.Is(err, errSlotUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the partner is not available at that time.", Data: &fiber.Map{"data": conflicts}})
+	}
+	if errors.Is(err, errOfferUnavailable) {
+		return c.Status(http.StatusConflict).JSON(responses.Response{Status: http.StatusConflict, Message: "Error: the offer expired or was already answered.", Data: &fiber.Map{"data": err.Error()}})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the Appointment creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	notifyAppointment(ctx, models.EventBookingConfirmed, newAppointment, nil)
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "The offer was accepted and the Appointment was booked.", Data: &fiber.Map{"data": newAppointment}})
+}
+
+// findWaitlistEntry loads a WaitlistEntry by its ID.
+func findWaitlistEntry(ctx context.Context, entryId string) (models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+
+	objId, err := primitive.ObjectIDFromHex(entryId)
+	if err != nil {
+		return entry, err
+	}
+
+	err = waitlistCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&entry)
+	return entry, err
+}
+
+// waitlistAppointment is the Appointment an entry gets when it accepts its offer.
+func waitlistAppointment(entry models.WaitlistEntry, service models.Service) models.Appointment {
+	return models.Appointment{
+		OwnerId:     entry.OwnerId,
+		PetId:       entry.PetId,
+		PartnerId:   entry.PartnerId,
+		ServiceId:   entry.ServiceId,
+		Service:     service.Name,
+		PaymentType: entry.PaymentType,
+		StartTime:   entry.Offer.Start,
+		EndTime:     entry.Offer.Start.Add(service.Duration()),
+	}
+}
+
+// offerFreedSlot offers the time a cancelled appointment took to the waitlist of its partner. Like notifications, a
+// failure is only logged, it never fails the cancellation.
+func offerFreedSlot(ctx context.Context, appointment models.Appointment, now time.Time) {
+	slot := models.WaitlistOffer{Start: appointment.StartTime, End: appointment.EndTime, FreedBy: appointment.Id.Hex()}
+	if err := offerSlot(ctx, appointment.PartnerId, slot, time.Time{}, now); err != nil {
+		log.Println("waitlist: offering the slot of appointment", appointment.Id.Hex(), "failed:", err)
+	}
+}
+
+// offerSlot offers a slot to the first waiting entry created after the given time whose date range and service fit
+// in it, holding the slot for them. Nothing is offered once the slot started or when it was booked in the meantime.
+func offerSlot(ctx context.Context, partnerId string, slot models.WaitlistOffer, after time.Time, now time.Time) error {
+	if !slot.Start.After(now) {
+		return nil
+	}
+
+	busy, err := partnerBusyPeriods(ctx, partnerId, slot.Start, slot.End)
+	if err != nil || len(busy) > 0 {
+		return err
+	}
+
+	filter := bson.M{
+		"partnerid":    partnerId,
+		"status":       models.WaitlistWaiting,
+		"from":         bson.M{"$lte": slot.Start},
+		"to":           bson.M{"$gt": slot.Start},
+		"creationdate": bson.M{"$gt": after},
+	}
+
+	var entries []models.WaitlistEntry
+	results, err := waitlistCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"creationdate": 1}))
+	if err != nil {
+		return err
+	}
+	if err := results.All(ctx, &entries); err != nil {
+		return err
+	}
+
+	services := map[string]models.Service{}
+	for _, entry := range entries {
+		service, ok := services[entry.ServiceId]
+		if !ok {
+			if service, err = findService(ctx, entry.ServiceId); err != nil {
+				continue
+			}
+			services[entry.ServiceId] = service
+		}
+		if !waitlist.Fits(entry, slot, service.Duration()) {
+			continue
+		}
+
+		offer := slot
+		offer.OfferDate = now
+		offer.ExpiresAt = now.Add(waitlistHold)
+
+		//the entry may have left or been offered another slot since it was read
+		var offered models.WaitlistEntry
+		err := waitlistCollection.FindOneAndUpdate(ctx, bson.M{"id": entry.Id, "status": models.WaitlistWaiting},
+			bson.M{"$set": bson.M{"status": models.WaitlistOffered, "offer": offer, "updatedate": now}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&offered)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		notifyWaitlistOffer(ctx, offered, service)
+		return nil
+	}
+	return nil
+}
+
+// releaseWaitlistEntry moves an entry to the given status, passing the slot it was offered on to the next entries
+// of the waitlist.
+func releaseWaitlistEntry(ctx context.Context, entry models.WaitlistEntry, status models.WaitlistStatus, now time.Time) (models.WaitlistEntry, error) {
+	if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistOffered {
+		return entry, errOfferUnavailable
+	}
+
+	//an offer answered in the meantime, or replaced by a newer one, is left alone
+	filter := bson.M{"id": entry.Id, "status": entry.Status}
+	if entry.Offer != nil {
+		filter["offer.expiresat"] = entry.Offer.ExpiresAt
+	}
+
+	update := bson.M{"$set": bson.M{"status": status, "updatedate": now}, "$unset": bson.M{"offer": ""}}
+	if entry.Status == models.WaitlistOffered && status == models.WaitlistWaiting {
+		update["$inc"] = bson.M{"offersmissed": 1}
+	}
+
+	var released models.WaitlistEntry
+	err := waitlistCollection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&released)
+	if err == mongo.ErrNoDocuments {
+		return entry, errOfferUnavailable
+	}
+	if err != nil {
+		return entry, err
+	}
+
+	if entry.Offer != nil {
+		if err := offerSlot(ctx, entry.PartnerId, *entry.Offer, entry.CreationDate, now); err != nil {
+			log.Println("waitlist: passing the offer of", entry.Id.Hex(), "on failed:", err)
+		}
+	}
+	return released, nil
+}
+
+// notifyWaitlistOffer tells the owner of an entry about the slot it was offered.
+func notifyWaitlistOffer(ctx context.Context, entry models.WaitlistEntry, service models.Service) {
+	data, err := notificationData(ctx, waitlistAppointment(entry, service))
+	if err != nil {
+		log.Println("notifications:", models.EventWaitlistOffer, "for waitlist entry", entry.Id.Hex(), "skipped:", err)
+		return
+	}
+
+	data.Offer = entry.Offer
+	notifications.Notify(models.EventWaitlistOffer, data)
+}
+
+// WaitlistStore is the MongoDB backed waitlist.EntryStore.
+type WaitlistStore struct{}
+
+func (WaitlistStore) ExpiredOffers(ctx context.Context, now time.Time, limit int) ([]models.WaitlistEntry, error) {
+	filter := bson.M{"status": models.WaitlistOffered, "offer.expiresat": bson.M{"$lte": now}}
+
+	var entries []models.WaitlistEntry
+	results, err := waitlistCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"offer.expiresat": 1}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	err = results.All(ctx, &entries)
+	return entries, err
+}
+
+func (WaitlistStore) ExpireOffer(ctx context.Context, entry models.WaitlistEntry, now time.Time) error {
+	if _, err := releaseWaitlistEntry(ctx, entry, models.WaitlistWaiting, now); err != nil && !errors.Is(err, errOfferUnavailable) {
+		return err
+	}
+	return nil
+}
+
+func (WaitlistStore) CloseEntries(ctx context.Context, now time.Time) (int64, error) {
+	filter := bson.M{"status": models.WaitlistWaiting, "to": bson.M{"$lte": now}}
+	result, err := waitlistCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"status": models.WaitlistExpired, "updatedate": now}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
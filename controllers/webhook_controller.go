@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"pet-appointments-api/responses"
+	"pet-appointments-api/safehttp"
+	"pet-appointments-api/webhooks"
+	"time"
+)
+
+var webhookCollection *mongo.Collection = configs.GetCollection(configs.DB, "webhookSubscriptions")
+var webhookDeliveryCollection *mongo.Collection = configs.GetCollection(configs.DB, "webhookDeliveries")
+var validateWebhook = validator.New()
+
+// Create a new Webhook
+func CreateWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var webhook models.WebhookSubscription
+	defer cancel()
+
+	//validate the request body
+	if err := c.BodyParser(&webhook); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateWebhook.Struct(&webhook); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if _, err := findPartner(ctx, webhook.PartnerId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the platform must not be made to call itself or the services of its private network
+	if err := safehttp.CheckURL(ctx, webhook.URL); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the webhook URL can't be called.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//a secret is generated when none was chosen, it is only shown in this response
+	if webhook.Secret == "" {
+		webhook.Secret = webhooks.NewSecret()
+	}
+
+	newWebhook := models.WebhookSubscription{
+		Id:           primitive.NewObjectID(),
+		PartnerId:    webhook.PartnerId,
+		URL:          webhook.URL,
+		Events:       webhook.Events,
+		Secret:       webhook.Secret,
+		Disabled:     webhook.Disabled,
+		CreationDate: time.Now(),
+	}
+
+	result, err := webhookCollection.InsertOne(ctx, newWebhook)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Webhook creation process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusCreated).JSON(responses.Response{Status: http.StatusCreated, Message: "A new Webhook was created successfully.", Data: &fiber.Map{"data": result, "secret": newWebhook.Secret}})
+}
+
+// Get a Webhook
+func GetWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	webhookId := c.Params("webhookId")
+	defer cancel()
+
+	//validate if the webhook ID exists
+	webhook, err := findWebhook(ctx, webhookId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid webhook ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": redactSecret(webhook)}})
+}
+
+// Edit a Webhook
+func EditWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	webhookId := c.Params("webhookId")
+	var webhook models.WebhookSubscription
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(webhookId)
+
+	//validate the request body
+	if err := c.BodyParser(&webhook); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//use the validator library to validate required fields
+	if validationErr := validateWebhook.Struct(&webhook); validationErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: some fields could be invalid.", Data: &fiber.Map{"data": validationErr.Error()}})
+	}
+
+	if _, err := findPartner(ctx, webhook.PartnerId); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: invalid partner ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//the platform must not be made to call itself or the services of its private network
+	if err := safehttp.CheckURL(ctx, webhook.URL); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the webhook URL can't be called.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	update := bson.M{"partnerid": webhook.PartnerId, "url": webhook.URL, "events": webhook.Events, "disabled": webhook.Disabled}
+	if webhook.Secret != "" {
+		update["secret"] = webhook.Secret
+	}
+
+	result, err := webhookCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Webhook edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//get updated webhook details
+	var updatedWebhook models.WebhookSubscription
+	if result.MatchedCount == 1 {
+		err := webhookCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedWebhook)
+
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: The Webhook edit process failed.", Data: &fiber.Map{"data": err.Error()}})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The Webhook with the ID " + webhookId + " was edited correctly.", Data: &fiber.Map{"data": redactSecret(updatedWebhook)}})
+}
+
+// Delete a Webhook
+func DeleteWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	webhookId := c.Params("webhookId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(webhookId)
+
+	result, err := webhookCollection.DeleteOne(ctx, bson.M{"id": objId})
+
+	//validate if the DeleteOne functions returns an Error
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: There is no webhook with that ID. ", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//validate the ID number
+	if result.DeletedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Webhook with the ID " + webhookId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": "The Webhook was deleted successfully."}},
+	)
+}
+
+// Get All Webhooks
+func GetAllWebhooks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var subscriptions []models.WebhookSubscription
+	defer cancel()
+
+	results, err := webhookCollection.Find(ctx, bson.M{})
+
+	//validate if the context has a collection
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	//reading from the db in an optimal way
+	defer results.Close(ctx)
+	for results.Next(ctx) {
+		var singleWebhook models.WebhookSubscription
+		if err = results.Decode(&singleWebhook); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+		}
+
+		subscriptions = append(subscriptions, redactSecret(singleWebhook))
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": subscriptions}},
+	)
+}
+
+// findWebhook loads a Webhook by its ID.
+func findWebhook(ctx context.Context, webhookId string) (models.WebhookSubscription, error) {
+	var webhook models.WebhookSubscription
+
+	objId, err := primitive.ObjectIDFromHex(webhookId)
+	if err != nil {
+		return webhook, err
+	}
+
+	err = webhookCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&webhook)
+	return webhook, err
+}
+
+// Get the Deliveries of a Webhook, optionally filtered by status
+func GetWebhookDeliveries(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	webhookId := c.Params("webhookId")
+	defer cancel()
+
+	filter := bson.M{"subscriptionid": webhookId}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	return listWebhookDeliveries(ctx, c, filter)
+}
+
+// Get the dead letters: the Deliveries that ran out of attempts
+func GetDeadWebhookDeliveries(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return listWebhookDeliveries(ctx, c, bson.M{"status": models.WebhookDead})
+}
+
+// Get a Webhook Delivery with its attempts log
+func GetWebhookDelivery(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	deliveryId := c.Params("deliveryId")
+	var delivery models.WebhookDelivery
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(deliveryId)
+
+	//validate if the delivery ID exists
+	err := webhookDeliveryCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&delivery)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: invalid delivery ID.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.Response{Status: http.StatusOK, Message: "The operation was successfully.", Data: &fiber.Map{"data": delivery}})
+}
+
+// Redeliver a Webhook Delivery, giving it a fresh set of attempts
+func RedeliverWebhookDelivery(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	deliveryId := c.Params("deliveryId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(deliveryId)
+
+	update := bson.M{"status": models.WebhookPending, "attemptcount": 0, "nextattempt": time.Now(), "updatedate": time.Now()}
+	result, err := webhookDeliveryCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error: the redelivery process failed.", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if result.MatchedCount < 1 {
+		return c.Status(http.StatusNotFound).JSON(
+			responses.Response{Status: http.StatusNotFound, Message: "Error", Data: &fiber.Map{"data": "Error: The Delivery with the ID " + deliveryId + " does not exists."}},
+		)
+	}
+
+	return c.Status(http.StatusAccepted).JSON(responses.Response{Status: http.StatusAccepted, Message: "The Delivery with the ID " + deliveryId + " was queued for redelivery.", Data: &fiber.Map{"data": deliveryId}})
+}
+
+// listWebhookDeliveries answers with the deliveries matching a filter, newest first.
+func listWebhookDeliveries(ctx context.Context, c *fiber.Ctx, filter bson.M) error {
+	var deliveries []models.WebhookDelivery
+
+	results, err := webhookDeliveryCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"creationdate": -1}).SetLimit(500))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	if err := results.All(ctx, &deliveries); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(responses.Response{Status: http.StatusInternalServerError, Message: "Error", Data: &fiber.Map{"data": err.Error()}})
+	}
+
+	return c.Status(http.StatusOK).JSON(
+		responses.Response{Status: http.StatusOK, Message: "Success", Data: &fiber.Map{"data": deliveries}},
+	)
+}
+
+// redactSecret hides the secret of a subscription, which is only shown once when it is created.
+func redactSecret(webhook models.WebhookSubscription) models.WebhookSubscription {
+	webhook.Secret = ""
+	return webhook
+}
+
+// WebhookStore gives the webhook worker access to the subscriptions and deliveries.
+type WebhookStore struct{}
+
+func (WebhookStore) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	filter := bson.M{"status": bson.M{"$in": []models.WebhookDeliveryStatus{models.WebhookPending, models.WebhookFailed}}, "nextattempt": bson.M{"$lte": now}}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"nextattempt": 1}).SetReturnDocument(options.After)
+
+	var deliveries []models.WebhookDelivery
+	for len(deliveries) < limit {
+		var delivery models.WebhookDelivery
+		err := webhookDeliveryCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"nextattempt": now.Add(lease)}}, opts).Decode(&delivery)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (WebhookStore) FindSubscription(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error) {
+	return findWebhook(ctx, subscriptionId)
+}
+
+func (WebhookStore) SaveDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	update := bson.M{"status": delivery.Status, "attempts": delivery.Attempts, "attemptcount": delivery.AttemptCount, "nextattempt": delivery.NextAttempt, "updatedate": delivery.UpdateDate}
+	_, err := webhookDeliveryCollection.UpdateOne(ctx, bson.M{"id": delivery.Id}, bson.M{"$set": update})
+	return err
+}
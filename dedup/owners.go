@@ -0,0 +1,181 @@
+package dedup
+
+import (
+	"pet-appointments-api/models"
+	"pet-appointments-api/textmatch"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reasons two owners are reported as possible duplicates.
+const (
+	ReasonIdNumber     = "same_id_number"
+	ReasonEmail        = "same_email"
+	ReasonPhone        = "same_phone"
+	ReasonName         = "similar_name"
+	ReasonSimilarEmail = "similar_email"
+)
+
+// DefaultMinScore is the score from which two owners are reported.
+const DefaultMinScore = 0.6
+
+// Duplicate is a pair of owners that look like the same person, with how sure the match is, from 0 to 1, and why.
+type Duplicate struct {
+	OwnerIds []string `json:"ownerIds"`
+	Names    []string `json:"names"`
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+}
+
+// Email normalises an email so the same address written differently compares equal.
+func Email(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// FindOwners returns the pairs of owners scoring at least minScore, the likeliest first. Owners are only compared
+// when they share something that could make them match, so every owner isn't compared with every other one.
+func FindOwners(owners []models.Owner, minScore float64) []Duplicate {
+	blocks := map[string][]int{}
+	for i, owner := range owners {
+		for _, key := range blockKeys(owner) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	compared := map[[2]int]bool{}
+	duplicates := []Duplicate{}
+	for _, block := range blocks {
+		for x := 0; x < len(block); x++ {
+			for y := x + 1; y < len(block); y++ {
+				pair := [2]int{block[x], block[y]}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+
+				a, b := owners[pair[0]], owners[pair[1]]
+				score, reasons := Score(a, b)
+				if score >= minScore {
+					duplicates = append(duplicates, Duplicate{
+						OwnerIds: []string{a.Id.Hex(), b.Id.Hex()},
+						Names:    []string{a.Name + " " + a.LastName, b.Name + " " + b.LastName},
+						Score:    score,
+						Reasons:  reasons,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		if duplicates[i].Score != duplicates[j].Score {
+			return duplicates[i].Score > duplicates[j].Score
+		}
+		return duplicates[i].OwnerIds[0] < duplicates[j].OwnerIds[0]
+	})
+	return duplicates
+}
+
+// Score tells how likely two owners are the same person. The same ID number or email settles it; otherwise a
+// similar name, the same phone and a similar email add up.
+func Score(a models.Owner, b models.Owner) (float64, []string) {
+	var reasons []string
+	score := 0.0
+
+	if a.IdNumber != 0 && a.IdNumber == b.IdNumber {
+		reasons = append(reasons, ReasonIdNumber)
+		score = 1
+	}
+	emailA, emailB := Email(a.Email), Email(b.Email)
+	if emailA != "" && emailA == emailB {
+		reasons = append(reasons, ReasonEmail)
+		score = 1
+	}
+
+	if a.Phone != "" && a.Phone == b.Phone {
+		reasons = append(reasons, ReasonPhone)
+		score += 0.4
+	}
+	if similarity := textmatch.Similarity(fullName(a), fullName(b)); similarity >= 0.85 {
+		reasons = append(reasons, ReasonName)
+		score += 0.4 * similarity
+	}
+	if emailA != emailB && emailA != "" && emailB != "" && textmatch.Distance(emailA, emailB) <= 2 {
+		reasons = append(reasons, ReasonSimilarEmail)
+		score += 0.2
+	}
+
+	if score > 1 {
+		score = 1
+	}
+	return float64(int(score*100+0.5)) / 100, reasons
+}
+
+// blockKeys are the things two owners must share at least one of to be compared.
+func blockKeys(owner models.Owner) []string {
+	keys := []string{}
+	if owner.IdNumber != 0 {
+		keys = append(keys, "id:"+strconv.Itoa(owner.IdNumber))
+	}
+	if owner.Phone != "" {
+		keys = append(keys, "phone:"+owner.Phone)
+	}
+	if email := Email(owner.Email); email != "" {
+		local := email
+		if at := strings.Index(email, "@"); at > 0 {
+			local = email[:at]
+		}
+		keys = append(keys, "email:"+prefix(local, 4))
+	}
+	if lastName := textmatch.Normalize(owner.LastName); lastName != "" {
+		keys = append(keys, "name:"+prefix(lastName, 3))
+	}
+	return keys
+}
+
+func fullName(owner models.Owner) string {
+	return textmatch.Normalize(owner.Name + " " + owner.LastName)
+}
+
+func prefix(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) > length {
+		runes = runes[:length]
+	}
+	return string(runes)
+}
+
+// MergeContacts returns the contact methods of an owner kept after merging another one into it: its own, followed
+// by the main phone, main email and contact methods of the merged owner that it doesn't have yet. The merged ones are
+// never preferred, so the owner kept is still contacted the way it chose.
+func MergeContacts(kept models.Owner, merged models.Owner) []models.ContactMethod {
+	known := map[string]bool{contactKey(models.ContactPhone, kept.Phone): true, contactKey(models.ContactEmail, kept.Email): true}
+	methods := []models.ContactMethod{}
+	for _, method := range kept.ContactMethods {
+		known[contactKey(method.Type, method.Value)] = true
+		methods = append(methods, method)
+	}
+
+	candidates := []models.ContactMethod{{Type: models.ContactPhone, Value: merged.Phone}, {Type: models.ContactEmail, Value: merged.Email}}
+	for _, method := range append(candidates, merged.ContactMethods...) {
+		key := contactKey(method.Type, method.Value)
+		if method.Value == "" || known[key] {
+			continue
+		}
+		known[key] = true
+		method.Preferred = false
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+func contactKey(contactType models.ContactType, value string) string {
+	if contactType == models.ContactEmail {
+		value = Email(value)
+	}
+	return string(contactType) + ":" + value
+}
@@ -0,0 +1,124 @@
+package dedup
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"pet-appointments-api/models"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func owner(name, lastName string, idNumber int, phone, email string) models.Owner {
+	return models.Owner{Id: primitive.NewObjectID(), Name: name, LastName: lastName, IdNumber: idNumber, Phone: phone, Email: email}
+}
+
+func TestScore(t *testing.T) {
+	cases := []struct {
+		name    string
+		a, b    models.Owner
+		score   float64
+		reasons []string
+	}{
+		{"same ID number", owner("Ana", "Pérez", 123, "+111", "ana@example.com"), owner("Bob", "Smith", 123, "+222", "bob@example.com"),
+			1, []string{ReasonIdNumber}},
+		{"same email written differently", owner("Ana", "Pérez", 1, "+111", "Ana@Example.com "), owner("Bob", "Smith", 2, "+222", "ana@example.com"),
+			1, []string{ReasonEmail}},
+		{"same phone and name", owner("Ana", "Pérez", 1, "+111", "ana@example.com"), owner("ana", "perez", 2, "+111", "other@example.org"),
+			0.8, []string{ReasonPhone, ReasonName}},
+		{"similar name, phone and email", owner("Maria", "Lopez", 1, "+111", "maria.lopez@example.com"), owner("Marie", "Lopez", 2, "+111", "marie.lopez@example.com"),
+			0.96, []string{ReasonPhone, ReasonName, ReasonSimilarEmail}},
+		{"similar name only", owner("Maria", "Lopez", 1, "+111", "maria@example.com"), owner("Marie", "Lopez", 2, "+222", "lopez@example.org"),
+			0.36, []string{ReasonName}},
+		{"names too far apart", owner("Ana", "Lopez", 1, "+111", "ana@example.com"), owner("Eva", "Lopez", 2, "+222", "eva@example.org"),
+			0, nil},
+		{"capped at 1", owner("Ana", "Pérez", 123, "+111", "ana@example.com"), owner("Ana", "Perez", 123, "+111", "ana@example.com"),
+			1, []string{ReasonIdNumber, ReasonEmail, ReasonPhone, ReasonName}},
+		{"missing fields don't match", owner("Ana", "Pérez", 0, "", ""), owner("Bob", "Smith", 0, "", ""),
+			0, nil},
+	}
+
+	for _, c := range cases {
+		score, reasons := Score(c.a, c.b)
+		if score != c.score || !reflect.DeepEqual(reasons, c.reasons) {
+			t.Errorf("%s: got %v %v, want %v %v", c.name, score, reasons, c.score, c.reasons)
+		}
+	}
+}
+
+func TestBlockKeys(t *testing.T) {
+	cases := []struct {
+		name  string
+		owner models.Owner
+		keys  []string
+	}{
+		{"every field", owner("Ana", "Pérez", 123, "+111", "Ana.Perez@Example.com"), []string{"id:123", "phone:+111", "email:ana.", "name:per"}},
+		{"short values", owner("Al", "Wu", 0, "", "al@example.com"), []string{"email:al", "name:wu"}},
+		{"email without @", owner("Al", "", 0, "", "alfonso"), []string{"email:alfo"}},
+		{"nothing to share", models.Owner{}, []string{}},
+	}
+
+	for _, c := range cases {
+		if keys := blockKeys(c.owner); !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("%s: got %v, want %v", c.name, keys, c.keys)
+		}
+	}
+}
+
+func TestFindOwners(t *testing.T) {
+	ana := owner("Ana", "Pérez", 1, "+111", "ana@example.com")
+	anaAgain := owner("ana", "perez", 2, "+111", "ana.p@example.org")
+	anaById := owner("Anita", "Gómez", 1, "+333", "anita@example.net")
+	bob := owner("Bob", "Smith", 3, "+222", "bob@example.com")
+	//same name as Bob, but shares no block key with him, so they are never compared
+	robert := owner("Bob", "Smyth", 4, "+444", "rob@example.com")
+
+	duplicates := FindOwners([]models.Owner{ana, bob, anaAgain, anaById, robert}, DefaultMinScore)
+
+	var got [][]string
+	for _, duplicate := range duplicates {
+		ids := append([]string{}, duplicate.OwnerIds...)
+		sort.Strings(ids)
+		got = append(got, ids)
+	}
+	pair := func(a, b models.Owner) []string {
+		ids := []string{a.Id.Hex(), b.Id.Hex()}
+		sort.Strings(ids)
+		return ids
+	}
+	want := [][]string{pair(ana, anaById), pair(ana, anaAgain)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if duplicates[0].Score != 1 || duplicates[1].Score != 0.8 {
+		t.Errorf("scores are %v and %v, want 1 and 0.8", duplicates[0].Score, duplicates[1].Score)
+	}
+
+	if duplicates := FindOwners(nil, DefaultMinScore); len(duplicates) != 0 {
+		t.Errorf("no owners: got %v", duplicates)
+	}
+}
+
+func TestMergeContacts(t *testing.T) {
+	kept := owner("Ana", "Pérez", 1, "+111", "ana@example.com")
+	kept.ContactMethods = []models.ContactMethod{{Type: models.ContactPhone, Value: "+112", Label: "work", Preferred: true}}
+	merged := owner("Ana", "Perez", 2, "+222", "ANA@example.com")
+	merged.ContactMethods = []models.ContactMethod{
+		{Type: models.ContactPhone, Value: "+112", Label: "office"},
+		{Type: models.ContactEmail, Value: "ana@work.example", Label: "work", Preferred: true},
+		{Type: models.ContactPhone, Value: "+111"},
+	}
+
+	want := []models.ContactMethod{
+		{Type: models.ContactPhone, Value: "+112", Label: "work", Preferred: true},
+		{Type: models.ContactPhone, Value: "+222"},
+		{Type: models.ContactEmail, Value: "ana@work.example", Label: "work"},
+	}
+	if got := MergeContacts(kept, merged); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	//nothing new to add
+	if got := MergeContacts(kept, kept); !reflect.DeepEqual(got, kept.ContactMethods) {
+		t.Errorf("merging the same contacts: got %v, want %v", got, kept.ContactMethods)
+	}
+}
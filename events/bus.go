@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pet-appointments-api/models"
+	"sync"
+)
+
+// Handler reacts to a published event. Events are delivered at least once, so handlers must be idempotent, using the
+// event ID to recognise the ones they already handled.
+type Handler func(ctx context.Context, event models.Event) error
+
+// Broker is where the Relay publishes the events taken from the outbox.
+type Broker interface {
+	Name() string
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// Bus is the in-process Broker: it hands every event to the handlers subscribed to its type, or to "*".
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+// Subscribe registers a handler for an event type, "*" receives every event.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish runs every interested handler, even when one of them fails, and returns all of their errors.
+func (b *Bus) Publish(ctx context.Context, event models.Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.handlers[event.Type]...), b.handlers["*"]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", event.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InMemoryBroker keeps every published event in memory, it is meant for tests.
+type InMemoryBroker struct {
+	mu     sync.Mutex
+	events []models.Event
+
+	// Err, when set, is returned by Publish instead of keeping the event.
+	Err error
+}
+
+func NewInMemoryBroker() *InMemoryBroker {
+	return &InMemoryBroker{}
+}
+
+func (b *InMemoryBroker) Name() string {
+	return "memory"
+}
+
+func (b *InMemoryBroker) Publish(ctx context.Context, event models.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Err != nil {
+		return b.Err
+	}
+	b.events = append(b.events, event)
+	return nil
+}
+
+// Events returns the events published so far.
+func (b *InMemoryBroker) Events() []models.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]models.Event{}, b.events...)
+}
+
+// Reset forgets the events published so far.
+func (b *InMemoryBroker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = nil
+}
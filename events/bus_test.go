@@ -0,0 +1,85 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"pet-appointments-api/models"
+	"strings"
+	"testing"
+)
+
+func TestBusPublish(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	handler := func(name string, err error) Handler {
+		return func(ctx context.Context, event models.Event) error {
+			got = append(got, name+":"+event.Id)
+			return err
+		}
+	}
+	bus.Subscribe(models.EventAppointmentCreated, handler("created", nil))
+	bus.Subscribe(models.EventAppointmentCreated, handler("failing", errors.New("boom")))
+	bus.Subscribe(models.EventAppointmentCancelled, handler("cancelled", nil))
+	bus.Subscribe("*", handler("all", nil))
+
+	err := bus.Publish(context.Background(), models.Event{Id: "1", Type: models.EventAppointmentCreated})
+	if err == nil || !strings.Contains(err.Error(), "appointment.created: boom") {
+		t.Errorf("got %v, want the error of the failing handler", err)
+	}
+
+	//a failing handler doesn't keep the others from running
+	want := []string{"created:1", "failing:1", "all:1"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = nil
+	if err := bus.Publish(context.Background(), models.Event{Id: "2", Type: models.EventPetCreated}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "all:2" {
+		t.Errorf("got %v, want only the catch-all handler", got)
+	}
+}
+
+func TestInMemoryBroker(t *testing.T) {
+	broker := NewInMemoryBroker()
+	ctx := context.Background()
+
+	if err := broker.Publish(ctx, models.Event{Id: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	broker.Err = errors.New("down")
+	if err := broker.Publish(ctx, models.Event{Id: "2"}); err != broker.Err {
+		t.Errorf("got %v, want %v", err, broker.Err)
+	}
+	if events := broker.Events(); len(events) != 1 || events[0].Id != "1" {
+		t.Errorf("got %+v", events)
+	}
+
+	broker.Reset()
+	if len(broker.Events()) != 0 {
+		t.Error("Reset kept the events")
+	}
+}
+
+func TestLogBroker(t *testing.T) {
+	var out bytes.Buffer
+	broker := NewLogBroker(&out)
+	for _, id := range []string{"1", "2"} {
+		if err := broker.Publish(context.Background(), models.Event{Id: id, Type: models.EventOwnerCreated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q, want a line per event", out.String())
+	}
+	var event models.Event
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil || event.Id != "2" || event.Type != models.EventOwnerCreated {
+		t.Errorf("got %+v, %v", event, err)
+	}
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"pet-appointments-api/models"
+	"sync"
+)
+
+// LogBroker writes every event as a line of JSON, which is handy to follow what happens while developing or to feed
+// a log shipper.
+type LogBroker struct {
+	mu     sync.Mutex
+	Writer io.Writer
+}
+
+func NewLogBroker(writer io.Writer) *LogBroker {
+	return &LogBroker{Writer: writer}
+}
+
+func (b *LogBroker) Name() string {
+	return "log"
+}
+
+func (b *LogBroker) Publish(ctx context.Context, event models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err = fmt.Fprintf(b.Writer, "%s\n", line)
+	return err
+}
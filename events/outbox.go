@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"pet-appointments-api/models"
+	"time"
+)
+
+const (
+	BaseBackoff = 5 * time.Second
+	MaxBackoff  = 10 * time.Minute
+)
+
+// NewRecord builds the outbox record of a new event concerning a partner, or none when partnerId is empty.
+func NewRecord(eventType string, partnerId string, data interface{}, now time.Time) (models.OutboxRecord, error) {
+	event := models.Event{Id: primitive.NewObjectID().Hex(), Type: eventType, PartnerId: partnerId, OccurredAt: now, Data: data}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return models.OutboxRecord{}, err
+	}
+
+	return models.OutboxRecord{
+		Id:           primitive.NewObjectID(),
+		EventId:      event.Id,
+		EventType:    event.Type,
+		Payload:      string(payload),
+		Status:       models.OutboxPending,
+		NextAttempt:  now,
+		CreationDate: now,
+	}, nil
+}
+
+// Decode returns the event stored in an outbox record, its data is left as raw JSON.
+func Decode(record models.OutboxRecord) (models.Event, error) {
+	var event struct {
+		models.Event
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(record.Payload), &event); err != nil {
+		return models.Event{}, err
+	}
+
+	event.Event.Data = event.Data
+	return event.Event, nil
+}
+
+// Backoff returns how long to wait before publishing a record again after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	backoff := BaseBackoff
+	for i := 1; i < attempts && backoff < MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxBackoff {
+		backoff = MaxBackoff
+	}
+	return backoff
+}
+
+// OutboxStore gives the Relay access to the outbox.
+type OutboxStore interface {
+	// ClaimPendingRecords returns the records due at the given time, oldest first, leasing them so no other relay
+	// picks them up until the lease expires.
+	ClaimPendingRecords(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxRecord, error)
+	SaveRecord(ctx context.Context, record models.OutboxRecord) error
+}
+
+// Relay periodically publishes the pending outbox records to its brokers.
+type Relay struct {
+	Store    OutboxStore
+	Brokers  []Broker
+	Interval time.Duration
+	Lease    time.Duration
+	Batch    int
+}
+
+func NewRelay(store OutboxStore, brokers ...Broker) *Relay {
+	return &Relay{Store: store, Brokers: brokers, Interval: time.Second, Lease: time.Minute, Batch: 100}
+}
+
+// Start runs the relay until the context is cancelled.
+func (r *Relay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.Run(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run publishes a batch of the records due at the given time. A record is only marked as published once every broker
+// accepted it, otherwise it is published again to all of them after a backoff.
+func (r *Relay) Run(ctx context.Context, now time.Time) {
+	records, err := r.Store.ClaimPendingRecords(ctx, now, r.Lease, r.Batch)
+	if err != nil {
+		log.Println("events: looking for pending outbox records failed:", err)
+		return
+	}
+
+	for _, record := range records {
+		if err := r.publish(ctx, record); err != nil {
+			record.Attempts++
+			record.LastError = err.Error()
+			record.NextAttempt = time.Now().Add(Backoff(record.Attempts))
+			log.Println("events: publishing", record.EventType, record.EventId, "failed:", err)
+		} else {
+			publishDate := time.Now()
+			record.Status = models.OutboxPublished
+			record.LastError = ""
+			record.PublishDate = &publishDate
+		}
+
+		if err := r.Store.SaveRecord(ctx, record); err != nil {
+			log.Println("events: saving outbox record", record.Id.Hex(), "failed:", err)
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, record models.OutboxRecord) error {
+	event, err := Decode(record)
+	if err != nil {
+		return err
+	}
+
+	for _, broker := range r.Brokers {
+		if err := broker.Publish(ctx, event); err != nil {
+			return fmt.Errorf("%s: %w", broker.Name(), err)
+		}
+	}
+	return nil
+}
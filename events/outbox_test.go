@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"pet-appointments-api/models"
+	"testing"
+	"time"
+)
+
+// memoryOutbox is an OutboxStore keeping its records in memory.
+type memoryOutbox struct {
+	records []models.OutboxRecord
+}
+
+func (s *memoryOutbox) ClaimPendingRecords(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxRecord, error) {
+	var claimed []models.OutboxRecord
+	for i, record := range s.records {
+		if len(claimed) == limit {
+			break
+		}
+		if record.Status == models.OutboxPending && !record.NextAttempt.After(now) {
+			s.records[i].NextAttempt = now.Add(lease)
+			claimed = append(claimed, s.records[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryOutbox) SaveRecord(ctx context.Context, record models.OutboxRecord) error {
+	for i := range s.records {
+		if s.records[i].Id == record.Id {
+			s.records[i] = record
+		}
+	}
+	return nil
+}
+
+func TestNewRecordDecode(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	record, err := NewRecord(models.EventAppointmentCreated, "partner-1", map[string]string{"id": "a1"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != models.OutboxPending || record.EventType != models.EventAppointmentCreated || !record.NextAttempt.Equal(now) {
+		t.Errorf("got %+v", record)
+	}
+
+	event, err := Decode(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Id != record.EventId || event.Type != models.EventAppointmentCreated || event.PartnerId != "partner-1" || !event.OccurredAt.Equal(now) {
+		t.Errorf("got %+v", event)
+	}
+	if data, ok := event.Data.(json.RawMessage); !ok || string(data) != `{"id":"a1"}` {
+		t.Errorf("got data %v, want it left as raw JSON", event.Data)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{0: BaseBackoff, 1: BaseBackoff, 2: 2 * BaseBackoff, 4: 8 * BaseBackoff, 50: MaxBackoff}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestRelayRun(t *testing.T) {
+	now := time.Now()
+	store := &memoryOutbox{}
+	for _, eventType := range []string{models.EventOwnerCreated, models.EventPetCreated, models.EventPartnerCreated} {
+		record, err := NewRecord(eventType, "", nil, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.records = append(store.records, record)
+	}
+
+	healthy, flaky := NewInMemoryBroker(), NewInMemoryBroker()
+	relay := NewRelay(store, healthy, flaky)
+	relay.Batch = 2
+
+	relay.Run(context.Background(), now)
+	if published := healthy.Events(); len(published) != 2 || published[0].Type != models.EventOwnerCreated || published[1].Type != models.EventPetCreated {
+		t.Fatalf("got %+v, want the first batch in order", published)
+	}
+	for _, record := range store.records[:2] {
+		if record.Status != models.OutboxPublished || record.PublishDate == nil {
+			t.Errorf("got %+v, want it published", record)
+		}
+	}
+
+	//a record some broker turned down stays pending, to be published to all of them again after a backoff
+	flaky.Err = errors.New("unavailable")
+	relay.Run(context.Background(), now)
+	failed := store.records[2]
+	if failed.Status != models.OutboxPending || failed.Attempts != 1 || failed.LastError != "memory: unavailable" || !failed.NextAttempt.After(now) {
+		t.Errorf("got %+v", failed)
+	}
+
+	flaky.Err = nil
+	relay.Run(context.Background(), failed.NextAttempt)
+	if store.records[2].Status != models.OutboxPublished || store.records[2].LastError != "" {
+		t.Errorf("got %+v, want it published on the retry", store.records[2])
+	}
+	if len(healthy.Events()) != 4 || len(flaky.Events()) != 3 {
+		t.Errorf("got %d and %d events, the retried one goes to every broker again", len(healthy.Events()), len(flaky.Events()))
+	}
+}
@@ -0,0 +1,29 @@
+package geocoding
+
+// Cities returns the centres of some large cities, so addresses can be located roughly without any fixture file.
+func Cities() []Fixture {
+	return []Fixture{
+		{City: "Buenos Aires", Country: "AR", Latitude: -34.6037, Longitude: -58.3816},
+		{City: "Córdoba", Country: "AR", Latitude: -31.4201, Longitude: -64.1888},
+		{City: "Sydney", Country: "AU", Latitude: -33.8688, Longitude: 151.2093},
+		{City: "São Paulo", Country: "BR", Latitude: -23.5505, Longitude: -46.6333},
+		{City: "Toronto", Country: "CA", Latitude: 43.6532, Longitude: -79.3832},
+		{City: "Santiago", Country: "CL", Latitude: -33.4489, Longitude: -70.6693},
+		{City: "Bogotá", Country: "CO", Latitude: 4.7110, Longitude: -74.0721},
+		{City: "Medellín", Country: "CO", Latitude: 6.2442, Longitude: -75.5812},
+		{City: "Berlin", Country: "DE", Latitude: 52.5200, Longitude: 13.4050},
+		{City: "Madrid", Country: "ES", Latitude: 40.4168, Longitude: -3.7038},
+		{City: "Barcelona", Country: "ES", Latitude: 41.3874, Longitude: 2.1686},
+		{City: "Paris", Country: "FR", Latitude: 48.8566, Longitude: 2.3522},
+		{City: "London", Country: "GB", Latitude: 51.5074, Longitude: -0.1278},
+		{City: "Rome", Country: "IT", Latitude: 41.9028, Longitude: 12.4964},
+		{City: "Mexico City", Country: "MX", Latitude: 19.4326, Longitude: -99.1332},
+		{City: "Guadalajara", Country: "MX", Latitude: 20.6597, Longitude: -103.3496},
+		{City: "Lima", Country: "PE", Latitude: -12.0464, Longitude: -77.0428},
+		{City: "Lisbon", Country: "PT", Latitude: 38.7223, Longitude: -9.1393},
+		{City: "New York", Country: "US", Latitude: 40.7128, Longitude: -74.0060},
+		{City: "Los Angeles", Country: "US", Latitude: 34.0522, Longitude: -118.2437},
+		{City: "San Francisco", Country: "US", Latitude: 37.7749, Longitude: -122.4194},
+		{City: "Montevideo", Country: "UY", Latitude: -34.9011, Longitude: -56.1645},
+	}
+}
@@ -0,0 +1,86 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"pet-appointments-api/models"
+	"pet-appointments-api/textmatch"
+	"strings"
+)
+
+// Fixture is a known location: a full address, a postal code or a city, by country.
+type Fixture struct {
+	Street     string  `json:"street,omitempty"`
+	City       string  `json:"city,omitempty"`
+	PostalCode string  `json:"postalCode,omitempty"`
+	Country    string  `json:"country"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+}
+
+// FixtureGeocoder locates addresses from a list of known locations, without calling any service. An address is
+// located at the most precise fixture matching it: its street, then its postal code, then its city.
+type FixtureGeocoder struct {
+	locations map[string]models.GeoPoint
+}
+
+func NewFixtureGeocoder(fixtures []Fixture) *FixtureGeocoder {
+	g := &FixtureGeocoder{locations: map[string]models.GeoPoint{}}
+	for _, fixture := range fixtures {
+		point := models.NewGeoPoint(fixture.Latitude, fixture.Longitude)
+		switch {
+		case fixture.Street != "":
+			g.locations[streetKey(fixture.Country, fixture.City, fixture.Street)] = point
+		case fixture.PostalCode != "":
+			g.locations[postalCodeKey(fixture.Country, fixture.PostalCode)] = point
+		default:
+			g.locations[cityKey(fixture.Country, fixture.City)] = point
+		}
+	}
+	return g
+}
+
+// LoadFixtures reads fixtures from a JSON file holding a list of them. Without a file, the built-in city centres
+// are used.
+func LoadFixtures(path string) ([]Fixture, error) {
+	if path == "" {
+		return Cities(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, err
+	}
+	return fixtures, nil
+}
+
+func (g *FixtureGeocoder) Geocode(ctx context.Context, address models.Address) (models.GeoPoint, error) {
+	keys := []string{
+		streetKey(address.Country, address.City, address.Street),
+		postalCodeKey(address.Country, address.PostalCode),
+		cityKey(address.Country, address.City),
+	}
+	for _, key := range keys {
+		if point, ok := g.locations[key]; ok {
+			return point, nil
+		}
+	}
+	return models.GeoPoint{}, ErrNotFound
+}
+
+func streetKey(country string, city string, street string) string {
+	return "street:" + strings.ToUpper(country) + ":" + textmatch.Normalize(city) + ":" + textmatch.Normalize(street)
+}
+
+func postalCodeKey(country string, postalCode string) string {
+	return "postal:" + strings.ToUpper(country) + ":" + strings.ToUpper(strings.ReplaceAll(postalCode, " ", ""))
+}
+
+func cityKey(country string, city string) string {
+	return "city:" + strings.ToUpper(country) + ":" + textmatch.Normalize(city)
+}
@@ -0,0 +1,87 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"pet-appointments-api/models"
+	"testing"
+)
+
+func TestFixtureGeocoder(t *testing.T) {
+	g := NewFixtureGeocoder([]Fixture{
+		{Street: "Gran Vía 28", City: "Madrid", Country: "ES", Latitude: 40.4200, Longitude: -3.7025},
+		{PostalCode: "28013", Country: "ES", Latitude: 40.4180, Longitude: -3.7100},
+		{City: "Madrid", Country: "ES", Latitude: 40.4168, Longitude: -3.7038},
+	})
+
+	cases := []struct {
+		name     string
+		address  models.Address
+		latitude float64
+	}{
+		{"street", models.Address{Street: "Gran Via  28", City: "madrid", PostalCode: "28013", Country: "es"}, 40.4200},
+		//a street that isn't known falls back to the postal code, spaces aside
+		{"postal code", models.Address{Street: "Calle Mayor 1", City: "Madrid", PostalCode: "280 13", Country: "ES"}, 40.4180},
+		{"city", models.Address{Street: "Calle de Alcalá 50", City: "MADRID", PostalCode: "28014", Country: "ES"}, 40.4168},
+	}
+
+	for _, c := range cases {
+		point, err := g.Geocode(context.Background(), c.address)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if point.Latitude() != c.latitude {
+			t.Errorf("%s: got latitude %v, want %v", c.name, point.Latitude(), c.latitude)
+		}
+	}
+}
+
+func TestFixtureGeocoderNotFound(t *testing.T) {
+	g := NewFixtureGeocoder(Cities())
+
+	//the same city name in another country isn't a match
+	_, err := g.Geocode(context.Background(), models.Address{City: "Madrid", Country: "US"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFixtureGeocoderIgnoresAccents(t *testing.T) {
+	g := NewFixtureGeocoder(Cities())
+
+	point, err := g.Geocode(context.Background(), models.Address{City: "Sao Paulo", Country: "BR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if point.Latitude() != -23.5505 || point.Longitude() != -46.6333 {
+		t.Errorf("got %v, want the centre of São Paulo", point.Coordinates)
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	data := `[{"street": "Gran Vía 28", "city": "Madrid", "country": "ES", "latitude": 40.42, "longitude": -3.7025}]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 1 || fixtures[0].Street != "Gran Vía 28" || fixtures[0].Longitude != -3.7025 {
+		t.Errorf("got %+v", fixtures)
+	}
+
+	//without a file, the built-in cities are used
+	if fixtures, err := LoadFixtures(""); err != nil || len(fixtures) != len(Cities()) {
+		t.Errorf("got %d fixtures, %v, want the built-in cities", len(fixtures), err)
+	}
+
+	if _, err := LoadFixtures(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing file was loaded")
+	}
+}
@@ -0,0 +1,35 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"pet-appointments-api/models"
+	"sync"
+)
+
+// ErrNotFound is returned by a Geocoder that can't locate an address.
+var ErrNotFound = errors.New("geocoding: address not found")
+
+// Geocoder finds where an address is.
+type Geocoder interface {
+	Geocode(ctx context.Context, address models.Address) (models.GeoPoint, error)
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultGeocoder Geocoder
+)
+
+// SetDefault sets the geocoder returned by Default.
+func SetDefault(geocoder Geocoder) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultGeocoder = geocoder
+}
+
+// Default returns the geocoder set up when the API started.
+func Default() Geocoder {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultGeocoder
+}
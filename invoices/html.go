@@ -0,0 +1,43 @@
+package invoices
+
+import (
+	"html/template"
+	"io"
+	"pet-appointments-api/models"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-top: 1em; }
+th, td { border-bottom: 1px solid #ddd; padding: .4em; text-align: left; }
+td.amount, th.amount { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p>Issued on {{.IssueDate.Format "2006-01-02"}}</p>
+<p><strong>From:</strong> {{.Partner.Name}}{{if .Partner.Email}} &lt;{{.Partner.Email}}&gt;{{end}}</p>
+<p><strong>To:</strong> {{.Owner.Name}}{{if .Owner.Email}} &lt;{{.Owner.Email}}&gt;{{end}}</p>
+<p><strong>Pet:</strong> {{.PetName}} &middot; <strong>Service date:</strong> {{.ServiceDate.Format "2006-01-02 15:04"}}</p>
+<table>
+<tr><th>Description</th><th class="amount">Quantity</th><th class="amount">Unit price</th><th class="amount">Total</th></tr>
+{{range .Lines}}<tr><td>{{.Description}}</td><td class="amount">{{.Quantity}}</td><td class="amount">{{.UnitPrice}}</td><td class="amount">{{.Total}}</td></tr>
+{{end}}<tr><td colspan="3">Subtotal</td><td class="amount">{{.Subtotal}}</td></tr>
+{{range .Discounts}}<tr><td colspan="3">{{.Name}}</td><td class="amount">-{{.Amount}}</td></tr>
+{{end}}{{range .Taxes}}<tr><td colspan="3">{{.Name}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}<tr><th colspan="3">Total</th><th class="amount">{{.Total}}</th></tr>
+</table>
+<p>Paid: {{.AmountPaid}}{{if .PaymentMethod}} ({{.PaymentMethod}}){{end}}</p>
+</body>
+</html>
+`))
+
+// RenderHTML writes the invoice as a standalone HTML page.
+func RenderHTML(w io.Writer, invoice models.Invoice) error {
+	return htmlTemplate.Execute(w, invoice)
+}
@@ -0,0 +1,46 @@
+package invoices
+
+import (
+	"fmt"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// Number formats the sequence of an invoice inside its clinic.
+func Number(sequence int64) string {
+	return fmt.Sprintf("INV-%06d", sequence)
+}
+
+// Build creates the invoice of a completed appointment. The numbering is left to the caller, which owns the
+// per-clinic sequence.
+func Build(appointment models.Appointment, owner models.Owner, pet models.Pet, partner models.Partner, issueDate time.Time) models.Invoice {
+	pricing := appointment.Pricing
+	line := models.InvoiceLine{
+		Description: appointment.Service,
+		Quantity:    1,
+		UnitPrice:   pricing.Subtotal,
+		Total:       pricing.Subtotal,
+	}
+
+	taxes := make([]models.InvoiceTax, 0, len(pricing.Taxes))
+	for _, tax := range pricing.Taxes {
+		taxes = append(taxes, models.InvoiceTax{Name: tax.Name, BasisPoints: tax.BasisPoints, Amount: tax.Amount})
+	}
+
+	return models.Invoice{
+		AppointmentId: appointment.Id.Hex(),
+		PartnerId:     appointment.PartnerId,
+		Partner:       models.InvoiceParty{Id: partner.Id.Hex(), Name: partner.Name + " " + partner.LastName, IdNumber: partner.IdNumber, Email: partner.Email, Phone: partner.Phone},
+		Owner:         models.InvoiceParty{Id: owner.Id.Hex(), Name: owner.Name + " " + owner.LastName, IdNumber: owner.IdNumber, Email: owner.Email, Phone: owner.Phone},
+		PetName:       pet.Name,
+		ServiceDate:   appointment.StartTime,
+		Lines:         []models.InvoiceLine{line},
+		Subtotal:      pricing.Subtotal,
+		Discounts:     pricing.Discounts,
+		Taxes:         taxes,
+		Total:         pricing.Total,
+		AmountPaid:    appointment.AmountPaid,
+		PaymentMethod: appointment.PaymentType,
+		IssueDate:     issueDate,
+	}
+}
@@ -0,0 +1,91 @@
+package invoices
+
+import (
+	"bytes"
+	"pet-appointments-api/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testInvoice() models.Invoice {
+	return models.Invoice{
+		Number:      Number(42),
+		Partner:     models.InvoiceParty{Name: "Ana Vet", Email: "ana@example.com"},
+		Owner:       models.InvoiceParty{Name: "Luis Owner", Email: "luis@example.com"},
+		PetName:     "Rex",
+		ServiceDate: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		IssueDate:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Lines: []models.InvoiceLine{
+			{Description: "Grooming", Quantity: 1, UnitPrice: models.NewMoney(5000, "USD"), Total: models.NewMoney(5000, "USD")},
+		},
+		Subtotal:      models.NewMoney(5000, "USD"),
+		Discounts:     []models.DiscountLine{{Name: "Spring coupon", Code: "SPRING", Amount: models.NewMoney(500, "USD")}},
+		Taxes:         []models.InvoiceTax{{Name: "VAT", BasisPoints: 2100, Amount: models.NewMoney(945, "USD")}},
+		Total:         models.NewMoney(5445, "USD"),
+		AmountPaid:    models.NewMoney(5445, "USD"),
+		PaymentMethod: models.PaymentMethodCash,
+	}
+}
+
+func TestNumber(t *testing.T) {
+	if got := Number(42); got != "INV-000042" {
+		t.Errorf("Number(42) = %q, want INV-000042", got)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := RenderHTML(&out, testInvoice()); err != nil {
+		t.Fatal(err)
+	}
+
+	html := out.String()
+	for _, want := range []string{
+		"Invoice INV-000042",
+		"<td>Grooming</td>",
+		"Spring coupon</td><td class=\"amount\">-5.00 USD",
+		"VAT</td><td class=\"amount\">9.45 USD",
+		"Total</th><th class=\"amount\">54.45 USD",
+		"Paid: 54.45 USD (cash)",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("the invoice is missing %q:\n%s", want, html)
+		}
+	}
+
+	//the taxes are listed once, not once per discount
+	if count := strings.Count(html, "VAT"); count != 1 {
+		t.Errorf("VAT is listed %d times, want 1", count)
+	}
+}
+
+func TestRenderHTMLWithoutDiscounts(t *testing.T) {
+	invoice := testInvoice()
+	invoice.Discounts = nil
+
+	var out bytes.Buffer
+	if err := RenderHTML(&out, invoice); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "VAT") {
+		t.Errorf("the taxes are missing when there are no discounts:\n%s", out.String())
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	var out bytes.Buffer
+	if err := RenderPDF(&out, testInvoice()); err != nil {
+		t.Fatal(err)
+	}
+
+	pdf := out.String()
+	if !strings.HasPrefix(pdf, "%PDF-") || !strings.HasSuffix(strings.TrimSpace(pdf), "%%EOF") {
+		t.Fatalf("the output is not a PDF document:\n%s", pdf)
+	}
+	for _, want := range []string{"INVOICE INV-000042", "Spring coupon", "VAT", "TOTAL"} {
+		if !strings.Contains(pdf, want) {
+			t.Errorf("the PDF is missing %q", want)
+		}
+	}
+}
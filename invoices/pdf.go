@@ -0,0 +1,140 @@
+package invoices
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"pet-appointments-api/models"
+	"strings"
+)
+
+const (
+	pageWidth    = 595 // A4, in points
+	pageHeight   = 842
+	margin       = 56
+	lineHeight   = 16
+	linesPerPage = (pageHeight - 2*margin) / lineHeight
+)
+
+// RenderPDF writes the invoice as a plain PDF document, using only the standard Helvetica font so no font files
+// have to be embedded.
+func RenderPDF(w io.Writer, invoice models.Invoice) error {
+	text := textLines(invoice)
+
+	var pages [][]string
+	for len(text) > linesPerPage {
+		pages = append(pages, text[:linesPerPage])
+		text = text[linesPerPage:]
+	}
+	pages = append(pages, text)
+
+	var doc pdfWriter
+	doc.start()
+
+	//objects 1 and 2 are the catalog and the page tree, 3 is the font, then a page and its content per page
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	doc.object("<< /Type /Catalog /Pages 2 0 R >>")
+	doc.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	doc.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range pages {
+		doc.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 5+2*i))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 11 Tf %d TL %d %d Td\n", lineHeight, margin, pageHeight-margin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) '\n", escapePDF(line))
+		}
+		content.WriteString("ET")
+		doc.stream(content.Bytes())
+	}
+
+	_, err := w.Write(doc.finish())
+	return err
+}
+
+// textLines lays the invoice out as lines of text.
+func textLines(invoice models.Invoice) []string {
+	lines := []string{
+		"INVOICE " + invoice.Number,
+		"Issued on " + invoice.IssueDate.Format("2006-01-02"),
+		"",
+		"From: " + invoice.Partner.Name + " " + invoice.Partner.Email,
+		"To:   " + invoice.Owner.Name + " " + invoice.Owner.Email,
+		"Pet:  " + invoice.PetName + "    Service date: " + invoice.ServiceDate.Format("2006-01-02 15:04"),
+		"",
+	}
+
+	for _, line := range invoice.Lines {
+		lines = append(lines, fmt.Sprintf("%-50s %3d x %14s = %14s", line.Description, line.Quantity, line.UnitPrice, line.Total))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("%-50s %33s", "Subtotal", invoice.Subtotal))
+	for _, discount := range invoice.Discounts {
+		lines = append(lines, fmt.Sprintf("%-50s %33s", discount.Name, "-"+discount.Amount.String()))
+	}
+	for _, tax := range invoice.Taxes {
+		lines = append(lines, fmt.Sprintf("%-50s %33s", tax.Name, tax.Amount))
+	}
+	lines = append(lines, fmt.Sprintf("%-50s %33s", "TOTAL", invoice.Total), "")
+
+	paid := "Paid: " + invoice.AmountPaid.String()
+	if invoice.PaymentMethod != "" {
+		paid += " (" + string(invoice.PaymentMethod) + ")"
+	}
+	return append(lines, paid)
+}
+
+// escapePDF escapes a PDF string literal and maps it to WinAnsi, replacing what can't be represented.
+func escapePDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfWriter assembles the objects of a PDF file and its cross-reference table.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (p *pdfWriter) start() {
+	p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+}
+
+func (p *pdfWriter) object(body string) {
+	p.offsets = append(p.offsets, p.buf.Len())
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", len(p.offsets), body)
+}
+
+func (p *pdfWriter) stream(data []byte) {
+	p.offsets = append(p.offsets, p.buf.Len())
+	fmt.Fprintf(&p.buf, "%d 0 obj\n<< /Length %d >>\nstream\n", len(p.offsets), len(data))
+	p.buf.Write(data)
+	p.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (p *pdfWriter) finish() []byte {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, xref)
+	return p.buf.Bytes()
+}
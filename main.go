@@ -1,22 +1,160 @@
 package main
 
 import (
+	"context"
 	"github.com/gofiber/fiber/v2"
+	"log"
+	"os"
+	"pet-appointments-api/calendar"
 	"pet-appointments-api/configs"
+	"pet-appointments-api/controllers"
+	"pet-appointments-api/events"
+	"pet-appointments-api/geocoding"
+	"pet-appointments-api/migrations"
+	"pet-appointments-api/models"
+	"pet-appointments-api/notifications"
+	"pet-appointments-api/payments"
 	"pet-appointments-api/routes"
+	"pet-appointments-api/storage"
+	"pet-appointments-api/waitlist"
+	"pet-appointments-api/webhooks"
+	_ "time/tzdata"
 )
 
 func main() {
-	app := fiber.New()
+	//bodies above the default limit are streamed, so only the attachment uploads are read up to their larger limit
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Use(routes.BodyLimit(fiber.DefaultBodyLimit, configs.EnvAttachmentMaxSize()+1<<20))
 
 	//run database
 	configs.ConnectDB()
+	if err := controllers.EnsureIndexes(); err != nil {
+		log.Fatal(err)
+	}
+	if err := migrations.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := controllers.EnsureOwnerIndexes(); err != nil {
+		//duplicated owners keep the API from starting otherwise, and it is where they get merged
+		log.Println("Error: owner ID numbers and emails are not unique yet, merge the duplicated owners, " + err.Error())
+	}
+
+	//payment providers
+	payments.Register(models.PaymentMethodCash, payments.NewManualProvider())
+	payments.Register(models.PaymentMethodTransfer, payments.NewManualProvider())
+	if configs.EnvCardGateway() == "fake" {
+		payments.Register(models.PaymentMethodCard, payments.NewFakeCardGateway())
+	}
+
+	//attachment files
+	storage.SetDefault(blobStore())
+
+	//address locations
+	geocoding.SetDefault(geocoder())
 
 	//routes
 	routes.AppointmentRoutes(app)
 	routes.OwnerRoutes(app)
 	routes.PetRoutes(app)
 	routes.PartnerRoutes(app)
+	routes.ServiceRoutes(app)
+	routes.PaymentRoutes(app)
+	routes.TaxRateRoutes(app)
+	routes.CouponRoutes(app)
+	routes.PayoutRoutes(app)
+	routes.NotificationTemplateRoutes(app)
+	routes.WebhookRoutes(app)
+	routes.WaitlistRoutes(app)
+	routes.SlotHoldRoutes(app)
+	routes.MedicalRecordRoutes(app)
+	routes.CatalogRoutes(app)
+	routes.AttachmentRoutes(app)
+
+	//notifications, appointment reminders and vaccination reminders
+	dispatcher := notifications.NewDispatcher(controllers.TemplateStore{}, notifiers())
+	notifications.SetDefault(dispatcher)
+	go notifications.NewScheduler(controllers.ReminderStore{}, configs.EnvReminderOffsets(), dispatcher).Start(context.Background())
+	go notifications.NewVaccinationScheduler(controllers.VaccinationStore{}, configs.EnvVaccinationReminderOffsets(), dispatcher).Start(context.Background())
+
+	//domain events are relayed from the outbox to the in-process bus and the configured brokers
+	bus := events.NewBus()
+	bus.Subscribe("*", controllers.QueueWebhooks)
+	go events.NewRelay(controllers.OutboxStore{}, append([]events.Broker{bus}, brokers()...)...).Start(context.Background())
+
+	//webhook deliveries
+	go webhooks.NewWorker(controllers.WebhookStore{}).Start(context.Background())
+
+	//partner calendars blocking their availability
+	go calendar.NewSyncer(controllers.CalendarSourceStore{}).Start(context.Background())
+
+	//expired waitlist offers go to the next owner
+	go waitlist.NewSweeper(controllers.WaitlistStore{}).Start(context.Background())
 
 	app.Listen(":6000")
 }
+
+// notifiers builds the notification channels enabled in the environment.
+func notifiers() []notifications.Notifier {
+	var enabled []notifications.Notifier
+	for _, name := range configs.EnvNotifiers() {
+		switch name {
+		case "smtp":
+			enabled = append(enabled, notifications.NewSMTPNotifier(configs.EnvSMTP()))
+		case "sms":
+			enabled = append(enabled, notifications.NewSMSNotifier(configs.EnvSMSGateway()))
+		case "log":
+			enabled = append(enabled, notifications.NewLogNotifier(os.Stdout))
+		case "file":
+			notifier, err := notifications.NewFileNotifier(configs.EnvNotificationLogFile())
+			if err != nil {
+				log.Fatal(err)
+			}
+			enabled = append(enabled, notifier)
+		default:
+			log.Fatal("Error: unknown notifier " + name)
+		}
+	}
+	return enabled
+}
+
+// blobStore builds the store attachment files are kept in.
+func blobStore() storage.BlobStore {
+	switch name := configs.EnvAttachmentStore(); name {
+	case "local":
+		return storage.NewLocalStore(configs.EnvAttachmentDir())
+	case "s3":
+		return storage.NewS3Store(configs.EnvS3())
+	default:
+		log.Fatal("Error: unknown attachment store " + name)
+		return nil
+	}
+}
+
+// geocoder builds the geocoder addresses are located with.
+func geocoder() geocoding.Geocoder {
+	switch name := configs.EnvGeocoder(); name {
+	case "fixture":
+		fixtures, err := geocoding.LoadFixtures(configs.EnvGeocoderFixtures())
+		if err != nil {
+			log.Fatal(err)
+		}
+		return geocoding.NewFixtureGeocoder(fixtures)
+	default:
+		log.Fatal("Error: unknown geocoder " + name)
+		return nil
+	}
+}
+
+// brokers builds the event brokers enabled in the environment.
+func brokers() []events.Broker {
+	var enabled []events.Broker
+	for _, name := range configs.EnvEventBrokers() {
+		switch name {
+		case "log":
+			enabled = append(enabled, events.NewLogBroker(os.Stdout))
+		default:
+			log.Fatal("Error: unknown event broker " + name)
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,165 @@
+package medication
+
+import (
+	"pet-appointments-api/models"
+	"sort"
+	"time"
+)
+
+// doseTimes are the hours of the day the doses of frequencies with several doses a day are given at, spread over
+// waking hours so owners can follow them. The other frequencies keep the time of day of the first dose.
+var doseTimes = map[models.DoseFrequency][]int{
+	models.FrequencyTwiceDaily:      {8, 20},
+	models.FrequencyThreeTimesDaily: {8, 14, 20},
+	models.FrequencyFourTimesDaily:  {8, 12, 16, 20},
+}
+
+// everyDays is how many days apart the dosing days of each frequency are.
+var everyDays = map[models.DoseFrequency]int{
+	models.FrequencyEveryOtherDay: 2,
+	models.FrequencyWeekly:        7,
+}
+
+// Dose is a scheduled dose of a prescription.
+type Dose struct {
+	PrescriptionId string    `json:"prescriptionId"`
+	Drug           string    `json:"drug"`
+	Dose           string    `json:"dose"`
+	Time           time.Time `json:"time"`
+}
+
+// Period is a span of time covered by a fill of a prescription.
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Coverage returns the periods the fills of a prescription cover, in order. The first fill starts on the start
+// date, and a refill starts when the previous supply runs out or on the day it was filled if that is later, so a
+// late refill leaves a gap. A stopped prescription covers nothing after it was stopped.
+func Coverage(p models.Prescription) []Period {
+	if p.Frequency == models.FrequencyOnce || p.Frequency == models.FrequencyAsNeeded || p.DurationDays <= 0 {
+		return nil
+	}
+
+	refills := append([]models.Refill{}, p.Refills...)
+	sort.SliceStable(refills, func(i, j int) bool { return refills[i].Date.Before(refills[j].Date) })
+
+	course := time.Duration(p.DurationDays) * 24 * time.Hour
+	periods := []Period{{Start: p.StartDate, End: p.StartDate.Add(course)}}
+	for _, refill := range refills {
+		start := periods[len(periods)-1].End
+		if refill.Date.After(start) {
+			start = refill.Date
+		}
+		periods = append(periods, Period{Start: start, End: start.Add(course)})
+	}
+
+	if p.StopDate == nil {
+		return periods
+	}
+
+	var stopped []Period
+	for _, period := range periods {
+		if !period.Start.Before(*p.StopDate) {
+			break
+		}
+		if period.End.After(*p.StopDate) {
+			period.End = *p.StopDate
+		}
+		stopped = append(stopped, period)
+	}
+	return stopped
+}
+
+// End returns when the last fill of a prescription runs out, or when it was stopped. Single doses end when given.
+func End(p models.Prescription) time.Time {
+	periods := Coverage(p)
+	if len(periods) == 0 {
+		if p.StopDate != nil && p.StopDate.Before(p.StartDate) {
+			return *p.StopDate
+		}
+		return p.StartDate
+	}
+	return periods[len(periods)-1].End
+}
+
+// Active reports whether a prescription is still being given at the given time. Medication given as needed is
+// active until it is stopped.
+func Active(p models.Prescription, now time.Time) bool {
+	if p.StopDate != nil && !now.Before(*p.StopDate) {
+		return false
+	}
+	if p.Frequency == models.FrequencyAsNeeded {
+		return true
+	}
+	return now.Before(End(p))
+}
+
+// RefillsLeft returns how many more refills a prescription allows.
+func RefillsLeft(p models.Prescription) int {
+	if left := p.RefillsAllowed - len(p.Refills); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// Schedule returns the doses of a prescription between from and to, at local times of the given location so they
+// don't move with daylight saving changes. A single dose is given at the start date, and medication given as needed
+// has no schedule.
+func Schedule(p models.Prescription, location *time.Location, from, to time.Time) []Dose {
+	dose := func(t time.Time) Dose {
+		return Dose{PrescriptionId: p.Id.Hex(), Drug: p.Drug, Dose: p.Dose, Time: t}
+	}
+
+	if p.Frequency == models.FrequencyOnce {
+		if !p.StartDate.Before(from) && p.StartDate.Before(to) && (p.StopDate == nil || p.StartDate.Before(*p.StopDate)) {
+			return []Dose{dose(p.StartDate)}
+		}
+		return nil
+	}
+
+	hours, every := doseTimes[p.Frequency], everyDays[p.Frequency]
+	if every == 0 {
+		every = 1
+	}
+
+	course := time.Duration(p.DurationDays) * 24 * time.Hour
+	var doses []Dose
+	for _, period := range Coverage(p) {
+		start := period.Start.In(location)
+		first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+
+		//a whole course ends on the same local time it started, even if a daylight saving change made it an hour
+		//shorter or longer, so it doesn't gain or lose the dose of its last day
+		end := period.End
+		if end.Equal(period.Start.Add(course)) {
+			end = start.AddDate(0, 0, p.DurationDays)
+		}
+
+		//hours and minutes of the doses of a day
+		clock := [][2]int{{start.Hour(), start.Minute()}}
+		if hours != nil {
+			clock = nil
+			for _, hour := range hours {
+				clock = append(clock, [2]int{hour, 0})
+			}
+		}
+
+		for day := 0; ; day += every {
+			date := first.AddDate(0, 0, day)
+			if !date.Before(end) || !date.Before(to) {
+				break
+			}
+
+			for _, at := range clock {
+				t := time.Date(date.Year(), date.Month(), date.Day(), at[0], at[1], 0, 0, location)
+				if t.Before(period.Start) || !t.Before(end) || t.Before(from) || !t.Before(to) {
+					continue
+				}
+				doses = append(doses, dose(t))
+			}
+		}
+	}
+	return doses
+}
@@ -0,0 +1,137 @@
+package medication
+
+import (
+	"pet-appointments-api/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour int, location *time.Location) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, location)
+}
+
+func TestSchedule(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	march := func(day, hour int) time.Time { return date(2024, time.March, day, hour, time.UTC) }
+	stop := march(3, 8)
+
+	cases := []struct {
+		name         string
+		frequency    models.DoseFrequency
+		durationDays int
+		start        time.Time
+		refills      []time.Time
+		stopDate     *time.Time
+		location     *time.Location
+		from, to     time.Time
+		doses        []string
+	}{
+		{"daily, none on the end day at the start time", models.FrequencyDaily, 3, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00", "03-02 09:00", "03-03 09:00"}},
+		{"twice daily, last dose on the end day", models.FrequencyTwiceDaily, 2, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 20:00", "03-02 08:00", "03-02 20:00", "03-03 08:00"}},
+		{"twice daily from midnight, none on the end day", models.FrequencyTwiceDaily, 2, march(1, 0), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 08:00", "03-01 20:00", "03-02 08:00", "03-02 20:00"}},
+		{"every other day", models.FrequencyEveryOtherDay, 5, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00", "03-03 09:00", "03-05 09:00"}},
+		{"weekly", models.FrequencyWeekly, 14, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00", "03-08 09:00"}},
+		{"zero duration", models.FrequencyDaily, 0, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			nil},
+		{"as needed", models.FrequencyAsNeeded, 0, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			nil},
+		{"once", models.FrequencyOnce, 0, march(1, 9), nil, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00"}},
+		{"once, stopped before", models.FrequencyOnce, 0, march(1, 9), nil, &[]time.Time{march(1, 8)}[0], time.UTC, march(1, 0), march(31, 0),
+			nil},
+		{"refilled before running out", models.FrequencyDaily, 2, march(1, 9), []time.Time{march(2, 12)}, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00", "03-02 09:00", "03-03 09:00", "03-04 09:00"}},
+		{"refilled late", models.FrequencyDaily, 2, march(1, 9), []time.Time{march(6, 10)}, nil, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00", "03-02 09:00", "03-06 10:00", "03-07 10:00"}},
+		{"stopped", models.FrequencyDaily, 5, march(1, 9), nil, &stop, time.UTC, march(1, 0), march(31, 0),
+			[]string{"03-01 09:00", "03-02 09:00"}},
+		{"window", models.FrequencyDaily, 10, march(1, 9), nil, nil, time.UTC, march(3, 0), march(5, 9),
+			[]string{"03-03 09:00", "03-04 09:00"}},
+		//clocks go forward on March 10th, which makes the course an hour shorter
+		{"daily over the spring change", models.FrequencyDaily, 3, date(2024, time.March, 9, 9, newYork), nil, nil, newYork, march(1, 0), march(31, 0),
+			[]string{"03-09 09:00", "03-10 09:00", "03-11 09:00"}},
+		{"twice daily over the spring change", models.FrequencyTwiceDaily, 2, date(2024, time.March, 9, 8, newYork), nil, nil, newYork, march(1, 0), march(31, 0),
+			[]string{"03-09 08:00", "03-09 20:00", "03-10 08:00", "03-10 20:00"}},
+		//clocks go back on November 3rd, which makes the course an hour longer
+		{"daily over the autumn change", models.FrequencyDaily, 3, date(2024, time.November, 2, 9, newYork), nil, nil, newYork, date(2024, time.November, 1, 0, time.UTC), date(2024, time.November, 30, 0, time.UTC),
+			[]string{"11-02 09:00", "11-03 09:00", "11-04 09:00"}},
+		{"twice daily over the autumn change", models.FrequencyTwiceDaily, 2, date(2024, time.November, 2, 9, newYork), nil, nil, newYork, date(2024, time.November, 1, 0, time.UTC), date(2024, time.November, 30, 0, time.UTC),
+			[]string{"11-02 20:00", "11-03 08:00", "11-03 20:00", "11-04 08:00"}},
+	}
+
+	for _, c := range cases {
+		p := models.Prescription{Drug: "Carprofen", Dose: "25 mg", Frequency: c.frequency, DurationDays: c.durationDays, StartDate: c.start, StopDate: c.stopDate}
+		for _, refill := range c.refills {
+			p.Refills = append(p.Refills, models.Refill{Date: refill})
+		}
+
+		var doses []string
+		for _, dose := range Schedule(p, c.location, c.from, c.to) {
+			doses = append(doses, dose.Time.In(c.location).Format("01-02 15:04"))
+		}
+		if !reflect.DeepEqual(doses, c.doses) {
+			t.Errorf("%s: got %v, want %v", c.name, doses, c.doses)
+		}
+	}
+}
+
+func TestEndAndActive(t *testing.T) {
+	start := date(2024, time.March, 1, 9, time.UTC)
+	stop := date(2024, time.March, 2, 9, time.UTC)
+
+	cases := []struct {
+		name         string
+		frequency    models.DoseFrequency
+		durationDays int
+		stopDate     *time.Time
+		end          time.Time
+		activeAt     time.Time
+		active       bool
+	}{
+		{"daily", models.FrequencyDaily, 3, nil, date(2024, time.March, 4, 9, time.UTC), date(2024, time.March, 4, 8, time.UTC), true},
+		{"daily, on the end", models.FrequencyDaily, 3, nil, date(2024, time.March, 4, 9, time.UTC), date(2024, time.March, 4, 9, time.UTC), false},
+		{"daily, stopped", models.FrequencyDaily, 3, &stop, stop, stop, false},
+		{"zero duration", models.FrequencyDaily, 0, nil, start, start, false},
+		{"once", models.FrequencyOnce, 0, nil, start, start, false},
+		{"as needed, open-ended", models.FrequencyAsNeeded, 0, nil, start, date(2025, time.March, 1, 9, time.UTC), true},
+		{"as needed, stopped", models.FrequencyAsNeeded, 0, &stop, start, stop, false},
+	}
+
+	for _, c := range cases {
+		p := models.Prescription{Frequency: c.frequency, DurationDays: c.durationDays, StartDate: start, StopDate: c.stopDate}
+		if end := End(p); !end.Equal(c.end) {
+			t.Errorf("%s: End() = %s, want %s", c.name, end, c.end)
+		}
+		if active := Active(p, c.activeAt); active != c.active {
+			t.Errorf("%s: Active(%s) = %t, want %t", c.name, c.activeAt, active, c.active)
+		}
+	}
+}
+
+func TestRefillsLeft(t *testing.T) {
+	cases := []struct {
+		allowed, used, left int
+	}{
+		{0, 0, 0},
+		{2, 0, 2},
+		{2, 1, 1},
+		{2, 2, 0},
+		{1, 3, 0},
+	}
+
+	for _, c := range cases {
+		p := models.Prescription{RefillsAllowed: c.allowed, Refills: make([]models.Refill, c.used)}
+		if left := RefillsLeft(p); left != c.left {
+			t.Errorf("%d allowed, %d used: got %d, want %d", c.allowed, c.used, left, c.left)
+		}
+	}
+}
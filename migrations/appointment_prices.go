@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+)
+
+// appointmentPrices turns the amount and the service name appointments were booked with into a price and a catalog
+// service. The amount is read in the currency the partner charges for the service of the same name, or in the
+// default currency when no service has that name; those appointments keep only their service name and are logged.
+var appointmentPrices = Migration{
+	Id:          "0005-appointment-prices",
+	Description: "replace the amount of appointments with a price and link them to the service catalog",
+	Up:          migrateAppointmentPrices,
+}
+
+// storedAmount is the part of an appointment stored before the service catalog.
+type storedAmount struct {
+	Id        interface{} `bson:"_id"`
+	PartnerId string      `bson:"partnerid"`
+	Service   string      `bson:"service"`
+	Amount    float64     `bson:"amount"`
+}
+
+func migrateAppointmentPrices(ctx context.Context) error {
+	appointments := configs.GetCollection(configs.DB, "appointments")
+	services := configs.GetCollection(configs.DB, "services")
+	currency := configs.EnvDefaultCurrency()
+
+	results, err := appointments.Find(ctx, bson.M{"amount": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	for results.Next(ctx) {
+		var appointment storedAmount
+		if err := results.Decode(&appointment); err != nil {
+			return err
+		}
+
+		set := bson.M{}
+		price := models.FromDecimal(appointment.Amount, currency)
+		var service models.Service
+		err := services.FindOne(ctx, bson.M{"name": appointment.Service}).Decode(&service)
+		switch {
+		case err == nil:
+			price = models.FromDecimal(appointment.Amount, service.PriceFor(appointment.PartnerId).Currency)
+			set["serviceid"] = service.Id.Hex()
+		case err == mongo.ErrNoDocuments:
+			log.Printf("Appointment %v: no service is named %q, the price is read in %s", appointment.Id, appointment.Service, currency)
+		default:
+			return err
+		}
+
+		set["pricing"] = models.PriceBreakdown{Subtotal: price, Total: price}
+		if _, err := appointments.UpdateOne(ctx, bson.M{"_id": appointment.Id}, bson.M{"$set": set, "$unset": bson.M{"amount": ""}}); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
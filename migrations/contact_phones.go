@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/contact"
+	"pet-appointments-api/models"
+	"strconv"
+)
+
+// contactPhones turns the phones owners and partners were stored with, numbers that lost their leading zeros and
+// calling code, into E.164 strings read in the default phone region, and opts the owners in to the channels they
+// were always contacted through. Invoices keep their phones as they were, only written as text.
+var contactPhones = Migration{
+	Id:          "0004-contact-phones",
+	Description: "store phones as E.164 strings and opt owners in to notifications",
+	Up:          migrateContactPhones,
+}
+
+// storedPhone is the part of an owner or a partner the migration changes.
+type storedPhone struct {
+	Id    interface{} `bson:"_id"`
+	Phone interface{} `bson:"phone"`
+}
+
+// storedInvoicePhones are the phones of the parties of an invoice.
+type storedInvoicePhones struct {
+	Id      interface{} `bson:"_id"`
+	Owner   storedPhone `bson:"owner"`
+	Partner storedPhone `bson:"partner"`
+}
+
+func migrateContactPhones(ctx context.Context) error {
+	region := configs.EnvPhoneRegion()
+	for _, name := range []string{"owners", "partners"} {
+		if err := migratePhones(ctx, configs.GetCollection(configs.DB, name), region); err != nil {
+			return err
+		}
+	}
+
+	optIns := bson.M{}
+	for _, channel := range models.ContactChannels {
+		optIns[channel] = true
+	}
+	owners := configs.GetCollection(configs.DB, "owners")
+	if _, err := owners.UpdateMany(ctx, bson.M{"optins": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"optins": optIns}}); err != nil {
+		return err
+	}
+
+	invoices := configs.GetCollection(configs.DB, "invoices")
+	filter := bson.M{"$or": bson.A{bson.M{"owner.phone": bson.M{"$type": "number"}}, bson.M{"partner.phone": bson.M{"$type": "number"}}}}
+	results, err := invoices.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	for results.Next(ctx) {
+		var invoice storedInvoicePhones
+		if err := results.Decode(&invoice); err != nil {
+			return err
+		}
+		update := bson.M{"owner.phone": digits(invoice.Owner.Phone), "partner.phone": digits(invoice.Partner.Phone)}
+		if _, err := invoices.UpdateOne(ctx, bson.M{"_id": invoice.Id}, bson.M{"$set": update}); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
+
+// migratePhones rewrites the numeric phones of a collection. A phone that can't be read is kept as its digits and
+// logged, it has to be fixed by hand and the next edit asks for a valid one.
+func migratePhones(ctx context.Context, collection *mongo.Collection, region string) error {
+	results, err := collection.Find(ctx, bson.M{"phone": bson.M{"$type": "number"}})
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	for results.Next(ctx) {
+		var stored storedPhone
+		if err := results.Decode(&stored); err != nil {
+			return err
+		}
+
+		phone := digits(stored.Phone)
+		if parsed, err := contact.ParsePhone(phone, region); err == nil {
+			phone = parsed
+		} else {
+			log.Printf("Error: the phone %s of %v in %s could not be read, it has to be fixed by hand", phone, stored.Id, collection.Name())
+		}
+
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": stored.Id}, bson.M{"$set": bson.M{"phone": phone}}); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
+
+// digits writes a phone stored as a number as text.
+func digits(phone interface{}) string {
+	switch value := phone.(type) {
+	case int32:
+		return strconv.FormatInt(int64(value), 10)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	case string:
+		return value
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"pet-appointments-api/configs"
+	"time"
+)
+
+// Migration changes the stored data once, when a release changes how something is stored. Migrations must be safe
+// to run again after failing halfway.
+type Migration struct {
+	Id          string
+	Description string
+	Up          func(ctx context.Context) error
+}
+
+// Applied is the record of a migration that ran.
+type Applied struct {
+	Id          string    `json:"id"`
+	StartDate   time.Time `json:"startDate"`
+	AppliedDate time.Time `json:"appliedDate,omitempty"`
+}
+
+// all are the migrations in the order they run. New migrations go at the end.
+var all = []Migration{
+	petBirthDate,
+	speciesCatalog,
+	ownerEmails,
+	contactPhones,
+	appointmentPrices,
+}
+
+var migrationCollection *mongo.Collection = configs.GetCollection(configs.DB, "migrations")
+
+// Run applies the migrations that were not applied yet, in order. Each one is claimed before it runs so two
+// instances starting together don't run it twice, and released if it fails so the next start retries it.
+func Run(ctx context.Context) error {
+	index := mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)}
+	if _, err := migrationCollection.Indexes().CreateOne(ctx, index); err != nil {
+		return err
+	}
+
+	for _, migration := range all {
+		var applied Applied
+		err := migrationCollection.FindOne(ctx, bson.M{"id": migration.Id}).Decode(&applied)
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		//the unique index on the id only lets one instance claim the migration
+		if _, err := migrationCollection.InsertOne(ctx, Applied{Id: migration.Id, StartDate: time.Now()}); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return err
+		}
+
+		log.Printf("Applying migration %s: %s", migration.Id, migration.Description)
+		if err := migration.Up(ctx); err != nil {
+			if _, releaseErr := migrationCollection.DeleteOne(ctx, bson.M{"id": migration.Id}); releaseErr != nil {
+				log.Println("Error: the migration " + migration.Id + " could not be released, " + releaseErr.Error())
+			}
+			return fmt.Errorf("migration %s: %w", migration.Id, err)
+		}
+
+		if _, err := migrationCollection.UpdateOne(ctx, bson.M{"id": migration.Id}, bson.M{"$set": bson.M{"applieddate": time.Now()}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/dedup"
+)
+
+// ownerEmails stores the emails of owners normalised, the way they are compared to find an owner registered twice.
+var ownerEmails = Migration{
+	Id:          "0003-owner-emails",
+	Description: "normalise the emails of owners",
+	Up:          migrateOwnerEmails,
+}
+
+// storedEmail is the part of an owner the migration changes.
+type storedEmail struct {
+	Id    interface{} `bson:"_id"`
+	Email string      `bson:"email"`
+}
+
+func migrateOwnerEmails(ctx context.Context) error {
+	owners := configs.GetCollection(configs.DB, "owners")
+
+	results, err := owners.Find(ctx, bson.M{"email": bson.M{"$type": "string"}})
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	for results.Next(ctx) {
+		var owner storedEmail
+		if err := results.Decode(&owner); err != nil {
+			return err
+		}
+
+		email := dedup.Email(owner.Email)
+		if email == owner.Email {
+			continue
+		}
+		if _, err := owners.UpdateOne(ctx, bson.M{"_id": owner.Id}, bson.M{"$set": bson.M{"email": email}}); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
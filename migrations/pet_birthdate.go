@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// petBirthDate replaces the age pets were created with by an estimated birth date: the age is counted back from
+// when the pet was created, and the birth date is marked as known only to the year.
+var petBirthDate = Migration{
+	Id:          "0001-pet-birthdate",
+	Description: "replace the stored age of pets with a birth date",
+	Up:          migratePetBirthDate,
+}
+
+// storedAge is the part of a pet stored before the migration.
+type storedAge struct {
+	Id           interface{} `bson:"_id"`
+	Age          int         `bson:"age"`
+	CreationDate time.Time   `bson:"creationdate"`
+}
+
+func migratePetBirthDate(ctx context.Context) error {
+	pets := configs.GetCollection(configs.DB, "pets")
+
+	results, err := pets.Find(ctx, bson.M{"age": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	for results.Next(ctx) {
+		var pet storedAge
+		if err := results.Decode(&pet); err != nil {
+			return err
+		}
+
+		created := pet.CreationDate
+		if created.IsZero() {
+			created = time.Now()
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"birthdate": created.AddDate(-pet.Age, 0, 0), "birthdateprecision": models.BirthDateYear},
+			"$unset": bson.M{"age": ""},
+		}
+		if _, err := pets.UpdateOne(ctx, bson.M{"_id": pet.Id}, update); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
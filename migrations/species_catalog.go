@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"pet-appointments-api/catalog"
+	"pet-appointments-api/configs"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// speciesCatalog fills the species catalog with its defaults and replaces the free text species and breeds of the
+// pets with catalog codes. Values with no close enough match are left as they are and logged, to be fixed by hand.
+var speciesCatalog = Migration{
+	Id:          "0002-species-catalog",
+	Description: "seed the species catalog and normalise the species and breeds of pets",
+	Up:          migrateSpeciesCatalog,
+}
+
+func migrateSpeciesCatalog(ctx context.Context) error {
+	speciesCollection := configs.GetCollection(configs.DB, "species")
+	breedCollection := configs.GetCollection(configs.DB, "breeds")
+	pets := configs.GetCollection(configs.DB, "pets")
+
+	//entries edited by the administrators in the meantime are kept
+	now := time.Now()
+	species, breeds := catalog.Defaults()
+	for _, s := range species {
+		s.Id, s.CreationDate = primitive.NewObjectID(), now
+		if _, err := speciesCollection.UpdateOne(ctx, bson.M{"code": s.Code}, bson.M{"$setOnInsert": s}, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+	for _, b := range breeds {
+		b.Id, b.CreationDate = primitive.NewObjectID(), now
+		if _, err := breedCollection.UpdateOne(ctx, bson.M{"speciescode": b.SpeciesCode, "code": b.Code}, bson.M{"$setOnInsert": b}, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+
+	cat, err := catalog.Load(ctx, speciesCollection, breedCollection)
+	if err != nil {
+		return err
+	}
+
+	results, err := pets.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	unmatched := 0
+	for results.Next(ctx) {
+		var pet models.Pet
+		if err := results.Decode(&pet); err != nil {
+			return err
+		}
+
+		species, ok := cat.ClosestSpecies(pet.PetType)
+		if !ok {
+			log.Printf("Pet %s: no species matches %q", pet.Id.Hex(), pet.PetType)
+			unmatched++
+			continue
+		}
+
+		set := bson.M{"pettype": species.Code}
+		if breed, ok := cat.ClosestBreed(species.Code, pet.Breed); ok {
+			set["breed"] = breed.Code
+		} else {
+			log.Printf("Pet %s: no %s breed matches %q", pet.Id.Hex(), species.Code, pet.Breed)
+			unmatched++
+		}
+
+		if _, err := pets.UpdateOne(ctx, bson.M{"id": pet.Id}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+	}
+	if unmatched > 0 {
+		log.Printf("%d species or breeds of pets are not in the catalog and were left as they were", unmatched)
+	}
+	return results.Err()
+}
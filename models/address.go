@@ -0,0 +1,53 @@
+package models
+
+import "strings"
+
+// Address is where an owner lives or a partner works. Its location is found from the rest of the address when it
+// isn't given.
+type Address struct {
+	Street     string    `json:"street,omitempty"`
+	City       string    `json:"city,omitempty" validate:"required"`
+	State      string    `json:"state,omitempty"`
+	PostalCode string    `json:"postalCode,omitempty"`
+	Country    string    `json:"country,omitempty" validate:"required,iso3166_1_alpha2"`
+	Location   *GeoPoint `json:"location,omitempty"`
+}
+
+// String writes the address on one line, like "221B Baker Street, London, NW1 6XE, GB".
+func (a Address) String() string {
+	var parts []string
+	for _, part := range []string{a.Street, a.City, a.State, a.PostalCode, a.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GeoPoint is a GeoJSON point, the shape MongoDB searches by distance. Its coordinates are the longitude first and
+// the latitude second.
+type GeoPoint struct {
+	Type        string    `json:"type" validate:"required,eq=Point"`
+	Coordinates []float64 `json:"coordinates" validate:"len=2"`
+}
+
+// NewGeoPoint returns the point at a latitude and a longitude.
+func NewGeoPoint(latitude float64, longitude float64) GeoPoint {
+	return GeoPoint{Type: "Point", Coordinates: []float64{longitude, latitude}}
+}
+
+// Latitude returns the latitude of the point.
+func (p GeoPoint) Latitude() float64 {
+	return p.Coordinates[1]
+}
+
+// Longitude returns the longitude of the point.
+func (p GeoPoint) Longitude() float64 {
+	return p.Coordinates[0]
+}
+
+// Valid tells whether the point is somewhere on Earth.
+func (p GeoPoint) Valid() bool {
+	return p.Type == "Point" && len(p.Coordinates) == 2 &&
+		p.Latitude() >= -90 && p.Latitude() <= 90 && p.Longitude() >= -180 && p.Longitude() <= 180
+}
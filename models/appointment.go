@@ -5,13 +5,48 @@ import (
 	"time"
 )
 
+// AppointmentStatus is the lifecycle state of an Appointment.
+type AppointmentStatus string
+
+const (
+	AppointmentScheduled AppointmentStatus = "scheduled"
+	AppointmentCompleted AppointmentStatus = "completed"
+	AppointmentCancelled AppointmentStatus = "cancelled"
+	AppointmentNoShow    AppointmentStatus = "no_show"
+)
+
+// ScheduledStatuses are the stored statuses of appointments still to happen, to query them with "$in". Appointments
+// stored before statuses were tracked have no status field at all, which only nil matches.
+var ScheduledStatuses = []interface{}{AppointmentScheduled, "", nil}
+
 type Appointment struct {
-	Id          primitive.ObjectID `json:"id,omitempty"`
-	OwnerId     string             `json:"ownerId,omitempty" validate:"required"`
-	PetId       string             `json:"petId,omitempty" validate:"required"`
-	PartnerId   string             `json:"partnerId,omitempty" validate:"required"`
-	Service     string             `json:"service,omitempty" validate:"required"`
-	Amount      float64            `json:"amount,omitempty" validate:"required"`
-	PaymentType string             `json:"paymentType,omitempty" validate:"required"`
-	Date        time.Time          `json:"date,omitempty" form:"date"`
+	Id              primitive.ObjectID       `json:"id,omitempty"`
+	OwnerId         string                   `json:"ownerId,omitempty" validate:"required"`
+	PetId           string                   `json:"petId,omitempty" validate:"required"`
+	PartnerId       string                   `json:"partnerId,omitempty" validate:"required"`
+	ServiceId       string                   `json:"serviceId,omitempty" validate:"required"`
+	Service         string                   `json:"service,omitempty"`
+	CouponCode      string                   `json:"couponCode,omitempty"`
+	Pricing         PriceBreakdown           `json:"pricing" validate:"-"`
+	PaymentType     PaymentMethod            `json:"paymentType,omitempty" validate:"required,oneof=cash card transfer"`
+	PaymentStatus   AppointmentPaymentStatus `json:"paymentStatus,omitempty"`
+	AmountPaid      Money                    `json:"amountPaid" validate:"-"`
+	PayoutId        string                   `json:"payoutId,omitempty" validate:"-"`
+	RemindersSent   []string                 `json:"remindersSent,omitempty" validate:"-"`
+	Status          AppointmentStatus        `json:"status,omitempty"`
+	Cancellation    *CancellationOutcome     `json:"cancellation,omitempty" validate:"-"`
+	Sequence        int                      `json:"sequence" validate:"-"`
+	Recurrence      string                   `json:"recurrence,omitempty"`
+	SeriesId        string                   `json:"seriesId,omitempty" validate:"-"`
+	OccurrenceIndex int                      `json:"occurrenceIndex,omitempty" validate:"-"`
+	HoldToken       string                   `json:"holdToken,omitempty" bson:"-"`
+	StartTime       time.Time                `json:"startTime,omitempty" validate:"required"`
+	EndTime         time.Time                `json:"endTime,omitempty"`
+	Date            time.Time                `json:"date,omitempty" form:"date"`
+	UpdateDate      time.Time                `json:"updateDate,omitempty" validate:"-"`
+}
+
+// IsScheduled tells whether the appointment is still to happen, so it can be edited or cancelled.
+func (a Appointment) IsScheduled() bool {
+	return a.Status == AppointmentScheduled || a.Status == ""
 }
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+// The prices and amounts of an appointment are computed by the API, so a booking request never sends them and
+// the validator must not require their currency.
+func TestAppointmentRequestValidates(t *testing.T) {
+	request := Appointment{
+		OwnerId:     "owner",
+		PetId:       "pet",
+		PartnerId:   "partner",
+		ServiceId:   "service",
+		PaymentType: PaymentMethodCash,
+		StartTime:   time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	if err := validator.New().Struct(&request); err != nil {
+		t.Fatalf("a booking request without prices was rejected: %v", err)
+	}
+}
+
+func TestAppointmentRequestRequiresPaymentType(t *testing.T) {
+	request := Appointment{OwnerId: "owner", PetId: "pet", PartnerId: "partner", ServiceId: "service", StartTime: time.Now()}
+
+	if err := validator.New().Struct(&request); err == nil {
+		t.Fatal("a booking request without a payment type was accepted")
+	}
+}
+
+// Appointments stored before statuses were tracked have no status field, and are still to happen.
+func TestAppointmentWithoutStatusIsScheduled(t *testing.T) {
+	stored, err := bson.Marshal(bson.M{"id": primitive.NewObjectID(), "partnerid": "partner", "starttime": time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var appointment Appointment
+	if err := bson.Unmarshal(stored, &appointment); err != nil {
+		t.Fatal(err)
+	}
+	if !appointment.IsScheduled() {
+		t.Error("an appointment without a status is not scheduled")
+	}
+
+	appointment.Status = AppointmentCancelled
+	if appointment.IsScheduled() {
+		t.Error("a cancelled appointment is scheduled")
+	}
+}
+
+// MongoDB only matches a missing field with null, so the query for scheduled appointments must hold one.
+func TestScheduledStatusesMatchMissingStatus(t *testing.T) {
+	filter, err := bson.Marshal(bson.M{"status": bson.M{"$in": ScheduledStatuses}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := bson.Raw(filter).LookupErr("status", "$in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	elements, err := values.Array().Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var null, scheduled bool
+	for _, element := range elements {
+		null = null || element.Type == bsontype.Null
+		if value, ok := element.StringValueOK(); ok && value == string(AppointmentScheduled) {
+			scheduled = true
+		}
+	}
+	if !null || !scheduled {
+		t.Errorf("got %v, want the scheduled status and null", elements)
+	}
+}
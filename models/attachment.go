@@ -0,0 +1,23 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Attachment is a file uploaded for a Pet, like a photo, or for an Appointment, like lab results or an X-ray.
+// Attachments of an appointment also belong to its pet. Who uploaded it is either the OwnerId or the PartnerId.
+type Attachment struct {
+	Id            primitive.ObjectID `json:"id,omitempty"`
+	PetId         string             `json:"petId,omitempty"`
+	AppointmentId string             `json:"appointmentId,omitempty"`
+	OwnerId       string             `json:"ownerId,omitempty"`
+	PartnerId     string             `json:"partnerId,omitempty"`
+	FileName      string             `json:"fileName,omitempty"`
+	Description   string             `json:"description,omitempty"`
+	ContentType   string             `json:"contentType,omitempty"`
+	Size          int64              `json:"size,omitempty"`
+	BlobKey       string             `json:"-"`
+	ThumbnailKey  string             `json:"-"`
+	CreationDate  time.Time          `json:"creationDate,omitempty" form:"date"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// BlockedSlot is time a Partner is not available, imported from one of their calendars. An occurrence is identified
+// by the calendar it came from, the UID of its event and its original start, so importing again updates it in place.
+type BlockedSlot struct {
+	Id              primitive.ObjectID `json:"id,omitempty"`
+	PartnerId       string             `json:"partnerId"`
+	Source          string             `json:"source"`
+	UID             string             `json:"uid"`
+	OccurrenceStart time.Time          `json:"occurrenceStart"`
+	Start           time.Time          `json:"start"`
+	End             time.Time          `json:"end"`
+	AllDay          bool               `json:"allDay,omitempty"`
+	Summary         string             `json:"summary,omitempty"`
+	ImportDate      time.Time          `json:"importDate"`
+	CreationDate    time.Time          `json:"creationDate,omitempty"`
+}
+
+// CalendarSource is the URL of an iCalendar feed the busy time of a Partner is imported from periodically.
+type CalendarSource struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	PartnerId    string             `json:"partnerId,omitempty" validate:"-"`
+	Name         string             `json:"name,omitempty" validate:"required"`
+	URL          string             `json:"url,omitempty" validate:"required,url"`
+	Disabled     bool               `json:"disabled"`
+	LastSync     *time.Time         `json:"lastSync,omitempty" validate:"-"`
+	LastError    string             `json:"lastError,omitempty" validate:"-"`
+	NextSync     time.Time          `json:"nextSync,omitempty" validate:"-"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+}
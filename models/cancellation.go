@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// CancellationPolicy is the set of rules a Partner applies when one of their appointments is cancelled.
+type CancellationPolicy struct {
+	FreeCancellationHours int `json:"freeCancellationHours" validate:"gte=0"`
+	LateFeePercent        int `json:"lateFeePercent" validate:"gte=0,lte=100"`
+	NoShowFeePercent      int `json:"noShowFeePercent" validate:"gte=0,lte=100"`
+}
+
+// DefaultCancellationPolicy applies to partners that did not configure their own.
+var DefaultCancellationPolicy = CancellationPolicy{FreeCancellationHours: 24, LateFeePercent: 50, NoShowFeePercent: 100}
+
+// CancellationOutcome records what happened to the money of a cancelled Appointment.
+type CancellationOutcome struct {
+	CancelledAt      time.Time          `json:"cancelledAt"`
+	Reason           string             `json:"reason,omitempty"`
+	NoShow           bool               `json:"noShow"`
+	HoursBeforeStart float64            `json:"hoursBeforeStart"`
+	Policy           CancellationPolicy `json:"policy"`
+	FeePercent       int                `json:"feePercent"`
+	Fee              Money              `json:"fee"`
+	Refund           Money              `json:"refund"`
+	RefundPayments   []string           `json:"refundPayments,omitempty"`
+	FeePaymentId     string             `json:"feePaymentId,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+// ContactType is what a ContactMethod is: a phone number or an email address.
+type ContactType string
+
+const (
+	ContactPhone ContactType = "phone"
+	ContactEmail ContactType = "email"
+)
+
+// Channels people are contacted through. They are the channels of the notifiers, which only write to an owner
+// through the ones the owner opted in to.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// ContactChannels are the channels that reach people, and so need their consent.
+var ContactChannels = []string{ChannelEmail, ChannelSMS}
+
+// IsContactChannel tells whether a channel reaches people, unlike the log of notifications.
+func IsContactChannel(channel string) bool {
+	for _, contactChannel := range ContactChannels {
+		if channel == contactChannel {
+			return true
+		}
+	}
+	return false
+}
+
+// ContactMethod is an extra phone number or email address, besides the main ones. The preferred one is how its
+// owner wants to be contacted. Phones are stored in E.164, like "+442079460958".
+type ContactMethod struct {
+	Type      ContactType `json:"type" validate:"required,oneof=phone email"`
+	Value     string      `json:"value" validate:"required"`
+	Label     string      `json:"label,omitempty"`
+	Preferred bool        `json:"preferred,omitempty"`
+}
+
+// Channel is the channel a contact method is reached through: SMS for phones.
+func (m ContactMethod) Channel() string {
+	if m.Type == ContactPhone {
+		return ChannelSMS
+	}
+	return ChannelEmail
+}
+
+// PreferredContact returns the contact method marked as preferred, if any.
+func PreferredContact(methods []ContactMethod) (ContactMethod, bool) {
+	for _, method := range methods {
+		if method.Preferred {
+			return method, true
+		}
+	}
+	return ContactMethod{}, false
+}
+
+// contactValue returns the value of the preferred contact method of a type, falling back to the main one.
+func contactValue(methods []ContactMethod, contactType ContactType, main string) string {
+	if method, ok := PreferredContact(methods); ok && method.Type == contactType {
+		return method.Value
+	}
+	return main
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+var (
+	ErrCouponInactive  = errors.New("coupon: the coupon is disabled")
+	ErrCouponExpired   = errors.New("coupon: the coupon is not valid at this date")
+	ErrCouponExhausted = errors.New("coupon: the coupon reached its maximum number of redemptions")
+	ErrCouponPartner   = errors.New("coupon: the coupon is not valid for this partner")
+)
+
+// DiscountKind tells whether a discount takes a percentage or a fixed amount off the price.
+type DiscountKind string
+
+const (
+	DiscountPercentage DiscountKind = "percentage"
+	DiscountFixed      DiscountKind = "fixed"
+)
+
+type Coupon struct {
+	Id             primitive.ObjectID `json:"id,omitempty"`
+	Code           string             `json:"code,omitempty" validate:"required,alphanum"`
+	Kind           DiscountKind       `json:"kind,omitempty" validate:"required,oneof=percentage fixed"`
+	BasisPoints    int64              `json:"basisPoints,omitempty" validate:"required_if=Kind percentage,lte=10000"`
+	Amount         *Money             `json:"amount,omitempty" validate:"required_if=Kind fixed"`
+	PartnerId      string             `json:"partnerId,omitempty"`
+	ValidFrom      *time.Time         `json:"validFrom,omitempty"`
+	ValidUntil     *time.Time         `json:"validUntil,omitempty"`
+	MaxRedemptions int                `json:"maxRedemptions,omitempty" validate:"gte=0"`
+	Redemptions    int                `json:"redemptions"`
+	Disabled       bool               `json:"disabled"`
+	CreationDate   time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// Check tells whether the coupon can be redeemed with the given partner at the given time.
+func (c Coupon) Check(partnerId string, at time.Time) error {
+	switch {
+	case c.Disabled:
+		return ErrCouponInactive
+	case c.ValidFrom != nil && at.Before(*c.ValidFrom), c.ValidUntil != nil && at.After(*c.ValidUntil):
+		return ErrCouponExpired
+	case c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions:
+		return ErrCouponExhausted
+	case c.PartnerId != "" && c.PartnerId != partnerId:
+		return ErrCouponPartner
+	}
+	return nil
+}
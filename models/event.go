@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Domain event types, named "<entity>.<what happened>".
+const (
+	EventAppointmentCreated     = "appointment.created"
+	EventAppointmentUpdated     = "appointment.updated"
+	EventAppointmentRescheduled = "appointment.rescheduled"
+	EventAppointmentCancelled   = "appointment.cancelled"
+	EventAppointmentCompleted   = "appointment.completed"
+	EventAppointmentDeleted     = "appointment.deleted"
+	EventOwnerCreated           = "owner.created"
+	EventOwnerUpdated           = "owner.updated"
+	EventOwnerDeleted           = "owner.deleted"
+	EventOwnerMerged            = "owner.merged"
+	EventPetCreated             = "pet.created"
+	EventPetUpdated             = "pet.updated"
+	EventPetDeleted             = "pet.deleted"
+	EventPetTransferred         = "pet.transferred"
+	EventPartnerCreated         = "partner.created"
+	EventPartnerUpdated         = "partner.updated"
+	EventPartnerDeleted         = "partner.deleted"
+)
+
+// Event is something that happened to an entity, as told to the outside world. PartnerId is the partner the event
+// concerns, if any: only its webhook subscriptions are told about it. Events about owners and pets have none, they
+// are told to the partners the owner or the pet has appointments with.
+type Event struct {
+	Id         string      `json:"id"`
+	Type       string      `json:"type"`
+	PartnerId  string      `json:"partnerId,omitempty"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
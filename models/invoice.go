@@ -0,0 +1,48 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// InvoiceParty is a snapshot of who issued or received an Invoice, so later edits don't change issued documents.
+type InvoiceParty struct {
+	Id       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	IdNumber int    `json:"idNumber,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+}
+
+type InvoiceLine struct {
+	Description string `json:"description,omitempty"`
+	Quantity    int64  `json:"quantity,omitempty"`
+	UnitPrice   Money  `json:"unitPrice"`
+	Total       Money  `json:"total"`
+}
+
+type InvoiceTax struct {
+	Name        string `json:"name,omitempty"`
+	BasisPoints int64  `json:"basisPoints"`
+	Amount      Money  `json:"amount"`
+}
+
+type Invoice struct {
+	Id            primitive.ObjectID `json:"id,omitempty"`
+	Number        string             `json:"number,omitempty"`
+	Sequence      int64              `json:"sequence,omitempty"`
+	AppointmentId string             `json:"appointmentId,omitempty"`
+	PartnerId     string             `json:"partnerId,omitempty"`
+	Partner       InvoiceParty       `json:"partner"`
+	Owner         InvoiceParty       `json:"owner"`
+	PetName       string             `json:"petName,omitempty"`
+	ServiceDate   time.Time          `json:"serviceDate,omitempty"`
+	Lines         []InvoiceLine      `json:"lines,omitempty"`
+	Subtotal      Money              `json:"subtotal"`
+	Discounts     []DiscountLine     `json:"discounts,omitempty"`
+	Taxes         []InvoiceTax       `json:"taxes,omitempty"`
+	Total         Money              `json:"total"`
+	AmountPaid    Money              `json:"amountPaid"`
+	PaymentMethod PaymentMethod      `json:"paymentMethod,omitempty"`
+	IssueDate     time.Time          `json:"issueDate,omitempty" form:"date"`
+}
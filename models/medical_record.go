@@ -0,0 +1,74 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Diagnosis is a finding of a visit, optionally coded (for example with a VeNom code).
+type Diagnosis struct {
+	Code        string `json:"code,omitempty"`
+	Description string `json:"description,omitempty" validate:"required"`
+}
+
+// VisitNote is what a Partner wrote down about a completed Appointment. A note with a weight also adds it to the
+// weight history of the pet.
+type VisitNote struct {
+	Id            primitive.ObjectID `json:"id,omitempty"`
+	PetId         string             `json:"petId,omitempty" validate:"-"`
+	AppointmentId string             `json:"appointmentId,omitempty" validate:"-"`
+	PartnerId     string             `json:"partnerId,omitempty" validate:"-"`
+	Notes         string             `json:"notes,omitempty" validate:"required"`
+	Diagnoses     []Diagnosis        `json:"diagnoses,omitempty" validate:"dive"`
+	WeightKg      float64            `json:"weightKg,omitempty" validate:"omitempty,gt=0"`
+	VisitDate     time.Time          `json:"visitDate,omitempty" validate:"-"`
+	CreationDate  time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate    time.Time          `json:"updateDate,omitempty" validate:"-"`
+}
+
+// WeightEntry is the weight of a pet at a date.
+type WeightEntry struct {
+	Id            primitive.ObjectID `json:"id,omitempty"`
+	PetId         string             `json:"petId,omitempty" validate:"-"`
+	WeightKg      float64            `json:"weightKg,omitempty" validate:"required,gt=0"`
+	Date          time.Time          `json:"date,omitempty"`
+	AppointmentId string             `json:"appointmentId,omitempty" validate:"-"`
+	PartnerId     string             `json:"partnerId,omitempty" validate:"-"`
+	CreationDate  time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// Allergy is something a pet reacts to.
+type Allergy struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	PetId        string             `json:"petId,omitempty" validate:"-"`
+	Substance    string             `json:"substance,omitempty" validate:"required"`
+	Reaction     string             `json:"reaction,omitempty"`
+	Severity     string             `json:"severity,omitempty" validate:"omitempty,oneof=mild moderate severe"`
+	PartnerId    string             `json:"partnerId,omitempty" validate:"-"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// ChronicCondition is a long-term condition of a pet. Resolved conditions are kept in the record.
+type ChronicCondition struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	PetId        string             `json:"petId,omitempty" validate:"-"`
+	Name         string             `json:"name,omitempty" validate:"required"`
+	Since        *time.Time         `json:"since,omitempty"`
+	Notes        string             `json:"notes,omitempty"`
+	Resolved     bool               `json:"resolved"`
+	PartnerId    string             `json:"partnerId,omitempty" validate:"-"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate   time.Time          `json:"updateDate,omitempty" validate:"-"`
+}
+
+// MedicalRecord is everything known about the health of a pet, put together from its visit notes, weights,
+// allergies, conditions, vaccinations and prescriptions.
+type MedicalRecord struct {
+	Pet           Pet                `json:"pet"`
+	Visits        []VisitNote        `json:"visits"`
+	Weights       []WeightEntry      `json:"weights"`
+	Allergies     []Allergy          `json:"allergies"`
+	Conditions    []ChronicCondition `json:"conditions"`
+	Vaccinations  []Vaccination      `json:"vaccinations"`
+	Prescriptions []Prescription     `json:"prescriptions"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned when two amounts with different currencies are combined.
+var ErrCurrencyMismatch = errors.New("money: currency mismatch")
+
+// Money is an amount expressed in the minor unit of its ISO 4217 currency (e.g. cents for USD),
+// so prices are never stored or added up as floats.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency,omitempty" validate:"required,iso4217"`
+}
+
+// Currencies whose minor unit is not the cent.
+var currencyExponents = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "OMR": 3, "PYG": 0, "TND": 3, "UYI": 0, "VND": 0,
+}
+
+// NewMoney returns an amount of the given currency, expressed in minor units.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// FromDecimal returns an amount given in major units, e.g. 12.5 USD, rounded to the nearest minor unit.
+func FromDecimal(amount float64, currency string) Money {
+	currency = strings.ToUpper(currency)
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		exponent = 2
+	}
+	return Money{Amount: int64(math.Round(amount * math.Pow10(exponent))), Currency: currency}
+}
+
+// Zero returns a zero amount in the given currency.
+func Zero(currency string) Money {
+	return NewMoney(0, currency)
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns m + other. Both amounts must share the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both amounts must share the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+// Multiply returns m * quantity.
+func (m Money) Multiply(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// Percentage returns the given share of m, expressed in basis points (1% = 100), rounded half away from zero
+// to the nearest minor unit.
+func (m Money) Percentage(basisPoints int64) Money {
+	return Money{Amount: divRound(m.Amount*basisPoints, 10000), Currency: m.Currency}
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or +1.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String formats the amount in major units, e.g. "12.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Decimal formats the amount in major units without the currency, e.g. "12.50".
+func (m Money) Decimal() string {
+	exponent, ok := currencyExponents[m.Currency]
+	if !ok {
+		exponent = 2
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+
+	unit := int64(1)
+	for i := 0; i < exponent; i++ {
+		unit *= 10
+	}
+
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/unit, exponent, amount%unit)
+}
+
+// divRound divides n by d rounding half away from zero.
+func divRound(n, d int64) int64 {
+	quotient, remainder := n/d, n%d
+	if remainder < 0 {
+		remainder = -remainder
+	}
+	if remainder*2 >= d {
+		if n < 0 {
+			return quotient - 1
+		}
+		return quotient + 1
+	}
+	return quotient
+}
@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestFromDecimal(t *testing.T) {
+	cases := []struct {
+		amount   float64
+		currency string
+		want     Money
+	}{
+		{12.5, "usd", Money{Amount: 1250, Currency: "USD"}},
+		//floats like 0.1 + 0.2 are rounded to the nearest cent, not truncated
+		{0.1 + 0.2, "EUR", Money{Amount: 30, Currency: "EUR"}},
+		{19.99, "EUR", Money{Amount: 1999, Currency: "EUR"}},
+		{1500, "JPY", Money{Amount: 1500, Currency: "JPY"}},
+		{1.2345, "KWD", Money{Amount: 1235, Currency: "KWD"}},
+	}
+
+	for _, c := range cases {
+		if got := FromDecimal(c.amount, c.currency); got != c.want {
+			t.Errorf("FromDecimal(%v, %s) = %+v, want %+v", c.amount, c.currency, got, c.want)
+		}
+	}
+}
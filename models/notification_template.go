@@ -0,0 +1,31 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// NotificationEvent is what a notification is about.
+type NotificationEvent string
+
+const (
+	EventBookingConfirmed NotificationEvent = "booking_confirmed"
+	EventRescheduled      NotificationEvent = "rescheduled"
+	EventCancelled        NotificationEvent = "cancelled"
+	EventReminder         NotificationEvent = "reminder"
+	EventInvoiceReady     NotificationEvent = "invoice_ready"
+	EventWaitlistOffer    NotificationEvent = "waitlist_offer"
+	EventVaccinationDue   NotificationEvent = "vaccination_due"
+)
+
+// NotificationTemplate is the subject and body of the notifications sent for an event in a language, written as
+// Go text templates.
+type NotificationTemplate struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	Event        NotificationEvent  `json:"event,omitempty" validate:"required,oneof=booking_confirmed rescheduled cancelled reminder invoice_ready waitlist_offer vaccination_due"`
+	Language     string             `json:"language,omitempty" validate:"required,bcp47_language_tag"`
+	Subject      string             `json:"subject,omitempty" validate:"required"`
+	Body         string             `json:"body,omitempty" validate:"required"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate   time.Time          `json:"updateDate,omitempty" form:"date"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxPublished OutboxStatus = "published"
+)
+
+// OutboxRecord is an Event waiting to be published. It is written in the same transaction as the change it
+// describes, so the event is never lost even if the process dies before publishing it.
+type OutboxRecord struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	EventId      string             `json:"eventId"`
+	EventType    string             `json:"eventType"`
+	Payload      string             `json:"payload"`
+	Status       OutboxStatus       `json:"status"`
+	Attempts     int                `json:"attempts"`
+	LastError    string             `json:"lastError,omitempty"`
+	NextAttempt  time.Time          `json:"nextAttempt"`
+	CreationDate time.Time          `json:"creationDate"`
+	PublishDate  *time.Time         `json:"publishDate,omitempty"`
+}
@@ -6,12 +6,27 @@ import (
 )
 
 type Owner struct {
-	Id           primitive.ObjectID `json:"id,omitempty"`
-	Name         string             `json:"name,omitempty" validate:"required"`
-	LastName     string             `json:"lastName,omitempty" validate:"required"`
-	IdNumber     int                `json:"idNumber,omitempty" validate:"required"`
-	Phone        int                `json:"phone,omitempty" validate:"required"`
-	Email        string             `json:"email,omitempty" validate:"required"`
-	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
-	Pets         []string           `json:"pets,omitempty"`
+	Id             primitive.ObjectID `json:"id,omitempty"`
+	Name           string             `json:"name,omitempty" validate:"required"`
+	LastName       string             `json:"lastName,omitempty" validate:"required"`
+	IdNumber       int                `json:"idNumber,omitempty" validate:"required"`
+	Phone          string             `json:"phone,omitempty" validate:"required,e164"`
+	Email          string             `json:"email,omitempty" validate:"required,email"`
+	ContactMethods []ContactMethod    `json:"contactMethods,omitempty" validate:"omitempty,dive"`
+	OptIns         map[string]bool    `json:"optIns,omitempty" validate:"omitempty,dive,keys,oneof=email sms,endkeys"`
+	Address        *Address           `json:"address,omitempty"`
+	CreationDate   time.Time          `json:"creationDate,omitempty" form:"date"`
+	Pets           []string           `json:"pets,omitempty"`
+	Language       string             `json:"language,omitempty" validate:"omitempty,bcp47_language_tag"`
+	CalendarToken  string             `json:"-"`
+}
+
+// ContactPhone returns the phone number to write to the owner at: the preferred one, or the main one.
+func (o Owner) ContactPhone() string {
+	return contactValue(o.ContactMethods, ContactPhone, o.Phone)
+}
+
+// ContactEmail returns the email address to write to the owner at: the preferred one, or the main one.
+func (o Owner) ContactEmail() string {
+	return contactValue(o.ContactMethods, ContactEmail, o.Email)
 }
@@ -6,12 +6,46 @@ import (
 )
 
 type Partner struct {
-	Id           primitive.ObjectID `json:"id,omitempty"`
-	Name         string             `json:"name,omitempty" validate:"required"`
-	LastName     string             `json:"lastName,omitempty" validate:"required"`
-	IdNumber     int                `json:"idNumber,omitempty" validate:"required"`
-	Phone        int                `json:"phone,omitempty" validate:"required"`
-	Email        string             `json:"email,omitempty" validate:"required"`
-	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
-	Services     []string           `json:"services,omitempty"`
+	Id                 primitive.ObjectID  `json:"id,omitempty"`
+	Name               string              `json:"name,omitempty" validate:"required"`
+	LastName           string              `json:"lastName,omitempty" validate:"required"`
+	IdNumber           int                 `json:"idNumber,omitempty" validate:"required"`
+	Phone              string              `json:"phone,omitempty" validate:"required,e164"`
+	Email              string              `json:"email,omitempty" validate:"required,email"`
+	ContactMethods     []ContactMethod     `json:"contactMethods,omitempty" validate:"omitempty,dive"`
+	Address            *Address            `json:"address,omitempty"`
+	CreationDate       time.Time           `json:"creationDate,omitempty" form:"date"`
+	Services           []string            `json:"services,omitempty"`
+	CancellationPolicy *CancellationPolicy `json:"cancellationPolicy,omitempty"`
+	Region             string              `json:"region,omitempty"`
+	MultiPetDiscount   *MultiPetDiscount   `json:"multiPetDiscount,omitempty"`
+	Commission         *CommissionRule     `json:"commission,omitempty"`
+	TimeZone           string              `json:"timeZone,omitempty" validate:"omitempty,timezone"`
+	CalendarToken      string              `json:"-"`
+}
+
+// Policy returns the cancellation policy of the partner, or the default one when none was configured.
+func (p Partner) Policy() CancellationPolicy {
+	if p.CancellationPolicy == nil {
+		return DefaultCancellationPolicy
+	}
+	return *p.CancellationPolicy
+}
+
+// Location returns the time zone the partner works in, UTC when none was configured.
+func (p Partner) Location() *time.Location {
+	location, err := time.LoadLocation(p.TimeZone)
+	if err != nil || p.TimeZone == "" {
+		return time.UTC
+	}
+	return location
+}
+
+// CommissionRule returns the commission the platform takes from the partner, or a percentage-only rule with the
+// given default when none was configured.
+func (p Partner) CommissionRule(defaultBasisPoints int64) CommissionRule {
+	if p.Commission == nil {
+		return CommissionRule{BasisPoints: defaultBasisPoints}
+	}
+	return *p.Commission
 }
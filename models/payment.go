@@ -0,0 +1,62 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// PaymentMethod is the way an appointment is paid.
+type PaymentMethod string
+
+const (
+	PaymentMethodCash     PaymentMethod = "cash"
+	PaymentMethodCard     PaymentMethod = "card"
+	PaymentMethodTransfer PaymentMethod = "transfer"
+)
+
+// PaymentStatus is the lifecycle state of a single Payment.
+type PaymentStatus string
+
+const (
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusProcessing PaymentStatus = "processing"
+	PaymentStatusCaptured   PaymentStatus = "captured"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusFailed     PaymentStatus = "failed"
+)
+
+// PaymentKind tells whether a Payment settles the whole appointment or only part of it.
+type PaymentKind string
+
+const (
+	PaymentKindDeposit PaymentKind = "deposit"
+	PaymentKindPartial PaymentKind = "partial"
+	PaymentKindFull    PaymentKind = "full"
+	PaymentKindFee     PaymentKind = "cancellation_fee"
+)
+
+// AppointmentPaymentStatus summarizes all the payments recorded for an Appointment.
+type AppointmentPaymentStatus string
+
+const (
+	AppointmentUnpaid        AppointmentPaymentStatus = "unpaid"
+	AppointmentPartiallyPaid AppointmentPaymentStatus = "partially_paid"
+	AppointmentPaid          AppointmentPaymentStatus = "paid"
+	AppointmentRefunded      AppointmentPaymentStatus = "refunded"
+)
+
+type Payment struct {
+	Id                primitive.ObjectID `json:"id,omitempty"`
+	AppointmentId     string             `json:"appointmentId,omitempty" validate:"required"`
+	Method            PaymentMethod      `json:"method,omitempty" validate:"required,oneof=cash card transfer"`
+	Kind              PaymentKind        `json:"kind,omitempty" validate:"required,oneof=deposit partial full cancellation_fee"`
+	Amount            Money              `json:"amount" validate:"required"`
+	RefundedAmount    Money              `json:"refundedAmount" validate:"-"`
+	Status            PaymentStatus      `json:"status,omitempty"`
+	Provider          string             `json:"provider,omitempty"`
+	ProviderReference string             `json:"providerReference,omitempty"`
+	FailureReason     string             `json:"failureReason,omitempty"`
+	CreationDate      time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate        time.Time          `json:"updateDate,omitempty" form:"date"`
+}
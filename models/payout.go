@@ -0,0 +1,51 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// CommissionRule is the cut the platform takes from each appointment of a Partner: a percentage of the amount before
+// taxes plus an optional fixed fee.
+type CommissionRule struct {
+	BasisPoints int64  `json:"basisPoints" validate:"gte=0,lte=10000"`
+	FixedFee    *Money `json:"fixedFee,omitempty"`
+}
+
+// PayoutStatus is the lifecycle state of a Payout: drafts are approved, then paid.
+type PayoutStatus string
+
+const (
+	PayoutDraft    PayoutStatus = "draft"
+	PayoutApproved PayoutStatus = "approved"
+	PayoutPaid     PayoutStatus = "paid"
+)
+
+type PayoutLine struct {
+	AppointmentId string    `json:"appointmentId,omitempty"`
+	ServiceDate   time.Time `json:"serviceDate,omitempty"`
+	Service       string    `json:"service,omitempty"`
+	Gross         Money     `json:"gross"`
+	Taxes         Money     `json:"taxes"`
+	Commission    Money     `json:"commission"`
+	Net           Money     `json:"net"`
+}
+
+// Payout is the statement of what the platform owes a Partner for the appointments of a period.
+type Payout struct {
+	Id               primitive.ObjectID `json:"id,omitempty"`
+	PartnerId        string             `json:"partnerId,omitempty"`
+	PeriodStart      time.Time          `json:"periodStart,omitempty"`
+	PeriodEnd        time.Time          `json:"periodEnd,omitempty"`
+	Commission       CommissionRule     `json:"commissionRule"`
+	Lines            []PayoutLine       `json:"lines,omitempty"`
+	Gross            Money              `json:"gross"`
+	Taxes            Money              `json:"taxes"`
+	CommissionTotal  Money              `json:"commission"`
+	Net              Money              `json:"net"`
+	Status           PayoutStatus       `json:"status,omitempty"`
+	PaymentReference string             `json:"paymentReference,omitempty"`
+	CreationDate     time.Time          `json:"creationDate,omitempty" form:"date"`
+	ApprovalDate     *time.Time         `json:"approvalDate,omitempty"`
+	PaymentDate      *time.Time         `json:"paymentDate,omitempty"`
+}
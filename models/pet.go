@@ -2,15 +2,100 @@ package models
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
 	"time"
 )
 
+// BirthDatePrecision is how much of the birth date of a Pet is known. Rescued and adopted pets often only have an
+// estimated month or year of birth.
+type BirthDatePrecision string
+
+const (
+	BirthDateDay   BirthDatePrecision = "day"
+	BirthDateMonth BirthDatePrecision = "month"
+	BirthDateYear  BirthDatePrecision = "year"
+)
+
+// Pet is an animal of an Owner. Its Age is not stored, it is computed from the birth date when the pet is read.
 type Pet struct {
-	Id           primitive.ObjectID `json:"id,omitempty"`
-	OwnerId      string             `json:"ownerId,omitempty" validate:"required"`
-	Name         string             `json:"name,omitempty" validate:"required"`
-	Age          int                `json:"age,omitempty" validate:"required"`
-	PetType      string             `json:"petType,omitempty" validate:"required"`
-	Breed        string             `json:"breed,omitempty" validate:"required"`
-	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+	Id                 primitive.ObjectID `json:"id,omitempty"`
+	OwnerId            string             `json:"ownerId,omitempty" validate:"required"`
+	Name               string             `json:"name,omitempty" validate:"required"`
+	BirthDate          time.Time          `json:"birthDate,omitempty" validate:"required,lte"`
+	BirthDatePrecision BirthDatePrecision `json:"birthDatePrecision,omitempty" validate:"omitempty,oneof=day month year"`
+	Age                *PetAge            `json:"age,omitempty" bson:"-" validate:"-"`
+	PetType            string             `json:"petType,omitempty" validate:"required"`
+	Breed              string             `json:"breed,omitempty" validate:"required"`
+	Vaccinations       []Vaccination      `json:"-" validate:"-"`
+	PastOwners         []PastOwner        `json:"pastOwners,omitempty" validate:"-"`
+	CreationDate       time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// PetAge is the age of a Pet in whole years and months. It is approximate when only the month or the year of birth
+// is known, and the months are left out when only the year is.
+type PetAge struct {
+	Years       int  `json:"years"`
+	Months      int  `json:"months"`
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// AgeAt returns the age of the pet at a time.
+func (p Pet) AgeAt(now time.Time) PetAge {
+	birth := p.BirthDate.UTC()
+	now = now.UTC()
+
+	months := (now.Year()-birth.Year())*12 + int(now.Month()-birth.Month())
+	if now.Day() < birth.Day() && p.BirthDatePrecision != BirthDateMonth && p.BirthDatePrecision != BirthDateYear {
+		months--
+	}
+	if months < 0 {
+		months = 0
+	}
+
+	age := PetAge{Years: months / 12, Months: months % 12}
+	switch p.BirthDatePrecision {
+	case BirthDateMonth:
+		age.Approximate = true
+	case BirthDateYear:
+		age.Approximate = true
+		age.Months = 0
+	}
+	return age
+}
+
+// WithAge returns the pet with its Age at a time filled in.
+func (p Pet) WithAge(now time.Time) Pet {
+	age := p.AgeAt(now)
+	p.Age = &age
+	return p
+}
+
+// Vaccination is a dose of a vaccine given to a Pet by a Partner. NextDueDate is when the next dose is due, if any.
+type Vaccination struct {
+	Id            primitive.ObjectID `json:"id,omitempty"`
+	Vaccine       string             `json:"vaccine,omitempty" validate:"required"`
+	Batch         string             `json:"batch,omitempty"`
+	DateGiven     time.Time          `json:"dateGiven,omitempty" validate:"required"`
+	PartnerId     string             `json:"partnerId,omitempty" validate:"-"`
+	NextDueDate   *time.Time         `json:"nextDueDate,omitempty"`
+	RemindersSent []string           `json:"remindersSent,omitempty" validate:"-"`
+	CreationDate  time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// CurrentVaccinations returns the last dose given of each vaccine, which are the ones whose due dates matter.
+// Vaccines are compared without caring about case.
+func (p Pet) CurrentVaccinations() []Vaccination {
+	latest := map[string]int{}
+	var current []Vaccination
+	for _, vaccination := range p.Vaccinations {
+		vaccine := strings.ToLower(strings.TrimSpace(vaccination.Vaccine))
+		index, ok := latest[vaccine]
+		if !ok {
+			latest[vaccine] = len(current)
+			current = append(current, vaccination)
+		} else if vaccination.DateGiven.After(current[index].DateGiven) {
+			current[index] = vaccination
+		}
+	}
+	return current
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// PetTransferStatus is the lifecycle state of a PetTransfer.
+type PetTransferStatus string
+
+const (
+	TransferPending   PetTransferStatus = "pending"
+	TransferAccepted  PetTransferStatus = "accepted"
+	TransferDeclined  PetTransferStatus = "declined"
+	TransferCancelled PetTransferStatus = "cancelled"
+	TransferExpired   PetTransferStatus = "expired"
+)
+
+// PetTransfer is the request of the owner of a Pet to hand it over to another owner. The pet only changes hands
+// when the receiving owner accepts it before it expires.
+type PetTransfer struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	PetId        string             `json:"petId,omitempty" validate:"-"`
+	FromOwnerId  string             `json:"fromOwnerId,omitempty" validate:"-"`
+	ToOwnerId    string             `json:"toOwnerId,omitempty" validate:"required"`
+	Reason       string             `json:"reason,omitempty"`
+	Status       PetTransferStatus  `json:"status,omitempty" validate:"-"`
+	ExpiresAt    time.Time          `json:"expiresAt,omitempty" validate:"-"`
+	DecisionDate *time.Time         `json:"decisionDate,omitempty" validate:"-"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// PastOwner is an owner a Pet had before it was transferred, until the date it was.
+type PastOwner struct {
+	OwnerId    string    `json:"ownerId"`
+	Until      time.Time `json:"until"`
+	TransferId string    `json:"transferId"`
+	Reason     string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// DoseFrequency is how often a medication is given.
+type DoseFrequency string
+
+const (
+	FrequencyOnce            DoseFrequency = "once"
+	FrequencyDaily           DoseFrequency = "daily"
+	FrequencyTwiceDaily      DoseFrequency = "twice_daily"
+	FrequencyThreeTimesDaily DoseFrequency = "three_times_daily"
+	FrequencyFourTimesDaily  DoseFrequency = "four_times_daily"
+	FrequencyEveryOtherDay   DoseFrequency = "every_other_day"
+	FrequencyWeekly          DoseFrequency = "weekly"
+	FrequencyAsNeeded        DoseFrequency = "as_needed"
+)
+
+// Refill is a new supply of a prescription, covering another course of DurationDays.
+type Refill struct {
+	Date      time.Time `json:"date"`
+	PartnerId string    `json:"partnerId,omitempty"`
+}
+
+// Prescription is a medication a Partner prescribed to a Pet during an Appointment. Each fill, the first one and
+// every refill, covers DurationDays of doses.
+type Prescription struct {
+	Id             primitive.ObjectID `json:"id,omitempty"`
+	PetId          string             `json:"petId,omitempty" validate:"-"`
+	AppointmentId  string             `json:"appointmentId,omitempty" validate:"required"`
+	PartnerId      string             `json:"partnerId,omitempty" validate:"-"`
+	Drug           string             `json:"drug,omitempty" validate:"required"`
+	Dose           string             `json:"dose,omitempty" validate:"required"`
+	Frequency      DoseFrequency      `json:"frequency,omitempty" validate:"required,oneof=once daily twice_daily three_times_daily four_times_daily every_other_day weekly as_needed"`
+	DurationDays   int                `json:"durationDays,omitempty" validate:"required_unless=Frequency once Frequency as_needed,gte=0"`
+	Instructions   string             `json:"instructions,omitempty"`
+	StartDate      time.Time          `json:"startDate,omitempty"`
+	RefillsAllowed int                `json:"refillsAllowed" validate:"gte=0"`
+	Refills        []Refill           `json:"refills,omitempty" validate:"-"`
+	StopDate       *time.Time         `json:"stopDate,omitempty" validate:"-"`
+	StopReason     string             `json:"stopReason,omitempty" validate:"-"`
+	CreationDate   time.Time          `json:"creationDate,omitempty" form:"date"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// DiscountLine is a single discount applied to the price of an Appointment.
+type DiscountLine struct {
+	Name   string `json:"name,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Amount Money  `json:"amount"`
+}
+
+// TaxLine is a single tax charged on the price of an Appointment.
+type TaxLine struct {
+	Name        string `json:"name,omitempty"`
+	BasisPoints int64  `json:"basisPoints"`
+	Amount      Money  `json:"amount"`
+}
+
+// PriceBreakdown is how the total price of an Appointment was computed: taxes are charged on the subtotal minus
+// the discounts.
+type PriceBreakdown struct {
+	Subtotal  Money          `json:"subtotal"`
+	Discounts []DiscountLine `json:"discounts,omitempty"`
+	Taxes     []TaxLine      `json:"taxes,omitempty"`
+	Total     Money          `json:"total"`
+}
+
+// MultiPetDiscount rewards owners that bring several pets to the same partner on the same day. The discount applies
+// from the MinPets-th pet on.
+type MultiPetDiscount struct {
+	MinPets     int   `json:"minPets" validate:"gte=2"`
+	BasisPoints int64 `json:"basisPoints" validate:"gt=0,lte=10000"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type Service struct {
+	Id              primitive.ObjectID `json:"id,omitempty"`
+	Name            string             `json:"name,omitempty" validate:"required"`
+	Category        string             `json:"category,omitempty"`
+	DurationMinutes int                `json:"durationMinutes,omitempty" validate:"required,gt=0"`
+	Price           Money              `json:"price" validate:"required"`
+	PartnerPrices   []PartnerPrice     `json:"partnerPrices,omitempty" validate:"dive"`
+	CreationDate    time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// PartnerPrice overrides the catalog price of a Service for a single Partner.
+type PartnerPrice struct {
+	PartnerId string `json:"partnerId,omitempty" validate:"required"`
+	Price     Money  `json:"price" validate:"required"`
+}
+
+// PriceFor returns the price the given partner charges for the service, falling back to the catalog price.
+func (s Service) PriceFor(partnerId string) Money {
+	for _, override := range s.PartnerPrices {
+		if override.PartnerId == partnerId {
+			return override.Price
+		}
+	}
+	return s.Price
+}
+
+// Duration returns how long an appointment for the service lasts.
+func (s Service) Duration() time.Duration {
+	return time.Duration(s.DurationMinutes) * time.Minute
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// SlotHold reserves the time a service takes with a Partner for a few minutes, while the owner fills in the
+// booking. Whoever has the token can book the slot, everybody else sees it as busy until it expires.
+type SlotHold struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	Token        string             `json:"token,omitempty" validate:"-"`
+	OwnerId      string             `json:"ownerId,omitempty" validate:"-"`
+	PartnerId    string             `json:"partnerId,omitempty" validate:"required"`
+	ServiceId    string             `json:"serviceId,omitempty" validate:"required"`
+	StartTime    time.Time          `json:"startTime,omitempty" validate:"required"`
+	EndTime      time.Time          `json:"endTime,omitempty" validate:"-"`
+	ExpiresAt    time.Time          `json:"expiresAt,omitempty" validate:"-"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+}
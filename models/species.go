@@ -0,0 +1,51 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// CatalogLanguage is the language catalog names fall back to.
+const CatalogLanguage = "en"
+
+// Species is an entry of the species catalog. Pets store its Code as their PetType; the localized Names and the
+// Aliases are what people may write instead of the code.
+type Species struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	Code         string             `json:"code,omitempty" validate:"required"`
+	Names        map[string]string  `json:"names,omitempty" validate:"required,min=1,dive,keys,len=2,endkeys,required"`
+	Aliases      []string           `json:"aliases,omitempty" validate:"dive,required"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate   time.Time          `json:"updateDate,omitempty" validate:"-"`
+}
+
+// Breed is an entry of the breed catalog of a Species. Pets store its Code as their Breed.
+type Breed struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	SpeciesCode  string             `json:"speciesCode,omitempty" validate:"-"`
+	Code         string             `json:"code,omitempty" validate:"required"`
+	Names        map[string]string  `json:"names,omitempty" validate:"required,min=1,dive,keys,len=2,endkeys,required"`
+	Aliases      []string           `json:"aliases,omitempty" validate:"dive,required"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate   time.Time          `json:"updateDate,omitempty" validate:"-"`
+}
+
+// Name returns the name of the species in a language, falling back to English and then to the code.
+func (s Species) Name(language string) string {
+	return catalogName(s.Names, s.Code, language)
+}
+
+// Name returns the name of the breed in a language, falling back to English and then to the code.
+func (b Breed) Name(language string) string {
+	return catalogName(b.Names, b.Code, language)
+}
+
+func catalogName(names map[string]string, code string, language string) string {
+	if name, ok := names[language]; ok {
+		return name
+	}
+	if name, ok := names[CatalogLanguage]; ok {
+		return name
+	}
+	return code
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// TaxRate is charged on every appointment whose service category and partner region match. An empty Category or
+// Region matches any value.
+type TaxRate struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	Name         string             `json:"name,omitempty" validate:"required"`
+	Category     string             `json:"category,omitempty"`
+	Region       string             `json:"region,omitempty"`
+	BasisPoints  int64              `json:"basisPoints,omitempty" validate:"required,gt=0,lte=10000"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// AppliesTo reports whether the rate is charged for the given service category and region.
+func (t TaxRate) AppliesTo(category string, region string) bool {
+	return (t.Category == "" || t.Category == category) && (t.Region == "" || t.Region == region)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// WaitlistStatus is the lifecycle state of a WaitlistEntry.
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting WaitlistStatus = "waiting"
+	WaitlistOffered WaitlistStatus = "offered"
+	WaitlistBooked  WaitlistStatus = "booked"
+	WaitlistLeft    WaitlistStatus = "left"
+	WaitlistExpired WaitlistStatus = "expired"
+)
+
+// WaitlistOffer is a freed slot held for a waitlisted owner until it expires. The slot is the whole time the
+// cancelled appointment took, the booking only takes the duration of the service of the entry.
+type WaitlistOffer struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	FreedBy   string    `json:"freedBy,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	OfferDate time.Time `json:"offerDate"`
+}
+
+// WaitlistEntry is an owner waiting for a slot with a Partner for a service between From and To. Entries are
+// offered freed slots in the order they were created.
+type WaitlistEntry struct {
+	Id            primitive.ObjectID `json:"id,omitempty"`
+	OwnerId       string             `json:"ownerId,omitempty" validate:"required"`
+	PetId         string             `json:"petId,omitempty" validate:"required"`
+	PartnerId     string             `json:"partnerId,omitempty" validate:"required"`
+	ServiceId     string             `json:"serviceId,omitempty" validate:"required"`
+	PaymentType   PaymentMethod      `json:"paymentType,omitempty" validate:"required,oneof=cash card transfer"`
+	From          time.Time          `json:"from,omitempty" validate:"required"`
+	To            time.Time          `json:"to,omitempty" validate:"required,gtfield=From"`
+	Status        WaitlistStatus     `json:"status,omitempty" validate:"-"`
+	Offer         *WaitlistOffer     `json:"offer,omitempty" validate:"-"`
+	OffersMissed  int                `json:"offersMissed,omitempty" validate:"-"`
+	AppointmentId string             `json:"appointmentId,omitempty" validate:"-"`
+	CreationDate  time.Time          `json:"creationDate,omitempty" form:"date"`
+	UpdateDate    time.Time          `json:"updateDate,omitempty" validate:"-"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// WebhookSubscription sends the events of the given types concerning a partner to a URL. The "*" type subscribes to
+// every event.
+type WebhookSubscription struct {
+	Id           primitive.ObjectID `json:"id,omitempty"`
+	PartnerId    string             `json:"partnerId,omitempty" validate:"required"`
+	URL          string             `json:"url,omitempty" validate:"required,url"`
+	Events       []string           `json:"events,omitempty" validate:"required,min=1,dive,required"`
+	Secret       string             `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Disabled     bool               `json:"disabled"`
+	CreationDate time.Time          `json:"creationDate,omitempty" form:"date"`
+}
+
+// WebhookDeliveryStatus is the lifecycle state of a WebhookDelivery. Failed deliveries are retried until they run
+// out of attempts and become dead letters.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookFailed    WebhookDeliveryStatus = "failed"
+	WebhookDead      WebhookDeliveryStatus = "dead"
+)
+
+// WebhookAttempt is the log of a single try to deliver a webhook.
+type WebhookAttempt struct {
+	Date       time.Time `json:"date"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// WebhookDelivery is an event to be sent to a subscription. The payload is kept as sent, so redeliveries are
+// byte-for-byte identical and keep the same signature input.
+type WebhookDelivery struct {
+	Id             primitive.ObjectID    `json:"id,omitempty"`
+	SubscriptionId string                `json:"subscriptionId,omitempty"`
+	EventId        string                `json:"eventId,omitempty"`
+	EventType      string                `json:"eventType,omitempty"`
+	Payload        string                `json:"payload,omitempty"`
+	Status         WebhookDeliveryStatus `json:"status,omitempty"`
+	Attempts       []WebhookAttempt      `json:"attempts,omitempty"`
+	AttemptCount   int                   `json:"attemptCount"`
+	NextAttempt    time.Time             `json:"nextAttempt,omitempty"`
+	CreationDate   time.Time             `json:"creationDate,omitempty" form:"date"`
+	UpdateDate     time.Time             `json:"updateDate,omitempty" form:"date"`
+}
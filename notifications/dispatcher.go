@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log"
+	"pet-appointments-api/models"
+	"sync"
+	"time"
+)
+
+// Dispatcher renders the template of an event in the language of the owner and sends it through every notifier
+// that can reach them on a channel they opted in to, or only through their preferred channel when it delivers.
+type Dispatcher struct {
+	Templates TemplateStore
+	Notifiers []Notifier
+}
+
+func NewDispatcher(templates TemplateStore, notifiers []Notifier) *Dispatcher {
+	return &Dispatcher{Templates: templates, Notifiers: notifiers}
+}
+
+// Notify sends the notification of an event. Failing channels don't stop the others, their errors are joined.
+func (d *Dispatcher) Notify(ctx context.Context, event models.NotificationEvent, data Data) error {
+	tpl, err := Lookup(ctx, d.Templates, event, data.Owner.Language)
+	if err != nil {
+		return err
+	}
+
+	message, err := Render(tpl, data)
+	if err != nil {
+		return err
+	}
+
+	//once the preferred channel delivers, the owner isn't also contacted through the others
+	var errs []error
+	delivered := false
+	for _, notifier := range d.ordered(message.To.Preferred) {
+		channel := notifier.Channel()
+		if !message.To.Allows(channel) || delivered && models.IsContactChannel(channel) {
+			continue
+		}
+
+		err := notifier.Send(ctx, message)
+		if err == nil && channel == message.To.Preferred {
+			delivered = true
+		}
+		if err != nil && !errors.Is(err, ErrNoRecipient) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ordered returns the notifiers with the ones of a channel first.
+func (d *Dispatcher) ordered(first string) []Notifier {
+	notifiers := make([]Notifier, 0, len(d.Notifiers))
+	for _, notifier := range d.Notifiers {
+		if notifier.Channel() == first {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	for _, notifier := range d.Notifiers {
+		if notifier.Channel() != first {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	return notifiers
+}
+
+var (
+	defaultMu         sync.RWMutex
+	defaultDispatcher *Dispatcher
+)
+
+// SetDefault sets the dispatcher used by Notify.
+func SetDefault(dispatcher *Dispatcher) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultDispatcher = dispatcher
+}
+
+// Notify sends the notification of an event in the background through the default dispatcher, so requests are
+// never slowed down or failed by a notification channel. It does nothing until a default dispatcher is set.
+func Notify(event models.NotificationEvent, data Data) {
+	defaultMu.RLock()
+	dispatcher := defaultDispatcher
+	defaultMu.RUnlock()
+
+	if dispatcher == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := dispatcher.Notify(ctx, event, data); err != nil {
+			log.Println("notifications: sending", event, "for appointment", data.Appointment.Id.Hex(), "failed:", err)
+		}
+	}()
+}
@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier writes every message to a writer instead of delivering it, which is handy while developing.
+type LogNotifier struct {
+	mu     sync.Mutex
+	Writer io.Writer
+}
+
+func NewLogNotifier(writer io.Writer) *LogNotifier {
+	return &LogNotifier{Writer: writer}
+}
+
+// NewFileNotifier appends every message to the file at the given path.
+func NewFileNotifier(path string) (*LogNotifier, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogNotifier(file), nil
+}
+
+func (n *LogNotifier) Channel() string {
+	return "log"
+}
+
+func (n *LogNotifier) Send(ctx context.Context, message Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.Writer, "[%s] to=%q email=%q phone=%q subject=%q\n%s\n\n", time.Now().Format(time.RFC3339), message.To.Name, message.To.Email, message.To.Phone, message.Subject, message.Body)
+	return err
+}
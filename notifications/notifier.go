@@ -0,0 +1,42 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"pet-appointments-api/models"
+)
+
+// ErrNoRecipient is returned by a Notifier when the recipient has no address on its channel, e.g. no phone for SMS.
+var ErrNoRecipient = errors.New("notifications: the recipient can't be reached on this channel")
+
+// Recipient is who a Message is for. Each Notifier uses the address of its own channel, and only writes through
+// the channels the recipient opted in to. The preferred channel is tried first.
+type Recipient struct {
+	Name      string
+	Email     string
+	Phone     string
+	OptIns    map[string]bool
+	Preferred string
+}
+
+// Allows tells whether the recipient agreed to be contacted through a channel. Channels that don't reach people,
+// like the log, are always allowed.
+func (r Recipient) Allows(channel string) bool {
+	if !models.IsContactChannel(channel) {
+		return true
+	}
+	return r.OptIns[channel]
+}
+
+// Message is a rendered notification, ready to be sent.
+type Message struct {
+	To      Recipient
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages through a single channel, like email or SMS.
+type Notifier interface {
+	Channel() string
+	Send(ctx context.Context, message Message) error
+}
@@ -0,0 +1,89 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// Reminder is an upcoming appointment that is due a reminder, with everything needed to write it.
+type Reminder struct {
+	Data
+	Offset time.Duration
+}
+
+// ReminderStore finds the appointments due a reminder and records which reminders were sent.
+type ReminderStore interface {
+	// DueReminders returns the scheduled appointments starting within the offset that didn't get its reminder yet.
+	DueReminders(ctx context.Context, offset time.Duration, now time.Time) ([]Reminder, error)
+	// ClaimReminder records that the reminder of an appointment is being sent, and reports false when it already was.
+	ClaimReminder(ctx context.Context, appointmentId string, offset time.Duration) (bool, error)
+}
+
+// RecipientOf returns the addresses of an owner, preferring the ones the owner chose, and the channels the owner
+// agreed to be contacted through.
+func RecipientOf(owner models.Owner) Recipient {
+	recipient := Recipient{
+		Name:   owner.Name + " " + owner.LastName,
+		Email:  owner.ContactEmail(),
+		Phone:  owner.ContactPhone(),
+		OptIns: owner.OptIns,
+	}
+	if method, ok := models.PreferredContact(owner.ContactMethods); ok {
+		recipient.Preferred = method.Channel()
+	}
+	return recipient
+}
+
+// Scheduler periodically looks for appointments due a reminder at each offset before their start, and sends it
+// through every notifier that can reach the owner.
+type Scheduler struct {
+	Store      ReminderStore
+	Offsets    []time.Duration
+	Dispatcher *Dispatcher
+	Interval   time.Duration
+}
+
+func NewScheduler(store ReminderStore, offsets []time.Duration, dispatcher *Dispatcher) *Scheduler {
+	return &Scheduler{Store: store, Offsets: offsets, Dispatcher: dispatcher, Interval: time.Minute}
+}
+
+// Start runs the scheduler until the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Run(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run sends the reminders due at the given time. A reminder is claimed before it is sent, so several instances
+// of the API never remind the same appointment twice.
+func (s *Scheduler) Run(ctx context.Context, now time.Time) {
+	for _, offset := range s.Offsets {
+		reminders, err := s.Store.DueReminders(ctx, offset, now)
+		if err != nil {
+			log.Println("reminders: looking for due reminders failed:", err)
+			continue
+		}
+
+		for _, reminder := range reminders {
+			claimed, err := s.Store.ClaimReminder(ctx, reminder.Appointment.Id.Hex(), offset)
+			if err != nil || !claimed {
+				continue
+			}
+
+			if err := s.Dispatcher.Notify(ctx, models.EventReminder, reminder.Data); err != nil {
+				log.Println("reminders: sending the reminder of", reminder.Appointment.Id.Hex(), "failed:", err)
+			}
+		}
+	}
+}
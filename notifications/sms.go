@@ -0,0 +1,58 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SMSNotifier sends text messages through an HTTP SMS gateway, posting a JSON body with the recipient, the sender
+// and the text.
+type SMSNotifier struct {
+	GatewayURL string
+	APIKey     string
+	From       string
+	Client     *http.Client
+}
+
+func NewSMSNotifier(gatewayURL string, apiKey string, from string) *SMSNotifier {
+	return &SMSNotifier{GatewayURL: gatewayURL, APIKey: apiKey, From: from, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *SMSNotifier) Channel() string {
+	return "sms"
+}
+
+func (n *SMSNotifier) Send(ctx context.Context, message Message) error {
+	if message.To.Phone == "" {
+		return ErrNoRecipient
+	}
+
+	body, err := json.Marshal(map[string]string{"to": message.To.Phone, "from": n.From, "text": message.Body})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.GatewayURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if n.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+n.APIKey)
+	}
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway answered %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPNotifier sends messages by email through an SMTP server. Authentication is only used when a username is set,
+// so it can be pointed to a local fake server while developing.
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPNotifier(host string, port int, username string, password string, from string) *SMTPNotifier {
+	return &SMTPNotifier{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (n *SMTPNotifier) Channel() string {
+	return "email"
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, message Message) error {
+	if message.To.Email == "" {
+		return ErrNoRecipient
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	//net/smtp doesn't take a context, so the call runs aside and is abandoned when the context is done
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(n.Host, strconv.Itoa(n.Port)), auth, n.From, []string{message.To.Email}, n.compose(message))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// compose builds the RFC 5322 email, encoding the headers that may contain non-ASCII text.
+func (n *SMTPNotifier) compose(message Message) []byte {
+	var b bytes.Buffer
+
+	to := message.To.Email
+	if message.To.Name != "" {
+		to = fmt.Sprintf("%s <%s>", mime.QEncoding.Encode("utf-8", message.To.Name), message.To.Email)
+	}
+
+	fmt.Fprintf(&b, "From: %s\r\n", n.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.Write(bytes.ReplaceAll([]byte(message.Body), []byte("\n"), []byte("\r\n")))
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
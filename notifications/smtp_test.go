@@ -0,0 +1,151 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// received is an email as the fake server got it.
+type received struct {
+	from, to, data string
+}
+
+// fakeSMTPServer accepts a single email and hands what it received over the returned channel.
+func fakeSMTPServer(t *testing.T) (string, int, <-chan received) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	mails := make(chan received, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var mail received
+		reply("220 fake ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				reply("250-fake\r\n250 8BITMIME")
+			case "MAIL":
+				mail.from = command
+				reply("250 OK")
+			case "RCPT":
+				mail.to = command
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				mail.data = data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				mails <- mail
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	return address.IP.String(), address.Port, mails
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	host, port, mails := fakeSMTPServer(t)
+	notifier := NewSMTPNotifier(host, port, "", "", "clinic@example.com")
+
+	message := Message{
+		To:      Recipient{Name: "José Pérez", Email: "jose@example.com"},
+		Subject: "Recordatorio: cita mañana",
+		Body:    "Hola José,\nmañana a las 10:00.",
+	}
+	if err := notifier.Send(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+
+	var mail received
+	select {
+	case mail = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fake server got no email")
+	}
+
+	if mail.from != "MAIL FROM:<clinic@example.com> BODY=8BITMIME" && mail.from != "MAIL FROM:<clinic@example.com>" {
+		t.Errorf("got %q", mail.from)
+	}
+	if mail.to != "RCPT TO:<jose@example.com>" {
+		t.Errorf("got %q", mail.to)
+	}
+	for _, want := range []string{
+		"From: clinic@example.com\r\n",
+		"To: =?utf-8?q?Jos=C3=A9_P=C3=A9rez?= <jose@example.com>\r\n",
+		"Subject: =?utf-8?q?Recordatorio:_cita_ma=C3=B1ana?=\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nHola José,\r\nmañana a las 10:00.\r\n",
+	} {
+		if !strings.Contains(mail.data, want) {
+			t.Errorf("the email lacks %q:\n%s", want, mail.data)
+		}
+	}
+}
+
+func TestSMTPNotifierNeedsAnEmail(t *testing.T) {
+	notifier := NewSMTPNotifier("127.0.0.1", 25, "", "", "clinic@example.com")
+	if err := notifier.Send(context.Background(), Message{To: Recipient{Phone: "+34600000000"}}); err != ErrNoRecipient {
+		t.Errorf("got %v, want %v", err, ErrNoRecipient)
+	}
+}
+
+func TestSMTPNotifierGivesUpWithTheContext(t *testing.T) {
+	//a server that accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	notifier := NewSMTPNotifier(address.IP.String(), address.Port, "", "", "clinic@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := notifier.Send(ctx, Message{To: Recipient{Email: "jose@example.com"}}); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,182 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"pet-appointments-api/models"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultLanguage is used when there is no template in the language of the owner.
+const DefaultLanguage = "en"
+
+// ErrTemplateNotFound is returned by a TemplateStore that has no template for an event and language.
+var ErrTemplateNotFound = errors.New("notifications: template not found")
+
+// Data is what notification templates are rendered against. Invoice is only set for the invoice_ready event,
+// Offer for the waitlist_offer event, whose Appointment is the one that will be booked on acceptance, and
+// Vaccination for the vaccination_due event, which has no Appointment.
+type Data struct {
+	Appointment models.Appointment
+	Owner       models.Owner
+	Pet         models.Pet
+	Partner     models.Partner
+	Invoice     *models.Invoice
+	Offer       *models.WaitlistOffer
+	Vaccination *models.Vaccination
+}
+
+// TemplateStore holds the templates edited by the administrators.
+type TemplateStore interface {
+	FindTemplate(ctx context.Context, event models.NotificationEvent, language string) (models.NotificationTemplate, error)
+}
+
+// Built-in templates, used until an administrator stores one for the event.
+var defaultTemplates = map[models.NotificationEvent]models.NotificationTemplate{
+	models.EventBookingConfirmed: {
+		Subject: `Your appointment for {{.Pet.Name}} is confirmed`,
+		Body: `Hello {{.Owner.Name}},
+
+Your appointment for {{.Pet.Name}} ({{.Appointment.Service}}) with {{.Partner.Name}} {{.Partner.LastName}} is confirmed for {{.Appointment.StartTime.Format "Monday, January 2 at 15:04"}}.
+`,
+	},
+	models.EventRescheduled: {
+		Subject: `Your appointment for {{.Pet.Name}} was rescheduled`,
+		Body: `Hello {{.Owner.Name}},
+
+The appointment of {{.Pet.Name}} with {{.Partner.Name}} {{.Partner.LastName}} was moved to {{.Appointment.StartTime.Format "Monday, January 2 at 15:04"}}.
+`,
+	},
+	models.EventCancelled: {
+		Subject: `Your appointment for {{.Pet.Name}} was cancelled`,
+		Body: `Hello {{.Owner.Name}},
+
+The appointment of {{.Pet.Name}} with {{.Partner.Name}} {{.Partner.LastName}} on {{.Appointment.StartTime.Format "Monday, January 2 at 15:04"}} was cancelled.
+{{with .Appointment.Cancellation}}{{if .Fee.Amount}}A cancellation fee of {{.Fee}} applies.{{end}}{{if .Refund.Amount}} {{.Refund}} will be refunded.{{end}}{{end}}
+`,
+	},
+	models.EventReminder: {
+		Subject: `Reminder: {{.Pet.Name}} has an appointment on {{.Appointment.StartTime.Format "Mon Jan 2 15:04"}}`,
+		Body: `Hello {{.Owner.Name}},
+
+This is a reminder that {{.Pet.Name}} has an appointment for {{.Appointment.Service}} with {{.Partner.Name}} {{.Partner.LastName}} on {{.Appointment.StartTime.Format "Monday, January 2 at 15:04"}}.
+
+If you can't make it, please contact {{.Partner.Name}}{{if .Partner.Email}} at {{.Partner.Email}}{{end}}{{if .Partner.Phone}} or {{.Partner.Phone}}{{end}}.
+`,
+	},
+	models.EventInvoiceReady: {
+		Subject: `Your receipt for the visit of {{.Pet.Name}}`,
+		Body: `Hello {{.Owner.Name}},
+
+Invoice {{.Invoice.Number}} for the visit of {{.Pet.Name}} to {{.Partner.Name}} {{.Partner.LastName}} is ready. Total: {{.Invoice.Total}}.
+`,
+	},
+	models.EventWaitlistOffer: {
+		Subject: `A slot opened up for {{.Pet.Name}}`,
+		Body: `Hello {{.Owner.Name}},
+
+A slot for {{.Appointment.Service}} with {{.Partner.Name}} {{.Partner.LastName}} opened up on {{.Appointment.StartTime.Format "Monday, January 2 at 15:04"}}.
+It is held for you until {{.Offer.ExpiresAt.Format "Monday, January 2 at 15:04"}}, accept it before then and it will be booked for {{.Pet.Name}}.
+`,
+	},
+	models.EventVaccinationDue: {
+		Subject: `{{.Pet.Name}} is due for the {{.Vaccination.Vaccine}} vaccine`,
+		Body: `Hello {{.Owner.Name}},
+
+The next dose of the {{.Vaccination.Vaccine}} vaccine for {{.Pet.Name}} is due on {{.Vaccination.NextDueDate.Format "Monday, January 2"}}.
+
+Book an appointment with {{.Partner.Name}} {{.Partner.LastName}}{{if .Partner.Phone}} ({{.Partner.Phone}}){{end}} so {{.Pet.Name}} stays protected.
+`,
+	},
+}
+
+// DefaultTemplate returns the built-in template of an event.
+func DefaultTemplate(event models.NotificationEvent) (models.NotificationTemplate, bool) {
+	tpl, ok := defaultTemplates[event]
+	tpl.Event = event
+	tpl.Language = DefaultLanguage
+	return tpl, ok
+}
+
+// Lookup finds the template of an event for a language, falling back to the base language ("es" for "es-AR"),
+// then to the default language and finally to the built-in template.
+func Lookup(ctx context.Context, store TemplateStore, event models.NotificationEvent, language string) (models.NotificationTemplate, error) {
+	candidates := []string{language}
+	if base, _, found := strings.Cut(language, "-"); found {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, DefaultLanguage)
+
+	if store != nil {
+		for _, candidate := range candidates {
+			if candidate == "" {
+				continue
+			}
+
+			tpl, err := store.FindTemplate(ctx, event, candidate)
+			if err == nil {
+				return tpl, nil
+			}
+			if !errors.Is(err, ErrTemplateNotFound) {
+				return tpl, err
+			}
+		}
+	}
+
+	tpl, ok := DefaultTemplate(event)
+	if !ok {
+		return tpl, ErrTemplateNotFound
+	}
+	return tpl, nil
+}
+
+// Validate checks that the subject and body of a template are valid Go templates, and renders them for its event so a
+// misspelled field, or one its event doesn't have, is found when the template is saved rather than when it is sent.
+func Validate(tpl models.NotificationTemplate) error {
+	_, err := Render(tpl, sampleData(tpl.Event))
+	return err
+}
+
+// sampleData is the data Validate renders templates with: empty, but with what only some events have set for them.
+// Fields that are only sometimes set, like the cancellation of an appointment, stay unset, so templates must check
+// them with "with" or "if".
+func sampleData(event models.NotificationEvent) Data {
+	var data Data
+	switch event {
+	case models.EventInvoiceReady:
+		data.Invoice = &models.Invoice{}
+	case models.EventWaitlistOffer:
+		data.Offer = &models.WaitlistOffer{}
+	case models.EventVaccinationDue:
+		data.Vaccination = &models.Vaccination{NextDueDate: &time.Time{}}
+	}
+	return data
+}
+
+// Render writes the message of a template for the owner of the data.
+func Render(tpl models.NotificationTemplate, data Data) (Message, error) {
+	subject, err := execute("subject", tpl.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	body, err := execute("body", tpl.Body, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{To: RecipientOf(data.Owner), Subject: strings.TrimSpace(subject), Body: body}, nil
+}
+
+func execute(name string, text string, data Data) (string, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	err = tpl.Execute(&b, data)
+	return b.String(), err
+}
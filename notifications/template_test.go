@@ -0,0 +1,151 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"pet-appointments-api/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryTemplates is a TemplateStore keeping its templates in memory, by event and language.
+type memoryTemplates struct {
+	templates map[string]models.NotificationTemplate
+	err       error
+}
+
+func (s memoryTemplates) FindTemplate(ctx context.Context, event models.NotificationEvent, language string) (models.NotificationTemplate, error) {
+	if s.err != nil {
+		return models.NotificationTemplate{}, s.err
+	}
+	tpl, ok := s.templates[string(event)+"/"+language]
+	if !ok {
+		return tpl, ErrTemplateNotFound
+	}
+	return tpl, nil
+}
+
+func stored(languages ...string) memoryTemplates {
+	store := memoryTemplates{templates: map[string]models.NotificationTemplate{}}
+	for _, language := range languages {
+		store.templates[string(models.EventReminder)+"/"+language] = models.NotificationTemplate{Event: models.EventReminder, Language: language, Subject: language}
+	}
+	return store
+}
+
+func TestLookup(t *testing.T) {
+	builtIn, _ := DefaultTemplate(models.EventReminder)
+	failure := errors.New("connection refused")
+
+	cases := []struct {
+		name     string
+		store    TemplateStore
+		language string
+		subject  string
+		err      error
+	}{
+		{"the language", stored("es-AR", "es", "en"), "es-AR", "es-AR", nil},
+		{"the base language", stored("es", "en"), "es-AR", "es", nil},
+		{"a language without region", stored("es", "en"), "es", "es", nil},
+		{"the default language", stored("en"), "fr-CA", "en", nil},
+		{"no language", stored("en"), "", "en", nil},
+		{"the built-in template", stored("de"), "fr", builtIn.Subject, nil},
+		{"no store", nil, "es", builtIn.Subject, nil},
+		{"a failing store", memoryTemplates{err: failure}, "es", "", failure},
+	}
+
+	for _, c := range cases {
+		tpl, err := Lookup(context.Background(), c.store, models.EventReminder, c.language)
+		if tpl.Subject != c.subject || !errors.Is(err, c.err) {
+			t.Errorf("%s: got %q, %v, want %q, %v", c.name, tpl.Subject, err, c.subject, c.err)
+		}
+	}
+
+	if _, err := Lookup(context.Background(), stored("en"), "unknown_event", "en"); err != ErrTemplateNotFound {
+		t.Errorf("unknown event: got %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for event := range defaultTemplates {
+		tpl, _ := DefaultTemplate(event)
+		if err := Validate(tpl); err != nil {
+			t.Errorf("built-in template of %s: %v", event, err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		event   models.NotificationEvent
+		subject string
+		body    string
+		valid   bool
+	}{
+		{"plain text", models.EventReminder, "Reminder", "See you soon.", true},
+		{"fields", models.EventReminder, "{{.Pet.Name}}", "{{.Owner.Name}} {{.Appointment.StartTime.Format \"15:04\"}}", true},
+		{"optional field checked", models.EventCancelled, "Cancelled", "{{with .Appointment.Cancellation}}{{.Fee}}{{end}}", true},
+		{"the data of its event", models.EventInvoiceReady, "{{.Invoice.Number}}", "{{.Invoice.Total}}", true},
+		{"unclosed action", models.EventReminder, "{{.Pet.Name", "", false},
+		{"unknown function", models.EventReminder, "", "{{upper .Pet.Name}}", false},
+		{"misspelled field", models.EventReminder, "", "{{.Pet.Nmae}}", false},
+		{"optional field not checked", models.EventCancelled, "", "{{.Appointment.Cancellation.Fee}}", false},
+		{"the data of another event", models.EventBookingConfirmed, "", "{{.Invoice.Number}}", false},
+	}
+
+	for _, c := range cases {
+		err := Validate(models.NotificationTemplate{Event: c.event, Subject: c.subject, Body: c.body})
+		if (err == nil) != c.valid {
+			t.Errorf("%s: got %v, want valid %t", c.name, err, c.valid)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Appointment: models.Appointment{Service: "Grooming", StartTime: time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)},
+		Owner:       models.Owner{Name: "Ana", LastName: "Pérez", Email: "ana@example.com", OptIns: map[string]bool{"email": true}},
+		Pet:         models.Pet{Name: "Rex"},
+		Partner:     models.Partner{Name: "Laura", LastName: "Gómez"},
+	}
+
+	tpl, _ := DefaultTemplate(models.EventBookingConfirmed)
+	message, err := Render(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Subject != "Your appointment for Rex is confirmed" {
+		t.Errorf("subject = %q", message.Subject)
+	}
+	if !strings.Contains(message.Body, "Hello Ana,") || !strings.Contains(message.Body, "Rex (Grooming) with Laura Gómez is confirmed for Friday, May 10 at 15:30.") {
+		t.Errorf("body = %q", message.Body)
+	}
+	if message.To.Name != "Ana Pérez" || message.To.Email != "ana@example.com" {
+		t.Errorf("recipient = %+v", message.To)
+	}
+
+	//subjects are trimmed, so a template can be written over several lines
+	message, err = Render(models.NotificationTemplate{Subject: "\n  {{.Pet.Name}}\n", Body: "body"}, data)
+	if err != nil || message.Subject != "Rex" {
+		t.Errorf("trimmed subject: got %q, %v", message.Subject, err)
+	}
+
+	errorCases := []struct {
+		name string
+		tpl  models.NotificationTemplate
+	}{
+		//missingkey=error turns a missing key of a map into an error instead of "<no value>"
+		{"missing map key", models.NotificationTemplate{Subject: "{{.Owner.OptIns.sms}}"}},
+		{"missing field", models.NotificationTemplate{Body: "{{.Pet.Nmae}}"}},
+		{"data of another event", models.NotificationTemplate{Body: "{{.Invoice.Number}}"}},
+	}
+	for _, c := range errorCases {
+		if message, err := Render(c.tpl, data); err == nil {
+			t.Errorf("%s: got %+v, want an error", c.name, message)
+		}
+	}
+
+	if message, err := Render(models.NotificationTemplate{Subject: "{{.Owner.OptIns.email}}"}, data); err != nil || message.Subject != "true" {
+		t.Errorf("present map key: got %q, %v", message.Subject, err)
+	}
+}
@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// VaccinationReminder is a vaccination due soon, with everything needed to write its reminder. The partner of the
+// data is the one who gave the last dose, so the owner knows where to book the next one.
+type VaccinationReminder struct {
+	Data
+	Offset time.Duration
+}
+
+// VaccinationStore finds the vaccinations due a reminder and records which reminders were sent.
+type VaccinationStore interface {
+	// DueVaccinations returns the current vaccinations due within the offset that didn't get its reminder yet.
+	DueVaccinations(ctx context.Context, offset time.Duration, now time.Time) ([]VaccinationReminder, error)
+	// ClaimVaccinationReminder records that the reminder of a vaccination is being sent, and reports false when it
+	// already was.
+	ClaimVaccinationReminder(ctx context.Context, petId string, vaccinationId string, offset time.Duration) (bool, error)
+}
+
+// VaccinationScheduler periodically looks for vaccinations due at each offset before their due date, and prompts
+// their owners to book the next dose.
+type VaccinationScheduler struct {
+	Store      VaccinationStore
+	Offsets    []time.Duration
+	Dispatcher *Dispatcher
+	Interval   time.Duration
+}
+
+func NewVaccinationScheduler(store VaccinationStore, offsets []time.Duration, dispatcher *Dispatcher) *VaccinationScheduler {
+	return &VaccinationScheduler{Store: store, Offsets: offsets, Dispatcher: dispatcher, Interval: time.Hour}
+}
+
+// Start runs the scheduler until the context is cancelled.
+func (s *VaccinationScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Run(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run sends the vaccination reminders due at the given time, claiming each one before it is sent.
+func (s *VaccinationScheduler) Run(ctx context.Context, now time.Time) {
+	for _, offset := range s.Offsets {
+		reminders, err := s.Store.DueVaccinations(ctx, offset, now)
+		if err != nil {
+			log.Println("reminders: looking for due vaccinations failed:", err)
+			continue
+		}
+
+		for _, reminder := range reminders {
+			claimed, err := s.Store.ClaimVaccinationReminder(ctx, reminder.Pet.Id.Hex(), reminder.Vaccination.Id.Hex(), offset)
+			if err != nil || !claimed {
+				continue
+			}
+
+			if err := s.Dispatcher.Notify(ctx, models.EventVaccinationDue, reminder.Data); err != nil {
+				log.Println("reminders: sending the vaccination reminder of", reminder.Pet.Id.Hex(), "failed:", err)
+			}
+		}
+	}
+}
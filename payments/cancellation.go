@@ -0,0 +1,34 @@
+package payments
+
+import (
+	"pet-appointments-api/models"
+	"time"
+)
+
+// CancellationFee applies a cancellation policy to an appointment cancelled at the given time. No-shows pay the
+// no-show fee, cancellations inside the free window pay nothing and later ones pay the late fee.
+func CancellationFee(policy models.CancellationPolicy, price models.Money, start time.Time, cancelledAt time.Time, noShow bool) (int, models.Money) {
+	feePercent := 0
+	switch {
+	case noShow:
+		feePercent = policy.NoShowFeePercent
+	case start.Sub(cancelledAt) < time.Duration(policy.FreeCancellationHours)*time.Hour:
+		feePercent = policy.LateFeePercent
+	}
+
+	return feePercent, price.Percentage(int64(feePercent) * 100)
+}
+
+// Settle splits what was already paid for a cancelled appointment into the amount that must be refunded and the
+// part of the fee that is still owed.
+func Settle(fee models.Money, paid models.Money) (refund models.Money, owed models.Money, err error) {
+	balance, err := paid.Sub(fee)
+	if err != nil {
+		return refund, owed, err
+	}
+
+	if balance.Amount >= 0 {
+		return balance, models.Zero(fee.Currency), nil
+	}
+	return models.Zero(fee.Currency), models.NewMoney(-balance.Amount, fee.Currency), nil
+}
@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"pet-appointments-api/models"
+	"testing"
+	"time"
+)
+
+func TestCancellationFee(t *testing.T) {
+	policy := models.CancellationPolicy{FreeCancellationHours: 24, LateFeePercent: 50, NoShowFeePercent: 100}
+	price := models.NewMoney(8000, "USD")
+	start := time.Date(2024, 5, 10, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		cancelledAt time.Time
+		noShow      bool
+		percent     int
+		fee         int64
+	}{
+		{"well in advance", start.Add(-48 * time.Hour), false, 0, 0},
+		{"right at the end of the free window", start.Add(-24 * time.Hour), false, 0, 0},
+		{"late", start.Add(-23 * time.Hour), false, 50, 4000},
+		{"after the start", start.Add(time.Hour), false, 50, 4000},
+		{"no-show", start.Add(time.Hour), true, 100, 8000},
+	}
+
+	for _, c := range cases {
+		percent, fee := CancellationFee(policy, price, start, c.cancelledAt, c.noShow)
+		if percent != c.percent || fee != models.NewMoney(c.fee, "USD") {
+			t.Errorf("%s: got %d%% %s, want %d%% %d", c.name, percent, fee, c.percent, c.fee)
+		}
+	}
+}
+
+func TestCancellationFeeRounding(t *testing.T) {
+	policy := models.CancellationPolicy{FreeCancellationHours: 24, LateFeePercent: 33}
+	start := time.Date(2024, 5, 10, 10, 0, 0, 0, time.UTC)
+
+	//33% of 0.50 is 0.165, which rounds half away from zero
+	if _, fee := CancellationFee(policy, models.NewMoney(50, "USD"), start, start, false); fee != models.NewMoney(17, "USD") {
+		t.Errorf("got %s, want 0.17 USD", fee)
+	}
+}
+
+func TestSettle(t *testing.T) {
+	cases := []struct {
+		name   string
+		fee    int64
+		paid   int64
+		refund int64
+		owed   int64
+	}{
+		{"paid more than the fee", 2000, 5000, 3000, 0},
+		{"paid exactly the fee", 2000, 2000, 0, 0},
+		{"paid less than the fee", 2000, 500, 0, 1500},
+		{"no fee", 0, 5000, 5000, 0},
+		{"nothing paid", 2000, 0, 0, 2000},
+	}
+
+	for _, c := range cases {
+		refund, owed, err := Settle(models.NewMoney(c.fee, "USD"), models.NewMoney(c.paid, "USD"))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if refund != models.NewMoney(c.refund, "USD") || owed != models.NewMoney(c.owed, "USD") {
+			t.Errorf("%s: got refund %s owed %s, want %d and %d", c.name, refund, owed, c.refund, c.owed)
+		}
+	}
+
+	if _, _, err := Settle(models.NewMoney(100, "USD"), models.NewMoney(100, "EUR")); err != models.ErrCurrencyMismatch {
+		t.Errorf("got %v, want %v", err, models.ErrCurrencyMismatch)
+	}
+}
@@ -0,0 +1,82 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pet-appointments-api/models"
+	"sync"
+)
+
+// Card tokens the FakeCardGateway treats specially, in the spirit of the test cards offered by real gateways.
+const (
+	FakeCardDeclined     = "tok_declined"
+	FakeCardInsufficient = "tok_insufficient_funds"
+)
+
+var errUnknownCharge = errors.New("fake card gateway: unknown charge")
+
+// FakeCardGateway is an in-memory stand-in for a card payment gateway, meant for tests and local development.
+// Every token is accepted except FakeCardDeclined and FakeCardInsufficient.
+type FakeCardGateway struct {
+	mu      sync.Mutex
+	next    int
+	charges map[string]models.PaymentStatus
+}
+
+func NewFakeCardGateway() *FakeCardGateway {
+	return &FakeCardGateway{charges: map[string]models.PaymentStatus{}}
+}
+
+func (g *FakeCardGateway) Name() string {
+	return "fake-card"
+}
+
+func (g *FakeCardGateway) Authorize(ctx context.Context, payment models.Payment, token string) (Result, error) {
+	switch token {
+	case FakeCardDeclined:
+		return Result{Status: models.PaymentStatusFailed, FailureReason: "card declined"}, nil
+	case FakeCardInsufficient:
+		return Result{Status: models.PaymentStatusFailed, FailureReason: "insufficient funds"}, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.next++
+	reference := fmt.Sprintf("ch_fake_%06d", g.next)
+	g.charges[reference] = models.PaymentStatusAuthorized
+
+	return Result{Status: models.PaymentStatusAuthorized, Reference: reference}, nil
+}
+
+func (g *FakeCardGateway) Capture(ctx context.Context, payment models.Payment) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status, ok := g.charges[payment.ProviderReference]
+	if !ok {
+		return Result{}, errUnknownCharge
+	}
+	if status != models.PaymentStatusAuthorized {
+		return Result{}, fmt.Errorf("fake card gateway: cannot capture a %s charge", status)
+	}
+
+	g.charges[payment.ProviderReference] = models.PaymentStatusCaptured
+	return Result{Status: models.PaymentStatusCaptured, Reference: payment.ProviderReference}, nil
+}
+
+func (g *FakeCardGateway) Refund(ctx context.Context, payment models.Payment, amount models.Money) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status, ok := g.charges[payment.ProviderReference]
+	if !ok {
+		return Result{}, errUnknownCharge
+	}
+	if status != models.PaymentStatusCaptured {
+		return Result{}, fmt.Errorf("fake card gateway: cannot refund a %s charge", status)
+	}
+
+	return Result{Status: models.PaymentStatusRefunded, Reference: payment.ProviderReference}, nil
+}
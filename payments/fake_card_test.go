@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"context"
+	"pet-appointments-api/models"
+	"testing"
+)
+
+func TestFakeCardGateway(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeCardGateway()
+	payment := models.Payment{Amount: models.NewMoney(5000, "USD")}
+
+	result, err := gateway.Authorize(ctx, payment, "tok_visa")
+	if err != nil || result.Status != models.PaymentStatusAuthorized || result.Reference == "" {
+		t.Fatalf("authorize: got %+v, %v", result, err)
+	}
+	payment.ProviderReference = result.Reference
+
+	if _, err := gateway.Refund(ctx, payment, payment.Amount); err == nil {
+		t.Error("an authorized charge was refunded before being captured")
+	}
+
+	if result, err = gateway.Capture(ctx, payment); err != nil || result.Status != models.PaymentStatusCaptured {
+		t.Fatalf("capture: got %+v, %v", result, err)
+	}
+	if _, err := gateway.Capture(ctx, payment); err == nil {
+		t.Error("a charge was captured twice")
+	}
+
+	if result, err = gateway.Refund(ctx, payment, payment.Amount); err != nil || result.Status != models.PaymentStatusRefunded {
+		t.Fatalf("refund: got %+v, %v", result, err)
+	}
+}
+
+func TestFakeCardGatewayDeclines(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeCardGateway()
+
+	for _, token := range []string{FakeCardDeclined, FakeCardInsufficient} {
+		result, err := gateway.Authorize(ctx, models.Payment{}, token)
+		if err != nil || result.Status != models.PaymentStatusFailed || result.FailureReason == "" {
+			t.Errorf("%s: got %+v, %v", token, result, err)
+		}
+	}
+
+	//a charge the gateway never authorized, like a cancellation fee not charged yet, can't be captured
+	if _, err := gateway.Capture(ctx, models.Payment{}); err != errUnknownCharge {
+		t.Errorf("got %v, want %v", err, errUnknownCharge)
+	}
+}
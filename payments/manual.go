@@ -0,0 +1,30 @@
+package payments
+
+import (
+	"context"
+	"pet-appointments-api/models"
+)
+
+// ManualProvider records payments collected outside the platform, like cash at the clinic or a bank transfer.
+// The money is already in the partner's hands, so authorizing a manual payment captures it right away.
+type ManualProvider struct{}
+
+func NewManualProvider() *ManualProvider {
+	return &ManualProvider{}
+}
+
+func (p *ManualProvider) Name() string {
+	return "manual"
+}
+
+func (p *ManualProvider) Authorize(ctx context.Context, payment models.Payment, token string) (Result, error) {
+	return Result{Status: models.PaymentStatusCaptured, Reference: payment.Id.Hex()}, nil
+}
+
+func (p *ManualProvider) Capture(ctx context.Context, payment models.Payment) (Result, error) {
+	return Result{Status: models.PaymentStatusCaptured, Reference: payment.ProviderReference}, nil
+}
+
+func (p *ManualProvider) Refund(ctx context.Context, payment models.Payment, amount models.Money) (Result, error) {
+	return Result{Status: models.PaymentStatusRefunded, Reference: payment.ProviderReference}, nil
+}
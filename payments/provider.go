@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"pet-appointments-api/models"
+	"sync"
+)
+
+// ErrNoProvider is returned when no PaymentProvider was registered for a payment method.
+var ErrNoProvider = errors.New("payments: no provider registered for this payment method")
+
+// Result is what a PaymentProvider reports back after every operation.
+type Result struct {
+	Status        models.PaymentStatus
+	Reference     string
+	FailureReason string
+}
+
+// PaymentProvider moves money for a Payment. Authorize reserves (or directly collects) the amount, Capture collects a
+// previously authorized amount and Refund gives back part or all of a captured one.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, payment models.Payment, token string) (Result, error)
+	Capture(ctx context.Context, payment models.Payment) (Result, error)
+	Refund(ctx context.Context, payment models.Payment, amount models.Money) (Result, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[models.PaymentMethod]PaymentProvider{}
+)
+
+// Register makes a PaymentProvider handle every payment made with the given method.
+func Register(method models.PaymentMethod, provider PaymentProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[method] = provider
+}
+
+// ProviderFor returns the PaymentProvider registered for the given method.
+func ProviderFor(method models.PaymentMethod) (PaymentProvider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	provider, ok := providers[method]
+	if !ok {
+		return nil, ErrNoProvider
+	}
+	return provider, nil
+}
@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"pet-appointments-api/models"
+)
+
+// Summarize adds up the captured payments of an appointment, net of refunds, and tells whether the appointment
+// price is fully covered.
+func Summarize(price models.Money, payments []models.Payment) (models.Money, models.AppointmentPaymentStatus, error) {
+	paid := models.Zero(price.Currency)
+	refunded := false
+
+	for _, payment := range payments {
+		if payment.Status != models.PaymentStatusCaptured && payment.Status != models.PaymentStatusRefunded {
+			continue
+		}
+
+		var err error
+		if paid, err = paid.Add(payment.Amount); err != nil {
+			return paid, models.AppointmentUnpaid, err
+		}
+		if payment.RefundedAmount.Currency != "" {
+			if paid, err = paid.Sub(payment.RefundedAmount); err != nil {
+				return paid, models.AppointmentUnpaid, err
+			}
+			refunded = refunded || !payment.RefundedAmount.IsZero()
+		}
+	}
+
+	switch {
+	case paid.Amount <= 0 && refunded:
+		return paid, models.AppointmentRefunded, nil
+	case paid.Amount >= price.Amount:
+		return paid, models.AppointmentPaid, nil
+	case paid.Amount <= 0:
+		return paid, models.AppointmentUnpaid, nil
+	}
+	return paid, models.AppointmentPartiallyPaid, nil
+}
+
+// Outstanding returns how much of the price is still covered by neither captured nor authorized payments, nor by
+// payments waiting for the provider.
+func Outstanding(price models.Money, payments []models.Payment) (models.Money, error) {
+	outstanding := price
+	for _, payment := range payments {
+		switch payment.Status {
+		case models.PaymentStatusFailed, models.PaymentStatusRefunded:
+			continue
+		}
+
+		var err error
+		if outstanding, err = outstanding.Sub(payment.Amount); err != nil {
+			return outstanding, err
+		}
+		if payment.RefundedAmount.Currency != "" {
+			if outstanding, err = outstanding.Add(payment.RefundedAmount); err != nil {
+				return outstanding, err
+			}
+		}
+	}
+	return outstanding, nil
+}
@@ -0,0 +1,71 @@
+package payments
+
+import (
+	"pet-appointments-api/models"
+	"testing"
+)
+
+func payment(status models.PaymentStatus, amount int64, refunded int64) models.Payment {
+	return models.Payment{Status: status, Amount: models.NewMoney(amount, "USD"), RefundedAmount: models.NewMoney(refunded, "USD")}
+}
+
+func TestSummarize(t *testing.T) {
+	price := models.NewMoney(10000, "USD")
+	cases := []struct {
+		name     string
+		payments []models.Payment
+		paid     int64
+		status   models.AppointmentPaymentStatus
+	}{
+		{"nothing paid", nil, 0, models.AppointmentUnpaid},
+		{"only pending and failed", []models.Payment{payment(models.PaymentStatusPending, 5000, 0), payment(models.PaymentStatusFailed, 10000, 0)}, 0, models.AppointmentUnpaid},
+		{"authorized is not paid yet", []models.Payment{payment(models.PaymentStatusAuthorized, 10000, 0)}, 0, models.AppointmentUnpaid},
+		{"deposit", []models.Payment{payment(models.PaymentStatusCaptured, 3000, 0)}, 3000, models.AppointmentPartiallyPaid},
+		{"paid in two parts", []models.Payment{payment(models.PaymentStatusCaptured, 3000, 0), payment(models.PaymentStatusCaptured, 7000, 0)}, 10000, models.AppointmentPaid},
+		{"partially refunded", []models.Payment{payment(models.PaymentStatusCaptured, 10000, 2500)}, 7500, models.AppointmentPartiallyPaid},
+		{"fully refunded", []models.Payment{payment(models.PaymentStatusRefunded, 10000, 10000)}, 0, models.AppointmentRefunded},
+	}
+
+	for _, c := range cases {
+		paid, status, err := Summarize(price, c.payments)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if paid != models.NewMoney(c.paid, "USD") || status != c.status {
+			t.Errorf("%s: got %s %s, want %d %s", c.name, paid, status, c.paid, c.status)
+		}
+	}
+}
+
+func TestSummarizeCurrencyMismatch(t *testing.T) {
+	eur := models.Payment{Status: models.PaymentStatusCaptured, Amount: models.NewMoney(100, "EUR"), RefundedAmount: models.Zero("EUR")}
+	if _, _, err := Summarize(models.NewMoney(10000, "USD"), []models.Payment{eur}); err != models.ErrCurrencyMismatch {
+		t.Errorf("got %v, want %v", err, models.ErrCurrencyMismatch)
+	}
+}
+
+func TestOutstanding(t *testing.T) {
+	price := models.NewMoney(10000, "USD")
+	cases := []struct {
+		name     string
+		payments []models.Payment
+		want     int64
+	}{
+		{"nothing paid", nil, 10000},
+		{"pending and authorized payments are spoken for", []models.Payment{payment(models.PaymentStatusPending, 2000, 0), payment(models.PaymentStatusAuthorized, 3000, 0)}, 5000},
+		{"payments being captured are spoken for", []models.Payment{payment(models.PaymentStatusProcessing, 2500, 0)}, 7500},
+		{"failed payments don't count", []models.Payment{payment(models.PaymentStatusFailed, 10000, 0)}, 10000},
+		{"refunds are owed again", []models.Payment{payment(models.PaymentStatusCaptured, 10000, 4000)}, 4000},
+		{"fully paid", []models.Payment{payment(models.PaymentStatusCaptured, 10000, 0)}, 0},
+	}
+
+	for _, c := range cases {
+		outstanding, err := Outstanding(price, c.payments)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if outstanding != models.NewMoney(c.want, "USD") {
+			t.Errorf("%s: got %s, want %d", c.name, outstanding, c.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package payouts
+
+import (
+	"encoding/csv"
+	"io"
+	"pet-appointments-api/models"
+	"strings"
+	"time"
+)
+
+// Commission computes the platform cut of an appointment. It is charged on the amount before taxes, since taxes
+// are owed by the partner to the tax authority, and never exceeds that amount.
+func Commission(rule models.CommissionRule, appointment models.Appointment) (models.Money, models.Money, error) {
+	taxes := models.Zero(appointment.Pricing.Total.Currency)
+	for _, tax := range appointment.Pricing.Taxes {
+		var err error
+		if taxes, err = taxes.Add(tax.Amount); err != nil {
+			return taxes, taxes, err
+		}
+	}
+
+	base, err := appointment.Pricing.Total.Sub(taxes)
+	if err != nil {
+		return taxes, taxes, err
+	}
+
+	commission := base.Percentage(rule.BasisPoints)
+	if rule.FixedFee != nil {
+		if commission, err = commission.Add(*rule.FixedFee); err != nil {
+			return taxes, commission, err
+		}
+	}
+	if commission.Amount > base.Amount {
+		commission.Amount = base.Amount
+	}
+	return taxes, commission, nil
+}
+
+// Build aggregates the appointments of a partner into a draft payout. All the appointments must share the same
+// currency.
+func Build(partnerId string, from time.Time, to time.Time, rule models.CommissionRule, appointments []models.Appointment) (models.Payout, error) {
+	payout := models.Payout{
+		PartnerId:   partnerId,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Commission:  rule,
+		Status:      models.PayoutDraft,
+	}
+	if len(appointments) == 0 {
+		return payout, nil
+	}
+
+	currency := appointments[0].Pricing.Total.Currency
+	payout.Gross, payout.Taxes, payout.CommissionTotal, payout.Net = models.Zero(currency), models.Zero(currency), models.Zero(currency), models.Zero(currency)
+
+	for _, appointment := range appointments {
+		taxes, commission, err := Commission(rule, appointment)
+		if err != nil {
+			return payout, err
+		}
+		net, err := appointment.Pricing.Total.Sub(commission)
+		if err != nil {
+			return payout, err
+		}
+
+		payout.Lines = append(payout.Lines, models.PayoutLine{
+			AppointmentId: appointment.Id.Hex(),
+			ServiceDate:   appointment.StartTime,
+			Service:       appointment.Service,
+			Gross:         appointment.Pricing.Total,
+			Taxes:         taxes,
+			Commission:    commission,
+			Net:           net,
+		})
+
+		for _, sum := range []struct {
+			total  *models.Money
+			amount models.Money
+		}{{&payout.Gross, appointment.Pricing.Total}, {&payout.Taxes, taxes}, {&payout.CommissionTotal, commission}, {&payout.Net, net}} {
+			if *sum.total, err = sum.total.Add(sum.amount); err != nil {
+				return payout, err
+			}
+		}
+	}
+
+	return payout, nil
+}
+
+// WriteCSV exports the lines of a payout, followed by a totals row.
+func WriteCSV(w io.Writer, payout models.Payout) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{{"appointment_id", "service_date", "service", "currency", "gross", "taxes", "commission", "net"}}
+	for _, line := range payout.Lines {
+		records = append(records, []string{line.AppointmentId, line.ServiceDate.Format(time.RFC3339), cell(line.Service), line.Gross.Currency, line.Gross.Decimal(), line.Taxes.Decimal(), line.Commission.Decimal(), line.Net.Decimal()})
+	}
+	records = append(records, []string{"TOTAL", "", "", payout.Gross.Currency, payout.Gross.Decimal(), payout.Taxes.Decimal(), payout.CommissionTotal.Decimal(), payout.Net.Decimal()})
+
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// cell keeps a free text value from being read as a formula when the statement is opened in a spreadsheet.
+func cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
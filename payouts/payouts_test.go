@@ -0,0 +1,133 @@
+package payouts
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"pet-appointments-api/models"
+	"testing"
+	"time"
+)
+
+func usd(amount int64) models.Money {
+	return models.NewMoney(amount, "USD")
+}
+
+// appointment returns a completed appointment of the given total, of which the given amount is taxes.
+func appointment(service string, total int64, taxes int64) models.Appointment {
+	pricing := models.PriceBreakdown{Subtotal: usd(total - taxes), Total: usd(total)}
+	if taxes > 0 {
+		pricing.Taxes = []models.TaxLine{{Name: "VAT", BasisPoints: 2100, Amount: usd(taxes)}}
+	}
+	return models.Appointment{Service: service, Pricing: pricing, StartTime: time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)}
+}
+
+func TestCommission(t *testing.T) {
+	fee := usd(150)
+	cases := []struct {
+		name        string
+		rule        models.CommissionRule
+		appointment models.Appointment
+		taxes       int64
+		commission  int64
+	}{
+		{"percentage", models.CommissionRule{BasisPoints: 1000}, appointment("Grooming", 5000, 0), 0, 500},
+		//the commission is charged on the amount before taxes
+		{"before taxes", models.CommissionRule{BasisPoints: 1000}, appointment("Grooming", 12100, 2100), 2100, 1000},
+		{"fixed fee", models.CommissionRule{BasisPoints: 500, FixedFee: &fee}, appointment("Grooming", 4000, 0), 0, 350},
+		//10% of 1.25 is 0.125, rounded half away from zero
+		{"rounding", models.CommissionRule{BasisPoints: 1000}, appointment("Nail trim", 125, 0), 0, 13},
+		{"rounding down", models.CommissionRule{BasisPoints: 1000}, appointment("Nail trim", 124, 0), 0, 12},
+		//the commission never takes more than the amount before taxes
+		{"capped", models.CommissionRule{BasisPoints: 5000, FixedFee: &fee}, appointment("Nail trim", 300, 100), 100, 200},
+		{"free appointment", models.CommissionRule{BasisPoints: 1000, FixedFee: &fee}, appointment("Check-up", 0, 0), 0, 0},
+	}
+
+	for _, c := range cases {
+		taxes, commission, err := Commission(c.rule, c.appointment)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if taxes != usd(c.taxes) || commission != usd(c.commission) {
+			t.Errorf("%s: got taxes %s and commission %s, want %d and %d", c.name, taxes, commission, c.taxes, c.commission)
+		}
+	}
+}
+
+func TestCommissionCurrencyMismatch(t *testing.T) {
+	fee := models.NewMoney(100, "EUR")
+	if _, _, err := Commission(models.CommissionRule{FixedFee: &fee}, appointment("Grooming", 5000, 0)); !errors.Is(err, models.ErrCurrencyMismatch) {
+		t.Errorf("got %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	from, to := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rule := models.CommissionRule{BasisPoints: 1000}
+
+	payout, err := Build("partner", from, to, rule, []models.Appointment{appointment("Grooming", 12100, 2100), appointment("Bath", 3000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if payout.Status != models.PayoutDraft || payout.PartnerId != "partner" || len(payout.Lines) != 2 {
+		t.Fatalf("got %+v", payout)
+	}
+	if payout.Gross != usd(15100) || payout.Taxes != usd(2100) || payout.CommissionTotal != usd(1300) || payout.Net != usd(13800) {
+		t.Errorf("got gross %s, taxes %s, commission %s and net %s", payout.Gross, payout.Taxes, payout.CommissionTotal, payout.Net)
+	}
+	//the partner keeps the taxes, which they owe to the tax authority
+	if line := payout.Lines[0]; line.Gross != usd(12100) || line.Commission != usd(1000) || line.Net != usd(11100) {
+		t.Errorf("got line %+v", line)
+	}
+}
+
+func TestBuildWithoutAppointments(t *testing.T) {
+	payout, err := Build("partner", time.Now(), time.Now(), models.CommissionRule{}, nil)
+	if err != nil || len(payout.Lines) != 0 || payout.Net.Currency != "" {
+		t.Errorf("got %+v, %v", payout, err)
+	}
+}
+
+func TestBuildRejectsMixedCurrencies(t *testing.T) {
+	euros := appointment("Bath", 3000, 0)
+	euros.Pricing.Total = models.NewMoney(3000, "EUR")
+
+	if _, err := Build("partner", time.Now(), time.Now(), models.CommissionRule{}, []models.Appointment{appointment("Grooming", 5000, 0), euros}); err == nil {
+		t.Error("a payout mixing currencies was built")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	payout, err := Build("partner", time.Now(), time.Now(), models.CommissionRule{BasisPoints: 1000}, []models.Appointment{
+		appointment("Grooming", 5000, 0),
+		appointment("=HYPERLINK(\"http://evil\")", 1000, 0),
+		appointment("@SUM(A1)", 1000, 0),
+		appointment("-2+3", 1000, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, payout); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 6 {
+		t.Fatalf("got %d rows, want a header, 4 lines and the totals", len(records))
+	}
+	want := []string{"Grooming", "'=HYPERLINK(\"http://evil\")", "'@SUM(A1)", "'-2+3"}
+	for i, service := range want {
+		if records[i+1][2] != service {
+			t.Errorf("line %d: got service %q, want %q", i+1, records[i+1][2], service)
+		}
+	}
+	if total := records[5]; total[0] != "TOTAL" || total[4] != "80.00" || total[6] != "8.00" || total[7] != "72.00" {
+		t.Errorf("got totals %v", total)
+	}
+}
@@ -0,0 +1,86 @@
+package pricing
+
+import (
+	"pet-appointments-api/models"
+	"time"
+)
+
+// Input gathers everything that changes the price of an appointment.
+type Input struct {
+	Service   models.Service
+	PartnerId string
+	Region    string
+	Coupon    *models.Coupon
+	MultiPet  *models.MultiPetDiscount
+	// SameDayPets is how many other pets of the owner already have an appointment with the partner that day.
+	SameDayPets int
+	TaxRates    []models.TaxRate
+}
+
+// Day returns the bounds [start, end) of the day t falls on in the given location, which is the day of the partner
+// for the multi-pet discount. Days around daylight saving changes last 23 or 25 hours.
+func Day(t time.Time, location *time.Location) (time.Time, time.Time) {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, location), time.Date(year, month, day+1, 0, 0, 0, 0, location)
+}
+
+// Calculate prices an appointment: the partner price of the service, minus the multi-pet and coupon discounts,
+// plus every matching tax charged on the discounted amount. Discounts never take the price below zero.
+func Calculate(in Input) (models.PriceBreakdown, error) {
+	subtotal := in.Service.PriceFor(in.PartnerId)
+	breakdown := models.PriceBreakdown{Subtotal: subtotal}
+	base := subtotal
+
+	discount := func(name string, code string, amount models.Money) error {
+		if amount.Amount > base.Amount {
+			amount.Amount = base.Amount
+		}
+		if amount.Amount <= 0 {
+			return nil
+		}
+
+		var err error
+		if base, err = base.Sub(amount); err != nil {
+			return err
+		}
+		breakdown.Discounts = append(breakdown.Discounts, models.DiscountLine{Name: name, Code: code, Amount: amount})
+		return nil
+	}
+
+	if in.MultiPet != nil && in.SameDayPets+1 >= in.MultiPet.MinPets {
+		if err := discount("Multi-pet discount", "", base.Percentage(in.MultiPet.BasisPoints)); err != nil {
+			return breakdown, err
+		}
+	}
+
+	if in.Coupon != nil {
+		amount := base.Percentage(in.Coupon.BasisPoints)
+		if in.Coupon.Kind == models.DiscountFixed && in.Coupon.Amount != nil {
+			amount = *in.Coupon.Amount
+		}
+		if amount.Currency != base.Currency {
+			return breakdown, models.ErrCurrencyMismatch
+		}
+		if err := discount("Coupon "+in.Coupon.Code, in.Coupon.Code, amount); err != nil {
+			return breakdown, err
+		}
+	}
+
+	total := base
+	for _, rate := range in.TaxRates {
+		if !rate.AppliesTo(in.Service.Category, in.Region) {
+			continue
+		}
+
+		tax := base.Percentage(rate.BasisPoints)
+		breakdown.Taxes = append(breakdown.Taxes, models.TaxLine{Name: rate.Name, BasisPoints: rate.BasisPoints, Amount: tax})
+
+		var err error
+		if total, err = total.Add(tax); err != nil {
+			return breakdown, err
+		}
+	}
+
+	breakdown.Total = total
+	return breakdown, nil
+}
@@ -0,0 +1,174 @@
+package pricing
+
+import (
+	"pet-appointments-api/models"
+	"testing"
+	"time"
+)
+
+func usd(amount int64) models.Money {
+	return models.NewMoney(amount, "USD")
+}
+
+func grooming() models.Service {
+	return models.Service{
+		Name:          "Grooming",
+		Category:      "grooming",
+		Price:         usd(5000),
+		PartnerPrices: []models.PartnerPrice{{PartnerId: "p2", Price: usd(6000)}},
+	}
+}
+
+func TestCalculateWithoutAdjustments(t *testing.T) {
+	breakdown, err := Calculate(Input{Service: grooming(), PartnerId: "p1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breakdown.Subtotal != usd(5000) || breakdown.Total != usd(5000) || len(breakdown.Discounts) != 0 || len(breakdown.Taxes) != 0 {
+		t.Errorf("got %+v", breakdown)
+	}
+}
+
+func TestCalculatePartnerPrice(t *testing.T) {
+	breakdown, err := Calculate(Input{Service: grooming(), PartnerId: "p2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breakdown.Subtotal != usd(6000) || breakdown.Total != usd(6000) {
+		t.Errorf("got %+v", breakdown)
+	}
+}
+
+func TestCalculateDiscountsAndTaxes(t *testing.T) {
+	breakdown, err := Calculate(Input{
+		Service:     grooming(),
+		PartnerId:   "p1",
+		Region:      "ES",
+		Coupon:      &models.Coupon{Code: "SPRING", Kind: models.DiscountPercentage, BasisPoints: 1000},
+		MultiPet:    &models.MultiPetDiscount{MinPets: 2, BasisPoints: 500},
+		SameDayPets: 1,
+		TaxRates: []models.TaxRate{
+			{Name: "VAT", Region: "ES", BasisPoints: 2100},
+			{Name: "Pet care levy", Category: "grooming", BasisPoints: 150},
+			{Name: "Boarding tax", Category: "boarding", BasisPoints: 900},
+			{Name: "US sales tax", Region: "US", BasisPoints: 800},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the multi-pet discount goes first, the coupon takes its share of what is left and taxes are charged on the rest:
+	//50.00 - 2.50 = 47.50, - 4.75 = 42.75, + 21% 8.98 (8.9775) + 1.5% 0.64 (0.64125) = 52.37
+	wantDiscounts := []models.DiscountLine{{Name: "Multi-pet discount", Amount: usd(250)}, {Name: "Coupon SPRING", Code: "SPRING", Amount: usd(475)}}
+	if len(breakdown.Discounts) != len(wantDiscounts) {
+		t.Fatalf("got discounts %+v, want %+v", breakdown.Discounts, wantDiscounts)
+	}
+	for i, want := range wantDiscounts {
+		if breakdown.Discounts[i] != want {
+			t.Errorf("discount %d: got %+v, want %+v", i, breakdown.Discounts[i], want)
+		}
+	}
+
+	wantTaxes := []models.TaxLine{{Name: "VAT", BasisPoints: 2100, Amount: usd(898)}, {Name: "Pet care levy", BasisPoints: 150, Amount: usd(64)}}
+	if len(breakdown.Taxes) != len(wantTaxes) {
+		t.Fatalf("got taxes %+v, want %+v", breakdown.Taxes, wantTaxes)
+	}
+	for i, want := range wantTaxes {
+		if breakdown.Taxes[i] != want {
+			t.Errorf("tax %d: got %+v, want %+v", i, breakdown.Taxes[i], want)
+		}
+	}
+
+	if breakdown.Total != usd(5237) {
+		t.Errorf("got total %s, want 52.37 USD", breakdown.Total)
+	}
+}
+
+func TestCalculateMultiPetNeedsEnoughPets(t *testing.T) {
+	breakdown, err := Calculate(Input{Service: grooming(), MultiPet: &models.MultiPetDiscount{MinPets: 3, BasisPoints: 1000}, SameDayPets: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breakdown.Discounts) != 0 || breakdown.Total != usd(5000) {
+		t.Errorf("got %+v", breakdown)
+	}
+}
+
+func TestCalculateFixedCouponNeverGoesBelowZero(t *testing.T) {
+	fixed := usd(8000)
+	breakdown, err := Calculate(Input{
+		Service:  grooming(),
+		Coupon:   &models.Coupon{Code: "FREEBIE", Kind: models.DiscountFixed, Amount: &fixed},
+		TaxRates: []models.TaxRate{{Name: "VAT", BasisPoints: 2100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breakdown.Discounts) != 1 || breakdown.Discounts[0].Amount != usd(5000) {
+		t.Errorf("got discounts %+v, want the whole price off", breakdown.Discounts)
+	}
+	if breakdown.Total != usd(0) || breakdown.Taxes[0].Amount != usd(0) {
+		t.Errorf("got %+v, want nothing to pay", breakdown)
+	}
+}
+
+func TestCalculateCouponCurrencyMismatch(t *testing.T) {
+	euros := models.NewMoney(500, "EUR")
+	_, err := Calculate(Input{Service: grooming(), Coupon: &models.Coupon{Code: "EURO", Kind: models.DiscountFixed, Amount: &euros}})
+	if err != models.ErrCurrencyMismatch {
+		t.Errorf("got %v, want %v", err, models.ErrCurrencyMismatch)
+	}
+}
+
+func TestBasisPointRounding(t *testing.T) {
+	cases := []struct {
+		amount      int64
+		basisPoints int64
+		want        int64
+	}{
+		{1000, 2100, 210},
+		{1, 5000, 1},   //0.5 rounds up
+		{3, 5000, 2},   //1.5 rounds up
+		{1, 4999, 0},   //0.4999 rounds down
+		{-1, 5000, -1}, //half away from zero
+		{333, 3333, 111},
+		{12345, 10000, 12345},
+	}
+	for _, c := range cases {
+		if got := usd(c.amount).Percentage(c.basisPoints); got != usd(c.want) {
+			t.Errorf("%d at %d bp: got %d, want %d", c.amount, c.basisPoints, got.Amount, c.want)
+		}
+	}
+}
+
+func TestDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	cases := []struct {
+		name       string
+		at         time.Time
+		start, end time.Time
+	}{
+		//8 pm in New York is already the next day in UTC, but still the same day of the partner
+		{"evening west of UTC", time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 0, 0, 0, 0, newYork), time.Date(2024, 6, 4, 0, 0, 0, 0, newYork)},
+		{"morning", time.Date(2024, 6, 3, 9, 0, 0, 0, newYork), time.Date(2024, 6, 3, 0, 0, 0, 0, newYork), time.Date(2024, 6, 4, 0, 0, 0, 0, newYork)},
+		{"midnight", time.Date(2024, 6, 3, 0, 0, 0, 0, newYork), time.Date(2024, 6, 3, 0, 0, 0, 0, newYork), time.Date(2024, 6, 4, 0, 0, 0, 0, newYork)},
+	}
+
+	for _, c := range cases {
+		start, end := Day(c.at, newYork)
+		if !start.Equal(c.start) || !end.Equal(c.end) {
+			t.Errorf("%s: got [%v, %v), want [%v, %v)", c.name, start, end, c.start, c.end)
+		}
+	}
+
+	//the day clocks go forward lasts 23 hours
+	start, end := Day(time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), newYork)
+	if end.Sub(start) != 23*time.Hour {
+		t.Errorf("got a %v long day, want 23h", end.Sub(start))
+	}
+}
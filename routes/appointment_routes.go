@@ -9,6 +9,11 @@ func AppointmentRoutes(app *fiber.App) {
 	app.Post("/appointment", controllers.CreateAppointment)
 	app.Get("/appointment/:appointmentId", controllers.GetAppointment)
 	app.Put("/appointment/:appointmentId", controllers.EditAppointment)
+	app.Post("/appointment/:appointmentId/complete", controllers.CompleteAppointment)
+	app.Post("/appointment/:appointmentId/cancel", controllers.CancelAppointment)
+	app.Post("/appointment/:appointmentId/invoice", controllers.IssueAppointmentInvoice)
+	app.Get("/appointment/:appointmentId/invoice", controllers.GetAppointmentInvoice)
+	app.Get("/appointment/:appointmentId/calendar.ics", controllers.GetAppointmentCalendar)
 	app.Delete("/appointment/:appointmentId", controllers.DeleteAppointment)
 	app.Get("/appointments", controllers.GetAllAppointments)
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func AttachmentRoutes(app *fiber.App) {
+	app.Post("/pet/:petId/attachment", controllers.UploadPetAttachment)
+	app.Get("/pet/:petId/attachments", controllers.GetPetAttachments)
+	app.Post("/appointment/:appointmentId/attachment", controllers.UploadAppointmentAttachment)
+	app.Get("/appointment/:appointmentId/attachments", controllers.GetAppointmentAttachments)
+	app.Get("/attachment/:attachmentId", controllers.GetAttachment)
+	app.Get("/attachment/:attachmentId/download", controllers.DownloadAttachment)
+	app.Delete("/attachment/:attachmentId", controllers.DeleteAttachment)
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http"
+	"pet-appointments-api/responses"
+	"regexp"
+	"strconv"
+)
+
+// uploadPath matches the routes attachment files are uploaded to, see AttachmentRoutes. Like the router, it ignores case.
+var uploadPath = regexp.MustCompile(`(?i)^/(pet|appointment)/[^/]+/attachment/?$`)
+
+// BodyLimit refuses request bodies bigger than limit, except the attachment uploads, which can be up to uploadLimit.
+// The server streams the bodies above its own limit instead of reading them, so a body is only read once it is known
+// to fit the limit of its route.
+func BodyLimit(limit int, uploadLimit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		max := limit
+		if c.Method() == fiber.MethodPost && uploadPath.MatchString(c.Path()) {
+			max = uploadLimit
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length > max {
+			return tooLarge(c, max, length)
+		}
+
+		//a chunked body doesn't tell its length, so it is read here, up to the limit
+		if stream := c.Context().RequestBodyStream(); length == -1 && stream != nil {
+			body, err := io.ReadAll(io.LimitReader(stream, int64(max)+1))
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(responses.Response{Status: http.StatusBadRequest, Message: "Error: the request body is invalid, please check it again.", Data: &fiber.Map{"data": err.Error()}})
+			}
+			if len(body) > max {
+				return tooLarge(c, max, len(body))
+			}
+			c.Request().SetBody(body)
+		}
+
+		return c.Next()
+	}
+}
+
+// tooLarge refuses a body bigger than max. The rest of the body is left unread, so the connection is closed instead of
+// being reused for the next request.
+func tooLarge(c *fiber.Ctx, max int, length int) error {
+	c.Context().SetConnectionClose()
+	return c.Status(http.StatusRequestEntityTooLarge).JSON(responses.Response{Status: http.StatusRequestEntityTooLarge, Message: "Error: the request body is larger than " + strconv.Itoa(max) + " bytes.", Data: &fiber.Map{"data": length}})
+}
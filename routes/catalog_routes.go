@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func CatalogRoutes(app *fiber.App) {
+	app.Post("/species", controllers.CreateSpecies)
+	app.Get("/species", controllers.GetAllSpecies)
+	app.Put("/species/:speciesCode", controllers.EditSpecies)
+	app.Post("/species/:speciesCode/breed", controllers.CreateBreed)
+	app.Get("/species/:speciesCode/breeds", controllers.GetSpeciesBreeds)
+	app.Put("/species/:speciesCode/breed/:breedCode", controllers.EditBreed)
+	app.Get("/catalog/lookup", controllers.LookupCatalog)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func CouponRoutes(app *fiber.App) {
+	app.Post("/coupon", controllers.CreateCoupon)
+	app.Get("/coupon/:couponId", controllers.GetCoupon)
+	app.Put("/coupon/:couponId", controllers.EditCoupon)
+	app.Delete("/coupon/:couponId", controllers.DeleteCoupon)
+	app.Get("/coupons", controllers.GetAllCoupons)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func MedicalRecordRoutes(app *fiber.App) {
+	app.Get("/pet/:petId/records", controllers.GetPetMedicalRecord)
+	app.Post("/pet/:petId/weight", controllers.AddPetWeight)
+	app.Post("/pet/:petId/allergy", controllers.AddPetAllergy)
+	app.Delete("/pet/:petId/allergy/:allergyId", controllers.DeletePetAllergy)
+	app.Post("/pet/:petId/condition", controllers.AddPetCondition)
+	app.Put("/pet/:petId/condition/:conditionId", controllers.EditPetCondition)
+	app.Post("/pet/:petId/prescription", controllers.CreatePrescription)
+	app.Get("/pet/:petId/prescriptions", controllers.GetPetPrescriptions)
+	app.Get("/pet/:petId/prescription/:prescriptionId", controllers.GetPetPrescription)
+	app.Post("/pet/:petId/prescription/:prescriptionId/refill", controllers.RefillPrescription)
+	app.Post("/pet/:petId/prescription/:prescriptionId/stop", controllers.StopPrescription)
+	app.Get("/pet/:petId/medication-schedule", controllers.GetPetMedicationSchedule)
+	app.Post("/appointment/:appointmentId/visit-note", controllers.CreateVisitNote)
+	app.Put("/visit-note/:noteId", controllers.EditVisitNote)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func NotificationTemplateRoutes(app *fiber.App) {
+	app.Post("/notification-template", controllers.CreateNotificationTemplate)
+	app.Get("/notification-template/:notificationTemplateId", controllers.GetNotificationTemplate)
+	app.Put("/notification-template/:notificationTemplateId", controllers.EditNotificationTemplate)
+	app.Delete("/notification-template/:notificationTemplateId", controllers.DeleteNotificationTemplate)
+	app.Get("/notification-template/:notificationTemplateId/preview/:appointmentId", controllers.PreviewNotificationTemplate)
+	app.Get("/notification-templates", controllers.GetAllNotificationTemplates)
+}
@@ -10,5 +10,9 @@ func OwnerRoutes(app *fiber.App) {
 	app.Get("/owner/:ownerId", controllers.GetOwner)
 	app.Put("/owner/:ownerId", controllers.EditOwner)
 	app.Delete("/owner/:ownerId", controllers.DeleteOwner)
+	app.Get("/owner/:ownerId/calendar.ics", controllers.GetOwnerCalendar)
+	app.Post("/owner/:ownerId/calendar-token", controllers.RotateOwnerCalendarToken)
 	app.Get("/owners", controllers.GetAllOwners)
+	app.Get("/owners/duplicates", controllers.GetOwnerDuplicates)
+	app.Post("/owner/merge", controllers.MergeOwners)
 }
@@ -10,5 +10,14 @@ func PartnerRoutes(app *fiber.App) {
 	app.Get("/partner/:partnerId", controllers.GetPartner)
 	app.Put("/partner/:partnerId", controllers.EditPartner)
 	app.Delete("/partner/:partnerId", controllers.DeletePartner)
+	app.Get("/partner/:partnerId/calendar.ics", controllers.GetPartnerCalendar)
+	app.Post("/partner/:partnerId/calendar-token", controllers.RotatePartnerCalendarToken)
+	app.Post("/partner/:partnerId/calendar-import", controllers.ImportPartnerCalendar)
+	app.Post("/partner/:partnerId/calendar-source", controllers.CreateCalendarSource)
+	app.Get("/partner/:partnerId/calendar-sources", controllers.GetPartnerCalendarSources)
+	app.Get("/partner/:partnerId/blocked-slots", controllers.GetPartnerBlockedSlots)
+	app.Get("/partner/:partnerId/availability", controllers.GetPartnerAvailability)
+	app.Post("/calendar-source/:sourceId/sync", controllers.SyncCalendarSource)
+	app.Delete("/calendar-source/:sourceId", controllers.DeleteCalendarSource)
 	app.Get("/partners", controllers.GetAllPartners)
 }
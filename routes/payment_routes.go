@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func PaymentRoutes(app *fiber.App) {
+	app.Post("/appointment/:appointmentId/payment", controllers.CreatePayment)
+	app.Get("/appointment/:appointmentId/payments", controllers.GetAppointmentPayments)
+	app.Get("/payment/:paymentId", controllers.GetPayment)
+	app.Post("/payment/:paymentId/capture", controllers.CapturePayment)
+	app.Post("/payment/:paymentId/refund", controllers.RefundPayment)
+}
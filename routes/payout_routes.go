@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func PayoutRoutes(app *fiber.App) {
+	app.Post("/payouts/settle", controllers.SettlePayouts)
+	app.Get("/payout/:payoutId", controllers.GetPayout)
+	app.Post("/payout/:payoutId/approve", controllers.ApprovePayout)
+	app.Post("/payout/:payoutId/paid", controllers.MarkPayoutPaid)
+	app.Get("/payout/:payoutId/csv", controllers.ExportPayoutCSV)
+	app.Get("/payouts", controllers.GetAllPayouts)
+}
@@ -10,5 +10,15 @@ func PetRoutes(app *fiber.App) {
 	app.Get("/pet/:petId", controllers.GetPet)
 	app.Put("/pet/:petId", controllers.EditPet)
 	app.Delete("/pet/:petId", controllers.DeletePet)
+	app.Post("/pet/:petId/vaccination", controllers.AddPetVaccination)
+	app.Get("/pet/:petId/vaccinations", controllers.GetPetVaccinations)
+	app.Delete("/pet/:petId/vaccination/:vaccinationId", controllers.DeletePetVaccination)
+	app.Post("/pet/:petId/transfer", controllers.CreatePetTransfer)
+	app.Get("/pet/:petId/transfers", controllers.GetPetTransfers)
+	app.Post("/pet/:petId/transfer/:transferId/accept", controllers.AcceptPetTransfer)
+	app.Post("/pet/:petId/transfer/:transferId/decline", controllers.DeclinePetTransfer)
+	app.Post("/pet/:petId/transfer/:transferId/cancel", controllers.CancelPetTransfer)
+	app.Get("/owner/:ownerId/transfers", controllers.GetOwnerPetTransfers)
 	app.Get("/pets", controllers.GetAllPets)
+	app.Get("/vaccinations/due", controllers.GetDueVaccinations)
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func ServiceRoutes(app *fiber.App) {
+	app.Post("/service", controllers.CreateService)
+	app.Get("/service/:serviceId", controllers.GetService)
+	app.Put("/service/:serviceId", controllers.EditService)
+	app.Delete("/service/:serviceId", controllers.DeleteService)
+	app.Get("/services", controllers.GetAllServices)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func SlotHoldRoutes(app *fiber.App) {
+	app.Post("/holds", controllers.CreateSlotHold)
+	app.Get("/holds/:token", controllers.GetSlotHold)
+	app.Delete("/holds/:token", controllers.DeleteSlotHold)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func TaxRateRoutes(app *fiber.App) {
+	app.Post("/tax-rate", controllers.CreateTaxRate)
+	app.Get("/tax-rate/:taxRateId", controllers.GetTaxRate)
+	app.Put("/tax-rate/:taxRateId", controllers.EditTaxRate)
+	app.Delete("/tax-rate/:taxRateId", controllers.DeleteTaxRate)
+	app.Get("/tax-rates", controllers.GetAllTaxRates)
+}
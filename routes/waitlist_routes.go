@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func WaitlistRoutes(app *fiber.App) {
+	app.Post("/waitlist", controllers.JoinWaitlist)
+	app.Get("/waitlist/:entryId", controllers.GetWaitlistEntry)
+	app.Delete("/waitlist/:entryId", controllers.LeaveWaitlist)
+	app.Post("/waitlist/:entryId/accept", controllers.AcceptWaitlistOffer)
+	app.Post("/waitlist/:entryId/decline", controllers.DeclineWaitlistOffer)
+	app.Get("/partner/:partnerId/waitlist", controllers.GetPartnerWaitlist)
+	app.Get("/owner/:ownerId/waitlist", controllers.GetOwnerWaitlist)
+}
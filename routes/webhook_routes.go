@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"pet-appointments-api/controllers"
+)
+
+func WebhookRoutes(app *fiber.App) {
+	app.Post("/webhook", controllers.CreateWebhook)
+	app.Get("/webhook/:webhookId", controllers.GetWebhook)
+	app.Put("/webhook/:webhookId", controllers.EditWebhook)
+	app.Delete("/webhook/:webhookId", controllers.DeleteWebhook)
+	app.Get("/webhook/:webhookId/deliveries", controllers.GetWebhookDeliveries)
+	app.Get("/webhooks", controllers.GetAllWebhooks)
+	app.Get("/webhook-deliveries/dead-letters", controllers.GetDeadWebhookDeliveries)
+	app.Get("/webhook-delivery/:deliveryId", controllers.GetWebhookDelivery)
+	app.Post("/webhook-delivery/:deliveryId/redeliver", controllers.RedeliverWebhookDelivery)
+}
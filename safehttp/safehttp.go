@@ -0,0 +1,78 @@
+// Package safehttp calls URLs given by users, like webhook targets or calendar feeds, without letting them reach the
+// services on the host or its private network.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrInvalidURL is returned for URLs that aren't absolute http or https URLs.
+	ErrInvalidURL = errors.New("safehttp: the URL must be an absolute http or https URL")
+	// ErrForbiddenAddress is returned for URLs resolving to loopback, link-local, private or otherwise internal
+	// addresses.
+	ErrForbiddenAddress = errors.New("safehttp: the URL points to an internal address")
+)
+
+// Allowed tells whether an address is on the public internet, so it can be called on behalf of a user.
+func Allowed(ip net.IP) bool {
+	return ip != nil && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the range carriers use for NAT, which Go doesn't count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// CheckURL tells whether a URL can be called: it must be an http or https URL whose host only resolves to public
+// addresses. It is meant to turn down bad URLs when they are saved, the Client checks again on every call since
+// the host may resolve elsewhere later.
+func CheckURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if !Allowed(address.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client that gives up after the timeout and refuses to connect to addresses that aren't
+// Allowed, also when a redirect leads there. It never goes through a proxy, which would connect on its behalf.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseInternal}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// refuseInternal runs right before every connection, once the host was resolved, so a host that resolves to a
+// public address when the URL is saved and to an internal one later is still refused.
+func refuseInternal(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !Allowed(net.ParseIP(host)) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowed(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.10":    false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	}
+	for address, want := range cases {
+		if got := Allowed(net.ParseIP(address)); got != want {
+			t.Errorf("Allowed(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	cases := map[string]error{
+		"https://93.184.216.34/hooks":         nil,
+		"http://127.0.0.1:8080/hooks":         ErrForbiddenAddress,
+		"http://169.254.169.254/latest/meta":  ErrForbiddenAddress,
+		"http://[::1]/hooks":                  ErrForbiddenAddress,
+		"http://localhost/hooks":              ErrForbiddenAddress,
+		"ftp://93.184.216.34/file":            ErrInvalidURL,
+		"/relative/path":                      ErrInvalidURL,
+		"https://user@192.168.0.1:8443/hooks": ErrForbiddenAddress,
+	}
+	for rawURL, want := range cases {
+		if err := CheckURL(ctx, rawURL); !errors.Is(err, want) {
+			t.Errorf("CheckURL(%s) = %v, want %v", rawURL, err, want)
+		}
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("got %v, want %v", err, ErrForbiddenAddress)
+	}
+}
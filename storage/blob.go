@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrNotFound is returned by a BlobStore that has no blob with a key.
+var ErrNotFound = errors.New("storage: blob not found")
+
+// BlobStore keeps the contents of uploaded files. Keys are made of letters, digits and "/", "-", "_" and "." only.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore BlobStore
+)
+
+// SetDefault sets the store returned by Default.
+func SetDefault(store BlobStore) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = store
+}
+
+// Default returns the store set up when the API started.
+func Default() BlobStore {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
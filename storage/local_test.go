@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "pets/1/report.pdf", []byte("report"), "application/pdf"); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+	reader, err := store.Get(ctx, "pets/1/report.pdf")
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "report" {
+		t.Errorf("Get() read %q, want %q", data, "report")
+	}
+
+	//a blob put again is replaced
+	if err := store.Put(ctx, "pets/1/report.pdf", []byte("new report"), "application/pdf"); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+	reader, _ = store.Get(ctx, "pets/1/report.pdf")
+	data, _ = io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "new report" {
+		t.Errorf("Get() read %q, want %q", data, "new report")
+	}
+
+	if err := store.Delete(ctx, "pets/1/report.pdf"); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if _, err := store.Get(ctx, "pets/1/report.pdf"); err != ErrNotFound {
+		t.Errorf("Get() after Delete() = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "pets/1/report.pdf"); err != nil {
+		t.Errorf("Delete() of a missing blob = %v, want nil", err)
+	}
+}
+
+func TestLocalStorePath(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(root)
+
+	valid := []string{"pets/1/report.pdf", "/pets/1/report.pdf", "a.b-c_d"}
+	for _, key := range valid {
+		path, err := store.path(key)
+		if err != nil {
+			t.Errorf("path(%q) = %v", key, err)
+			continue
+		}
+		if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			t.Errorf("path(%q) = %q, outside of %q", key, path, root)
+		}
+	}
+
+	invalid := []string{"", "/", "..", "../x", "a/../../b", "a/..", "../" + filepath.Base(root) + "-other/x", "pets/..%2f/x/.."}
+	for _, key := range invalid {
+		if path, err := store.path(key); err == nil {
+			t.Errorf("path(%q) = %q, want an error", key, path)
+		}
+	}
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	store := NewLocalStore(filepath.Join(t.TempDir(), "blobs"))
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "../outside", []byte("x"), ""); err == nil {
+		t.Error("Put() of ../outside succeeded, want an error")
+	}
+	if _, err := store.Get(ctx, "../../etc/passwd"); err == nil || err == ErrNotFound {
+		t.Errorf("Get() of ../../etc/passwd = %v, want an invalid key error", err)
+	}
+	if err := store.Delete(ctx, "a/../../b"); err == nil {
+		t.Error("Delete() of a/../../b succeeded, want an error")
+	}
+}
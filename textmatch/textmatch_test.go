@@ -0,0 +1,77 @@
+package textmatch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{"Pastor  Alemán", "pastor aleman"},
+		{"pastor-aleman", "pastor aleman"},
+		{"  Gato_Persa! ", "gato persa"},
+		{"Ñandú", "nandu"},
+		{"Perro 2", "perro 2"},
+		{"---", ""},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		if got := Normalize(c.value); got != c.want {
+			t.Errorf("Normalize(%q) = %q, want %q", c.value, got, c.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"maria", "maria", 0},
+		{"maria", "marie", 1},
+		{"maria", "mara", 1},
+		{"maria", "marias", 1},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		//letters, not bytes, are edited
+		{"jose", "josé", 1},
+		{"ñandu", "nandu", 1},
+	}
+
+	for _, c := range cases {
+		if got := Distance(c.a, c.b); got != c.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := Distance(c.b, c.a); got != c.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", c.b, c.a, got, c.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"maria", "maria", 1},
+		{"maria", "marie", 0.8},
+		{"abc", "xyz", 0},
+		{"abc", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"josé", "jose", 0.75},
+	}
+
+	for _, c := range cases {
+		if got := Similarity(c.a, c.b); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
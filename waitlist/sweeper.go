@@ -0,0 +1,73 @@
+package waitlist
+
+import (
+	"context"
+	"log"
+	"pet-appointments-api/models"
+	"time"
+)
+
+// EntryStore gives the Sweeper access to the waitlist.
+type EntryStore interface {
+	// ExpiredOffers returns the offers whose hold ran out at the given time.
+	ExpiredOffers(ctx context.Context, now time.Time, limit int) ([]models.WaitlistEntry, error)
+	// ExpireOffer takes an expired offer back and offers its slot to the next owner in the waitlist. Offers that
+	// were accepted or declined in the meantime are left alone.
+	ExpireOffer(ctx context.Context, entry models.WaitlistEntry, now time.Time) error
+	// CloseEntries closes the waiting entries whose date range is over, returning how many were closed.
+	CloseEntries(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Sweeper periodically passes expired offers on and closes the entries nobody can be offered anything anymore.
+type Sweeper struct {
+	Store    EntryStore
+	Interval time.Duration
+	Batch    int
+}
+
+func NewSweeper(store EntryStore) *Sweeper {
+	return &Sweeper{Store: store, Interval: time.Minute, Batch: 100}
+}
+
+// Start runs the sweeper until the context is cancelled.
+func (s *Sweeper) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Run(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run expires the offers due at the given time. Each expired slot goes to the next owner in the waitlist, so a
+// slot nobody accepts walks the whole list before it is given up.
+func (s *Sweeper) Run(ctx context.Context, now time.Time) {
+	if _, err := s.Store.CloseEntries(ctx, now); err != nil {
+		log.Println("waitlist: closing ended entries failed:", err)
+	}
+
+	entries, err := s.Store.ExpiredOffers(ctx, now, s.Batch)
+	if err != nil {
+		log.Println("waitlist: looking for expired offers failed:", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if err := s.Store.ExpireOffer(ctx, entry, now); err != nil {
+			log.Println("waitlist: expiring the offer of", entry.Id.Hex(), "failed:", err)
+		}
+	}
+}
+
+// Fits reports whether a service of the given duration can be booked in the offered slot within the date range
+// of an entry.
+func Fits(entry models.WaitlistEntry, slot models.WaitlistOffer, duration time.Duration) bool {
+	end := slot.Start.Add(duration)
+	return !slot.Start.Before(entry.From) && !end.After(entry.To) && !end.After(slot.End)
+}
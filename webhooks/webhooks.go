@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"pet-appointments-api/models"
+	"strconv"
+	"time"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it becomes a dead letter.
+	MaxAttempts = 8
+	// BaseBackoff is the wait after the first failure, doubled after each following one.
+	BaseBackoff = 30 * time.Second
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff = 6 * time.Hour
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// subscription secret, so receivers can check both the origin and the freshness of a delivery.
+const (
+	HeaderEventId   = "X-Webhook-Id"
+	HeaderEventType = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Matches reports whether a subscription wants the events of the given type.
+func Matches(subscription models.WebhookSubscription, eventType string) bool {
+	if subscription.Disabled {
+		return false
+	}
+	for _, wanted := range subscription.Events {
+		if wanted == "*" || wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign computes the signature of a delivery body sent at the given Unix timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature the way receivers are expected to, rejecting timestamps older than the tolerance.
+func Verify(secret string, signature string, timestamp int64, body []byte, tolerance time.Duration, now time.Time) bool {
+	if now.Sub(time.Unix(timestamp, 0)) > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// Backoff returns how long to wait before retrying a delivery that failed the given number of times.
+func Backoff(failures int) time.Duration {
+	wait := BaseBackoff
+	for i := 1; i < failures; i++ {
+		wait *= 2
+		if wait >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return wait
+}
+
+// Send posts a delivery to the subscription URL and logs the attempt. Any 2xx answer is a success.
+func Send(ctx context.Context, client *http.Client, subscription models.WebhookSubscription, delivery models.WebhookDelivery, now time.Time) models.WebhookAttempt {
+	attempt := models.WebhookAttempt{Date: now}
+	body := []byte(delivery.Payload)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := now.Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEventId, delivery.EventId)
+	request.Header.Set(HeaderEventType, delivery.EventType)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	start := time.Now()
+	response, err := client.Do(request)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+
+	//the body is drained so the connection can be reused, only the status matters
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		attempt.Error = "unexpected status " + response.Status
+	}
+	return attempt
+}
+
+// Record applies the outcome of an attempt to a delivery: success, retry later with backoff, or dead letter.
+func Record(delivery models.WebhookDelivery, attempt models.WebhookAttempt) models.WebhookDelivery {
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.AttemptCount++
+	delivery.UpdateDate = attempt.Date
+
+	switch {
+	case attempt.Error == "":
+		delivery.Status = models.WebhookSucceeded
+	case delivery.AttemptCount >= MaxAttempts:
+		delivery.Status = models.WebhookDead
+	default:
+		delivery.Status = models.WebhookFailed
+		delivery.NextAttempt = attempt.Date.Add(Backoff(delivery.AttemptCount))
+	}
+	return delivery
+}
+
+// NewSecret generates a random secret for a subscription.
+func NewSecret() string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(secret)
+}
@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"pet-appointments-api/models"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"appointment.created"}`)
+	signature := Sign("secret", now.Unix(), body)
+
+	cases := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp int64
+		body      string
+		now       time.Time
+		valid     bool
+	}{
+		{"valid", "secret", signature, now.Unix(), string(body), now, true},
+		{"within the tolerance", "secret", signature, now.Unix(), string(body), now.Add(5 * time.Minute), true},
+		{"too old", "secret", signature, now.Unix(), string(body), now.Add(5*time.Minute + time.Second), false},
+		{"other secret", "other", signature, now.Unix(), string(body), now, false},
+		{"tampered body", "secret", signature, now.Unix(), `{"type":"appointment.deleted"}`, now, false},
+		//the timestamp is signed too, so an old delivery can't be replayed with a fresh one
+		{"replayed with another timestamp", "secret", signature, now.Unix() + 60, string(body), now.Add(time.Minute), false},
+		{"no signature", "secret", "", now.Unix(), string(body), now, false},
+	}
+
+	for _, c := range cases {
+		if valid := Verify(c.secret, c.signature, c.timestamp, []byte(c.body), 5*time.Minute, c.now); valid != c.valid {
+			t.Errorf("%s: got %v, want %v", c.name, valid, c.valid)
+		}
+	}
+}
+
+func TestSignFormat(t *testing.T) {
+	//receivers compute the HMAC-SHA256 of "<timestamp>.<body>" themselves
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000.{}"))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, BaseBackoff},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, MaxBackoff},
+		{100, MaxBackoff},
+	}
+
+	for _, c := range cases {
+		if got := Backoff(c.failures); got != c.want {
+			t.Errorf("Backoff(%d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		name         string
+		subscription models.WebhookSubscription
+		matches      bool
+	}{
+		{"listed", models.WebhookSubscription{Events: []string{models.EventAppointmentCreated}}, true},
+		{"wildcard", models.WebhookSubscription{Events: []string{"*"}}, true},
+		{"not listed", models.WebhookSubscription{Events: []string{models.EventAppointmentCancelled}}, false},
+		{"disabled", models.WebhookSubscription{Events: []string{"*"}, Disabled: true}, false},
+	}
+
+	for _, c := range cases {
+		if matches := Matches(c.subscription, models.EventAppointmentCreated); matches != c.matches {
+			t.Errorf("%s: got %v, want %v", c.name, matches, c.matches)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	succeeded := Record(models.WebhookDelivery{Status: models.WebhookPending}, models.WebhookAttempt{Date: now, StatusCode: 204})
+	if succeeded.Status != models.WebhookSucceeded || succeeded.AttemptCount != 1 || len(succeeded.Attempts) != 1 {
+		t.Errorf("got %+v, want a succeeded delivery", succeeded)
+	}
+
+	failed := Record(models.WebhookDelivery{AttemptCount: 2}, models.WebhookAttempt{Date: now, StatusCode: 500, Error: "unexpected status"})
+	if failed.Status != models.WebhookFailed || !failed.NextAttempt.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("got %+v, want a retry in 2 minutes", failed)
+	}
+
+	dead := Record(models.WebhookDelivery{AttemptCount: MaxAttempts - 1}, models.WebhookAttempt{Date: now, Error: "connection refused"})
+	if dead.Status != models.WebhookDead || dead.AttemptCount != MaxAttempts {
+		t.Errorf("got %+v, want a dead letter", dead)
+	}
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"pet-appointments-api/models"
+	"pet-appointments-api/safehttp"
+	"time"
+)
+
+// DeliveryStore gives the Worker access to the pending deliveries and their subscriptions.
+type DeliveryStore interface {
+	// ClaimDueDeliveries returns the deliveries due at the given time, leasing them so no other worker picks them
+	// up until the lease expires.
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	FindSubscription(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error)
+	SaveDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+// Worker periodically sends the due deliveries.
+type Worker struct {
+	Store    DeliveryStore
+	Client   *http.Client
+	Interval time.Duration
+	Lease    time.Duration
+	Batch    int
+}
+
+func NewWorker(store DeliveryStore) *Worker {
+	//the targets were checked when they were saved, the client checks them again as their hosts may move
+	return &Worker{Store: store, Client: safehttp.NewClient(10 * time.Second), Interval: 5 * time.Second, Lease: time.Minute, Batch: 50}
+}
+
+// Start runs the worker until the context is cancelled.
+func (w *Worker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.Run(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run sends a batch of the deliveries due at the given time.
+func (w *Worker) Run(ctx context.Context, now time.Time) {
+	deliveries, err := w.Store.ClaimDueDeliveries(ctx, now, w.Lease, w.Batch)
+	if err != nil {
+		log.Println("webhooks: looking for due deliveries failed:", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		subscription, err := w.Store.FindSubscription(ctx, delivery.SubscriptionId)
+
+		var attempt models.WebhookAttempt
+		if err != nil {
+			//the subscription was deleted, there is nowhere to deliver to anymore
+			attempt = models.WebhookAttempt{Date: time.Now(), Error: "subscription not found: " + err.Error()}
+			delivery.AttemptCount = MaxAttempts - 1
+		} else {
+			attempt = Send(ctx, w.Client, subscription, delivery, time.Now())
+		}
+
+		if err := w.Store.SaveDelivery(ctx, Record(delivery, attempt)); err != nil {
+			log.Println("webhooks: saving delivery", delivery.Id.Hex(), "failed:", err)
+		}
+	}
+}
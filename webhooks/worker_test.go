@@ -0,0 +1,177 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pet-appointments-api/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// memoryDeliveries is a DeliveryStore keeping its deliveries and subscriptions in memory.
+type memoryDeliveries struct {
+	subscriptions map[string]models.WebhookSubscription
+	deliveries    []models.WebhookDelivery
+}
+
+func (s *memoryDeliveries) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var claimed []models.WebhookDelivery
+	for i, delivery := range s.deliveries {
+		due := delivery.Status == models.WebhookPending || delivery.Status == models.WebhookFailed
+		if due && !delivery.NextAttempt.After(now) && len(claimed) < limit {
+			s.deliveries[i].NextAttempt = now.Add(lease)
+			claimed = append(claimed, s.deliveries[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryDeliveries) FindSubscription(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error) {
+	subscription, ok := s.subscriptions[subscriptionId]
+	if !ok {
+		return subscription, errors.New("no documents in result")
+	}
+	return subscription, nil
+}
+
+func (s *memoryDeliveries) SaveDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	for i := range s.deliveries {
+		if s.deliveries[i].Id == delivery.Id {
+			s.deliveries[i] = delivery
+		}
+	}
+	return nil
+}
+
+func delivery(subscriptionId string) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		Id:             primitive.NewObjectID(),
+		SubscriptionId: subscriptionId,
+		EventId:        "event-1",
+		EventType:      models.EventAppointmentCreated,
+		Payload:        `{"type":"appointment.created"}`,
+		Status:         models.WebhookPending,
+	}
+}
+
+// newWorker returns a worker sending to a local server, which the default client refuses to reach.
+func newWorker(server *httptest.Server, store DeliveryStore) *Worker {
+	worker := NewWorker(store)
+	worker.Client = server.Client()
+	return worker
+}
+
+func TestWorkerDelivers(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &memoryDeliveries{
+		subscriptions: map[string]models.WebhookSubscription{"s1": {URL: server.URL, Secret: "0123456789abcdef", Events: []string{"*"}}},
+		deliveries:    []models.WebhookDelivery{delivery("s1")},
+	}
+	newWorker(server, store).Run(context.Background(), time.Now())
+
+	if received == nil {
+		t.Fatal("nothing was delivered")
+	}
+	if string(body) != store.deliveries[0].Payload || received.Header.Get(HeaderEventId) != "event-1" || received.Header.Get(HeaderEventType) != models.EventAppointmentCreated {
+		t.Errorf("got %s with headers %v", body, received.Header)
+	}
+
+	//the receiver can check the signature with the shared secret
+	timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify("0123456789abcdef", received.Header.Get(HeaderSignature), timestamp, body, 5*time.Minute, time.Now()) {
+		t.Error("the signature does not verify")
+	}
+
+	if saved := store.deliveries[0]; saved.Status != models.WebhookSucceeded || saved.AttemptCount != 1 || saved.Attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("got %+v, want a succeeded delivery", saved)
+	}
+}
+
+func TestWorkerRetriesUntilDeadLetter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := &memoryDeliveries{
+		subscriptions: map[string]models.WebhookSubscription{"s1": {URL: server.URL, Secret: "0123456789abcdef", Events: []string{"*"}}},
+		deliveries:    []models.WebhookDelivery{delivery("s1")},
+	}
+	worker := newWorker(server, store)
+
+	//a failed delivery waits for its backoff before the next attempt
+	worker.Run(context.Background(), time.Now())
+	saved := store.deliveries[0]
+	if saved.Status != models.WebhookFailed || saved.Attempts[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("got %+v, want a failed delivery", saved)
+	}
+	if wait := time.Until(saved.NextAttempt); wait < BaseBackoff-time.Second || wait > BaseBackoff {
+		t.Errorf("the next attempt is in %v, want %v", wait, BaseBackoff)
+	}
+	worker.Run(context.Background(), time.Now())
+	if calls != 1 {
+		t.Errorf("the delivery was retried before its backoff")
+	}
+
+	for i := 1; i < MaxAttempts; i++ {
+		worker.Run(context.Background(), store.deliveries[0].NextAttempt)
+	}
+	if saved := store.deliveries[0]; saved.Status != models.WebhookDead || saved.AttemptCount != MaxAttempts || calls != MaxAttempts {
+		t.Fatalf("got %+v after %d calls, want a dead letter after %d", saved, calls, MaxAttempts)
+	}
+
+	//dead letters are not tried again
+	worker.Run(context.Background(), time.Now().Add(24*time.Hour))
+	if calls != MaxAttempts {
+		t.Errorf("a dead letter was delivered again")
+	}
+}
+
+func TestWorkerDeadLettersDeletedSubscriptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a delivery was sent without a subscription")
+	}))
+	defer server.Close()
+
+	store := &memoryDeliveries{deliveries: []models.WebhookDelivery{delivery("deleted")}}
+	newWorker(server, store).Run(context.Background(), time.Now())
+
+	if saved := store.deliveries[0]; saved.Status != models.WebhookDead {
+		t.Errorf("got %+v, want a dead letter", saved)
+	}
+}
+
+func TestNewWorkerRefusesInternalTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the default client reached a loopback address")
+	}))
+	defer server.Close()
+
+	store := &memoryDeliveries{
+		subscriptions: map[string]models.WebhookSubscription{"s1": {URL: server.URL, Events: []string{"*"}}},
+		deliveries:    []models.WebhookDelivery{delivery("s1")},
+	}
+	NewWorker(store).Run(context.Background(), time.Now())
+
+	if saved := store.deliveries[0]; saved.Status != models.WebhookFailed || saved.Attempts[0].Error == "" {
+		t.Errorf("got %+v, want a failed delivery", saved)
+	}
+}